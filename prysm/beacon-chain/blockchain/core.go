@@ -2,6 +2,7 @@ package blockchain
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math"
@@ -11,15 +12,29 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/protobuf/proto"
 	"github.com/prysmaticlabs/prysm/beacon-chain/params"
 	"github.com/prysmaticlabs/prysm/beacon-chain/types"
 	"github.com/prysmaticlabs/prysm/beacon-chain/utils"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+	"github.com/prysmaticlabs/prysm/shared/crypto/bls"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/blake2b"
 )
 
 var stateLookupKey = "beaconchainstate"
 
+// blockPrefix is prepended to a block hash to form the key under which the
+// block is stored in the db.
+const blockPrefix = "block-"
+
+// canonicalPrefix is prepended to a big-endian slot number to form the key
+// under which the hash of the canonical block at that slot is stored.
+const canonicalPrefix = "canonical-"
+
+// canonicalHeadKey stores the hash of the canonical block with the highest slot.
+var canonicalHeadKey = "canonicalhead"
+
 // BeaconChain represents the core PoS blockchain object containing
 // both a crystallized and active state.
 type BeaconChain struct {
@@ -126,6 +141,18 @@ func (b *BeaconChain) CanProcessBlock(fetcher types.POWBlockFetcher, block *type
 	if mainchainBlock == nil {
 		return false, nil
 	}
+	// The mainchain block must be at least as deep as the confirmed block, so
+	// that it is unlikely to be reorganized away.
+	confirmed := fetcher.ConfirmedBlock()
+	if confirmed == nil || mainchainBlock.NumberU64() > confirmed.Number.Uint64() {
+		return false, nil
+	}
+	// The mainchain block must also be the canonical block at its height, and
+	// not one of a branch that was reorganized away.
+	canonical := fetcher.CanonicalHeader(mainchainBlock.NumberU64())
+	if canonical == nil || canonical.Hash() != block.MainChainRef() {
+		return false, nil
+	}
 	// TODO: check if the parentHash pointed by the beacon block is in the beaconDB.
 
 	// Calculate the timestamp validity condition.
@@ -218,14 +245,113 @@ func (b *BeaconChain) persist() error {
 	return b.db.Put([]byte(stateLookupKey), encodedState)
 }
 
+// SaveBlock stores the block in the db, keyed by its hash.
+func (b *BeaconChain) SaveBlock(block *types.Block) error {
+	h, err := block.Hash()
+	if err != nil {
+		return err
+	}
+	enc, err := block.Marshal()
+	if err != nil {
+		return err
+	}
+	return b.db.Put(blockKey(h), enc)
+}
+
+// HasBlock checks if a block with the given hash is stored in the db.
+func (b *BeaconChain) HasBlock(h [32]byte) (bool, error) {
+	return b.db.Has(blockKey(h))
+}
+
+// GetBlock retrieves the block with the given hash from the db.
+func (b *BeaconChain) GetBlock(h [32]byte) (*types.Block, error) {
+	enc, err := b.db.Get(blockKey(h))
+	if err != nil {
+		return nil, err
+	}
+	block := &pb.BeaconBlockResponse{}
+	if err := proto.Unmarshal(enc, block); err != nil {
+		return nil, fmt.Errorf("could not unmarshal block %#x: %v", h, err)
+	}
+	return types.NewBlockWithData(block)
+}
+
+// SetCanonicalBlock marks a stored block as the canonical block for its slot,
+// and as the canonical head if no canonical block has a higher slot.
+func (b *BeaconChain) SetCanonicalBlock(block *types.Block) error {
+	h, err := block.Hash()
+	if err != nil {
+		return err
+	}
+	head, err := b.CanonicalHead()
+	if err != nil {
+		return err
+	}
+	if err := b.db.Put(canonicalKey(block.SlotNumber()), h[:]); err != nil {
+		return err
+	}
+	if block.SlotNumber() < head.SlotNumber() {
+		return nil
+	}
+	return b.db.Put([]byte(canonicalHeadKey), h[:])
+}
+
+// CanonicalHead returns the canonical block with the highest slot, or the
+// genesis block if no block has been marked canonical yet.
+func (b *BeaconChain) CanonicalHead() (*types.Block, error) {
+	has, err := b.db.Has([]byte(canonicalHeadKey))
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return b.GenesisBlock()
+	}
+	enc, err := b.db.Get([]byte(canonicalHeadKey))
+	if err != nil {
+		return nil, err
+	}
+	var h [32]byte
+	copy(h[:], enc)
+	return b.GetBlock(h)
+}
+
+// CanonicalBlockBySlot returns the canonical block at the given slot. It
+// returns nil if the slot is empty.
+func (b *BeaconChain) CanonicalBlockBySlot(slot uint64) (*types.Block, error) {
+	has, err := b.db.Has(canonicalKey(slot))
+	if err != nil || !has {
+		return nil, err
+	}
+	enc, err := b.db.Get(canonicalKey(slot))
+	if err != nil {
+		return nil, err
+	}
+	var h [32]byte
+	copy(h[:], enc)
+	return b.GetBlock(h)
+}
+
+func blockKey(h [32]byte) []byte {
+	return append([]byte(blockPrefix), h[:]...)
+}
+
+func canonicalKey(slot uint64) []byte {
+	key := make([]byte, len(canonicalPrefix)+8)
+	copy(key, canonicalPrefix)
+	binary.BigEndian.PutUint64(key[len(canonicalPrefix):], slot)
+	return key
+}
+
 // computeNewActiveState computes a new active state for every beacon block.
-func (b *BeaconChain) computeNewActiveState(seed common.Hash) (*types.ActiveState, error) {
+func (b *BeaconChain) computeNewActiveState(seed common.Hash, block *types.Block) (*types.ActiveState, error) {
 	attesters, proposer, err := b.getAttestersProposer(seed)
 	if err != nil {
 		return nil, err
 	}
-	// TODO: Verify attestations from attesters.
 	log.WithFields(logrus.Fields{"attestersIndices": attesters}).Debug("Attester indices")
+	if err := b.verifyAttestations(block, attesters); err != nil {
+		return nil, fmt.Errorf("could not verify attestations: %v", err)
+	}
 
 	// TODO: Verify main signature from proposer.
 	log.WithFields(logrus.Fields{"proposerIndex": proposer}).Debug("Proposer index")
@@ -242,8 +368,49 @@ func (b *BeaconChain) computeNewActiveState(seed common.Hash) (*types.ActiveStat
 	}, nil
 }
 
+// verifyAttestations checks the aggregate signature of a block against the
+// public keys of the sampled attesters whose bit is set in the block's
+// attestation bitmask. Blocks without any attestations are accepted without
+// a signature check, as nothing produces attestations yet, but they must not
+// carry a signature either.
+func (b *BeaconChain) verifyAttestations(block *types.Block, attesters []int) error {
+	bitmask := block.AttestationBitmask()
+	validators := b.CrystallizedState().ActiveValidators
+
+	var pubKeys []*bls.PublicKey
+	for i, index := range attesters {
+		if i/8 >= len(bitmask) || bitmask[i/8]&(0x80>>uint(i%8)) == 0 {
+			continue
+		}
+		pub, err := bls.PublicKeyFromBytes(validators[index].PubKey)
+		if err != nil {
+			return fmt.Errorf("invalid public key for validator %d: %v", index, err)
+		}
+		pubKeys = append(pubKeys, pub)
+	}
+	if len(pubKeys) == 0 {
+		if len(block.AttestationAggregateSig()) != 0 {
+			return errors.New("aggregate signature without attesters")
+		}
+		return nil
+	}
+
+	sig, err := bls.SignatureFromBytes(block.AttestationAggregateSig())
+	if err != nil {
+		return err
+	}
+	msg := block.AttestationMessage()
+	if !sig.VerifyAggregate(pubKeys, msg[:]) {
+		return errors.New("aggregate signature does not match attesters")
+	}
+	return nil
+}
+
 // getAttestersProposer returns lists of random sampled attesters and proposer indices.
 func (b *BeaconChain) getAttestersProposer(seed common.Hash) ([]int, int, error) {
+	if len(b.CrystallizedState().ActiveValidators) == 0 {
+		return nil, -1, errors.New("no active validators to sample attesters and proposer from")
+	}
 	attesterCount := math.Min(params.AttesterCount, float64(len(b.CrystallizedState().ActiveValidators)))
 	indices, err := utils.ShuffleIndices(seed, len(b.CrystallizedState().ActiveValidators))
 	if err != nil {
@@ -3,18 +3,20 @@ package blockchain
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"errors"
 	"math"
+	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	gethTypes "github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/prysmaticlabs/prysm/beacon-chain/params"
 	"github.com/prysmaticlabs/prysm/beacon-chain/types"
 	"github.com/prysmaticlabs/prysm/beacon-chain/utils"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+	"github.com/prysmaticlabs/prysm/shared/crypto/bls"
 	"github.com/prysmaticlabs/prysm/shared/database"
 	logTest "github.com/sirupsen/logrus/hooks/test"
 )
@@ -25,13 +27,57 @@ func (f *faultyFetcher) BlockByHash(ctx context.Context, hash common.Hash) (*get
 	return nil, errors.New("cannot fetch block")
 }
 
-type mockFetcher struct{}
+func (f *faultyFetcher) CanonicalHeader(number uint64) *gethTypes.Header {
+	return nil
+}
+
+func (f *faultyFetcher) ConfirmedBlock() *gethTypes.Header {
+	return nil
+}
+
+// mockFetcher serves mainchain blocks at the given number, and confirms
+// blocks up to the confirmed number. The served blocks are canonical unless
+// reorged is set.
+type mockFetcher struct {
+	number    int64
+	confirmed int64
+	reorged   bool
+}
 
 func (m *mockFetcher) BlockByHash(ctx context.Context, hash common.Hash) (*gethTypes.Block, error) {
-	block := gethTypes.NewBlock(&gethTypes.Header{}, nil, nil, nil)
+	block := gethTypes.NewBlock(mockMainchainHeader(uint64(m.number)), nil, nil, nil)
 	return block, nil
 }
 
+func (m *mockFetcher) CanonicalHeader(number uint64) *gethTypes.Header {
+	header := mockMainchainHeader(number)
+	if m.reorged {
+		header.Extra = []byte("reorged")
+	}
+	return header
+}
+
+func (m *mockFetcher) ConfirmedBlock() *gethTypes.Header {
+	return &gethTypes.Header{Number: big.NewInt(m.confirmed)}
+}
+
+// mockMainchainHeader returns the header of the mainchain block served by
+// mockFetcher at the number.
+func mockMainchainHeader(number uint64) *gethTypes.Header {
+	return &gethTypes.Header{Number: new(big.Int).SetUint64(number)}
+}
+
+// newBlockWithMainchainRef returns a block at the slot referencing the
+// canonical mainchain block served by mockFetcher at number 0.
+func newBlockWithMainchainRef(t *testing.T, slot uint64) *types.Block {
+	ref := mockMainchainHeader(0).Hash()
+	block, err := types.NewBlockWithData(&pb.BeaconBlockResponse{SlotNumber: slot, MainChainRef: ref[:]})
+	if err != nil {
+		t.Fatalf("Could not create block: %v", err)
+	}
+	return block
+}
+
 func startInMemoryBeaconChain(t *testing.T) (*BeaconChain, *database.DB) {
 	config := &database.DBConfig{DataDir: "", Name: "", InMemory: true}
 	db, err := database.NewDB(config)
@@ -130,18 +176,24 @@ func TestGetAttestersProposer(t *testing.T) {
 	beaconChain, db := startInMemoryBeaconChain(t)
 	defer db.Close()
 
-	priv, err := crypto.GenerateKey()
+	priv, err := bls.RandKey(rand.Reader)
 	if err != nil {
 		t.Fatalf("Could not generate key: %v", err)
 	}
+	pubKey := priv.PublicKey().Marshal()
 
 	var validators []types.ValidatorRecord
 	// Create 1000 validators in ActiveValidators.
 	for i := 0; i < 1000; i++ {
-		validator := types.ValidatorRecord{WithdrawalAddress: common.Address{'A'}, PubKey: enr.Secp256k1(priv.PublicKey)}
+		validator := types.ValidatorRecord{WithdrawalAddress: common.Address{'A'}, PubKey: pubKey}
 		validators = append(validators, validator)
 	}
 
+	// Attesters and proposer cannot be sampled without active validators.
+	if _, _, err := beaconChain.getAttestersProposer(common.Hash{'A'}); err == nil {
+		t.Error("Expected an error without active validators")
+	}
+
 	beaconChain.MutateCrystallizedState(&types.CrystallizedState{ActiveValidators: validators})
 
 	attesters, propser, err := beaconChain.getAttestersProposer(common.Hash{'A'})
@@ -162,11 +214,124 @@ func TestGetAttestersProposer(t *testing.T) {
 	}
 }
 
+func TestVerifyAttestations(t *testing.T) {
+	beaconChain, db := startInMemoryBeaconChain(t)
+	defer db.Close()
+
+	var keys []*bls.SecretKey
+	var validators []types.ValidatorRecord
+	for i := 0; i < 3; i++ {
+		priv, err := bls.RandKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("Could not generate key: %v", err)
+		}
+		keys = append(keys, priv)
+		validators = append(validators, types.ValidatorRecord{PubKey: priv.PublicKey().Marshal()})
+	}
+	beaconChain.MutateCrystallizedState(&types.CrystallizedState{ActiveValidators: validators})
+
+	attesters := []int{2, 0, 1}
+	block, err := types.NewBlockWithData(&pb.BeaconBlockResponse{SlotNumber: 1, ParentHash: []byte{'A'}})
+	if err != nil {
+		t.Fatalf("Could not create block: %v", err)
+	}
+	if err := beaconChain.verifyAttestations(block, attesters); err != nil {
+		t.Errorf("Block without attestations should be valid: %v", err)
+	}
+
+	// Attesters at positions 0 and 2 sign, which are validators 2 and 1.
+	msg := block.AttestationMessage()
+	sig := bls.AggregateSignatures([]*bls.Signature{keys[2].Sign(msg[:]), keys[1].Sign(msg[:])})
+	block, err = types.NewBlockWithData(&pb.BeaconBlockResponse{
+		SlotNumber:              1,
+		ParentHash:              []byte{'A'},
+		AttestationBitmask:      []byte{0xA0},
+		AttestationAggregateSig: sig.Marshal(),
+	})
+	if err != nil {
+		t.Fatalf("Could not create block: %v", err)
+	}
+	if err := beaconChain.verifyAttestations(block, attesters); err != nil {
+		t.Errorf("Attestations should be valid: %v", err)
+	}
+
+	// A signature without any attester bit set must not be accepted
+	// unchecked.
+	unsigned, err := types.NewBlockWithData(&pb.BeaconBlockResponse{
+		SlotNumber:              1,
+		ParentHash:              []byte{'A'},
+		AttestationBitmask:      []byte{0x00},
+		AttestationAggregateSig: sig.Marshal(),
+	})
+	if err != nil {
+		t.Fatalf("Could not create block: %v", err)
+	}
+	if err := beaconChain.verifyAttestations(unsigned, attesters); err == nil {
+		t.Error("Signature without attesters should be invalid")
+	}
+
+	// Claiming validator 0 also signed must fail verification.
+	block, err = types.NewBlockWithData(&pb.BeaconBlockResponse{
+		SlotNumber:              1,
+		ParentHash:              []byte{'A'},
+		AttestationBitmask:      []byte{0xE0},
+		AttestationAggregateSig: sig.Marshal(),
+	})
+	if err != nil {
+		t.Fatalf("Could not create block: %v", err)
+	}
+	if err := beaconChain.verifyAttestations(block, attesters); err == nil {
+		t.Error("Attestations with a missing signature should be invalid")
+	}
+}
+
+func TestSaveAndGetBlock(t *testing.T) {
+	beaconChain, db := startInMemoryBeaconChain(t)
+	defer db.Close()
+
+	block := types.NewBlock(5)
+	h, err := block.Hash()
+	if err != nil {
+		t.Fatalf("Could not hash block: %v", err)
+	}
+
+	has, err := beaconChain.HasBlock(h)
+	if err != nil {
+		t.Fatalf("HasBlock failed: %v", err)
+	}
+	if has {
+		t.Fatal("Block should not exist before it is saved")
+	}
+
+	if err := beaconChain.SaveBlock(block); err != nil {
+		t.Fatalf("SaveBlock failed: %v", err)
+	}
+	has, err = beaconChain.HasBlock(h)
+	if err != nil {
+		t.Fatalf("HasBlock failed: %v", err)
+	}
+	if !has {
+		t.Fatal("Block should exist after it is saved")
+	}
+
+	stored, err := beaconChain.GetBlock(h)
+	if err != nil {
+		t.Fatalf("GetBlock failed: %v", err)
+	}
+	storedHash, err := stored.Hash()
+	if err != nil {
+		t.Fatalf("Could not hash block: %v", err)
+	}
+	if storedHash != h {
+		t.Errorf("Stored block hash mismatch. wanted=%x, got=%x", h, storedHash)
+	}
+}
+
 func TestCanProcessBlock(t *testing.T) {
 	beaconChain, db := startInMemoryBeaconChain(t)
 	defer db.Close()
 
-	block := types.NewBlock(1)
+	block := newBlockWithMainchainRef(t, 1)
 	// Using a faulty fetcher should throw an error.
 	if _, err := beaconChain.CanProcessBlock(&faultyFetcher{}, block); err == nil {
 		t.Errorf("Using a faulty fetcher should throw an error, received nil")
@@ -194,9 +359,27 @@ func TestCanProcessBlock(t *testing.T) {
 		t.Errorf("Should be able to process block, could not")
 	}
 
+	// The mainchain reference must have enough confirmations.
+	canProcess, err = beaconChain.CanProcessBlock(&mockFetcher{number: 11, confirmed: 10}, block)
+	if err != nil {
+		t.Fatalf("CanProcessBlocks failed: %v", err)
+	}
+	if canProcess {
+		t.Errorf("Should not be able to process block referencing an unconfirmed mainchain block")
+	}
+
+	// The mainchain reference must be part of the canonical mainchain.
+	canProcess, err = beaconChain.CanProcessBlock(&mockFetcher{reorged: true}, block)
+	if err != nil {
+		t.Fatalf("CanProcessBlocks failed: %v", err)
+	}
+	if canProcess {
+		t.Errorf("Should not be able to process block referencing a reorganized mainchain block")
+	}
+
 	// Attempting to try a block with that fails the timestamp validity
 	// condition.
-	block = types.NewBlock(1000000)
+	block = newBlockWithMainchainRef(t, 1000000)
 	block.InsertActiveHash(activeHash)
 	block.InsertCrystallizedHash(crystallizedHash)
 	canProcess, err = beaconChain.CanProcessBlock(&mockFetcher{}, block)
@@ -213,7 +396,7 @@ func TestProcessBlockWithBadHashes(t *testing.T) {
 	defer db.Close()
 
 	// Test negative scenario where active state hash is different than node's compute
-	block := types.NewBlock(1)
+	block := newBlockWithMainchainRef(t, 1)
 	activeState := &types.ActiveState{TotalAttesterDeposits: 10000}
 	stateHash, err := hashActiveState(activeState)
 	if err != nil {
@@ -383,10 +566,11 @@ func TestApplyRewardAndPenalty(t *testing.T) {
 	beaconChain, db := startInMemoryBeaconChain(t)
 	defer db.Close()
 
-	priv, err := crypto.GenerateKey()
+	priv, err := bls.RandKey(rand.Reader)
 	if err != nil {
 		t.Fatalf("Could not generate key: %v", err)
 	}
+	pubKey := priv.PublicKey().Marshal()
 
 	balance1 := uint64(10000)
 	balance2 := uint64(15000)
@@ -395,11 +579,11 @@ func TestApplyRewardAndPenalty(t *testing.T) {
 	balance5 := uint64(30000)
 
 	activeValidators := &types.CrystallizedState{ActiveValidators: []types.ValidatorRecord{
-		{Balance: balance1, WithdrawalAddress: common.Address{'A'}, PubKey: enr.Secp256k1(priv.PublicKey)},
-		{Balance: balance2, WithdrawalAddress: common.Address{'B'}, PubKey: enr.Secp256k1(priv.PublicKey)},
-		{Balance: balance3, WithdrawalAddress: common.Address{'C'}, PubKey: enr.Secp256k1(priv.PublicKey)},
-		{Balance: balance4, WithdrawalAddress: common.Address{'D'}, PubKey: enr.Secp256k1(priv.PublicKey)},
-		{Balance: balance5, WithdrawalAddress: common.Address{'E'}, PubKey: enr.Secp256k1(priv.PublicKey)},
+		{Balance: balance1, WithdrawalAddress: common.Address{'A'}, PubKey: pubKey},
+		{Balance: balance2, WithdrawalAddress: common.Address{'B'}, PubKey: pubKey},
+		{Balance: balance3, WithdrawalAddress: common.Address{'C'}, PubKey: pubKey},
+		{Balance: balance4, WithdrawalAddress: common.Address{'D'}, PubKey: pubKey},
+		{Balance: balance5, WithdrawalAddress: common.Address{'E'}, PubKey: pubKey},
 	}}
 
 	if err := beaconChain.MutateCrystallizedState(activeValidators); err != nil {
@@ -444,10 +628,11 @@ func TestResetAttesterBitfields(t *testing.T) {
 	beaconChain, db := startInMemoryBeaconChain(t)
 	defer db.Close()
 
-	priv, err := crypto.GenerateKey()
+	priv, err := bls.RandKey(rand.Reader)
 	if err != nil {
 		t.Fatalf("Could not generate key: %v", err)
 	}
+	pubKey := priv.PublicKey().Marshal()
 
 	// Testing validator set sizes from 1 to 100.
 
@@ -456,7 +641,7 @@ func TestResetAttesterBitfields(t *testing.T) {
 		var validators []types.ValidatorRecord
 
 		for i := 0; i < j; i++ {
-			validator := types.ValidatorRecord{WithdrawalAddress: common.Address{'A'}, PubKey: enr.Secp256k1(priv.PublicKey)}
+			validator := types.ValidatorRecord{WithdrawalAddress: common.Address{'A'}, PubKey: pubKey}
 			validators = append(validators, validator)
 		}
 
@@ -566,15 +751,16 @@ func TestUpdateJustifiedEpoch(t *testing.T) {
 func TestUpdateRewardsAndPenalties(t *testing.T) {
 	beaconChain, db := startInMemoryBeaconChain(t)
 	defer db.Close()
-	priv, err := crypto.GenerateKey()
+	priv, err := bls.RandKey(rand.Reader)
 	if err != nil {
 		t.Fatalf("Could not generate key: %v", err)
 	}
+	pubKey := priv.PublicKey().Marshal()
 
 	var validators []types.ValidatorRecord
 
 	for i := 0; i < 40; i++ {
-		validator := types.ValidatorRecord{Balance: 1000, WithdrawalAddress: common.Address{'A'}, PubKey: enr.Secp256k1(priv.PublicKey)}
+		validator := types.ValidatorRecord{Balance: 1000, WithdrawalAddress: common.Address{'A'}, PubKey: pubKey}
 		validators = append(validators, validator)
 	}
 
@@ -633,15 +819,16 @@ func TestUpdateRewardsAndPenalties(t *testing.T) {
 func TestComputeValidatorRewardsAndPenalties(t *testing.T) {
 	beaconChain, db := startInMemoryBeaconChain(t)
 	defer db.Close()
-	priv, err := crypto.GenerateKey()
+	priv, err := bls.RandKey(rand.Reader)
 	if err != nil {
 		t.Fatalf("Could not generate key: %v", err)
 	}
+	pubKey := priv.PublicKey().Marshal()
 
 	var validators []types.ValidatorRecord
 
 	for i := 0; i < 40; i++ {
-		validator := types.ValidatorRecord{Balance: 1000, WithdrawalAddress: common.Address{'A'}, PubKey: enr.Secp256k1(priv.PublicKey)}
+		validator := types.ValidatorRecord{Balance: 1000, WithdrawalAddress: common.Address{'A'}, PubKey: pubKey}
 		validators = append(validators, validator)
 	}
 
@@ -709,3 +896,46 @@ func unique(ints []int) []int {
 	return list
 
 }
+
+func TestCanonicalHead(t *testing.T) {
+	beaconChain, db := startInMemoryBeaconChain(t)
+	defer db.Close()
+
+	head, err := beaconChain.CanonicalHead()
+	if err != nil {
+		t.Fatalf("CanonicalHead failed: %v", err)
+	}
+	if head.SlotNumber() != 0 {
+		t.Errorf("Expected genesis block as canonical head, got slot %d", head.SlotNumber())
+	}
+
+	block := types.NewBlock(5)
+	if err := beaconChain.SaveBlock(block); err != nil {
+		t.Fatalf("SaveBlock failed: %v", err)
+	}
+	if err := beaconChain.SetCanonicalBlock(block); err != nil {
+		t.Fatalf("SetCanonicalBlock failed: %v", err)
+	}
+	head, err = beaconChain.CanonicalHead()
+	if err != nil {
+		t.Fatalf("CanonicalHead failed: %v", err)
+	}
+	if head.SlotNumber() != 5 {
+		t.Errorf("Expected canonical head at slot 5, got slot %d", head.SlotNumber())
+	}
+
+	stored, err := beaconChain.CanonicalBlockBySlot(5)
+	if err != nil {
+		t.Fatalf("CanonicalBlockBySlot failed: %v", err)
+	}
+	if stored == nil || stored.SlotNumber() != 5 {
+		t.Errorf("Expected canonical block at slot 5, got %v", stored)
+	}
+	stored, err = beaconChain.CanonicalBlockBySlot(4)
+	if err != nil {
+		t.Fatalf("CanonicalBlockBySlot failed: %v", err)
+	}
+	if stored != nil {
+		t.Errorf("Expected no canonical block at slot 4, got %v", stored)
+	}
+}
@@ -2,6 +2,8 @@ package blockchain
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/prysmaticlabs/prysm/beacon-chain/powchain"
 	"github.com/prysmaticlabs/prysm/beacon-chain/types"
@@ -19,15 +21,22 @@ type ChainService struct {
 	beaconDB          *database.DB
 	chain             *BeaconChain
 	web3Service       *powchain.Web3Service
-	latestBeaconBlock chan *types.Block
+	latestBeaconBlock chan *processedBlock
 	processedHashes   [][32]byte
 }
 
+// processedBlock is a block accepted by ProcessBlock along with the active
+// state computed while validating it.
+type processedBlock struct {
+	block       *types.Block
+	activeState *types.ActiveState
+}
+
 // NewChainService instantiates a new service instance that will
 // be registered into a running beacon node.
 func NewChainService(ctx context.Context, beaconDB *database.DB, web3Service *powchain.Web3Service) (*ChainService, error) {
 	ctx, cancel := context.WithCancel(ctx)
-	return &ChainService{ctx, cancel, beaconDB, nil, web3Service, nil, nil}, nil
+	return &ChainService{ctx, cancel, beaconDB, nil, web3Service, make(chan *processedBlock), nil}, nil
 }
 
 // Start a blockchain service's main event loop.
@@ -54,36 +63,85 @@ func (c *ChainService) ProcessedHashes() [][32]byte {
 	return c.processedHashes
 }
 
-// ProcessBlock accepts a new block for inclusion in the chain.
+// ProcessBlock validates a new block and accepts it for inclusion in the
+// chain. Blocks that fail validation are not saved and the error is returned
+// to the caller.
 func (c *ChainService) ProcessBlock(b *types.Block) error {
-	c.latestBeaconBlock <- b
-	return nil
+	canProcess, err := c.chain.CanProcessBlock(c.web3Service, b)
+	if err != nil {
+		return fmt.Errorf("could not validate block: %v", err)
+	}
+	if !canProcess {
+		return errors.New("block cannot be processed")
+	}
+	// TODO: Using latest block hash for seed, this will eventually be replaced by randao
+	activeState, err := c.chain.computeNewActiveState(c.web3Service.LatestBlockHash(), b)
+	if err != nil {
+		return fmt.Errorf("compute active state failed: %v", err)
+	}
+
+	if err := c.chain.SaveBlock(b); err != nil {
+		return fmt.Errorf("could not save block: %v", err)
+	}
+	// TODO: Apply fork choice instead of treating every block as canonical.
+	if err := c.chain.SetCanonicalBlock(b); err != nil {
+		return fmt.Errorf("could not mark block as canonical: %v", err)
+	}
+	select {
+	case c.latestBeaconBlock <- &processedBlock{block: b, activeState: activeState}:
+		return nil
+	case <-c.ctx.Done():
+		return fmt.Errorf("chain service stopped before applying block: %v", c.ctx.Err())
+	}
 }
 
 // ContainsBlock checks if a block for the hash exists in the chain.
 // This method must be safe to call from a goroutine
 func (c *ChainService) ContainsBlock(h [32]byte) bool {
-	// TODO
-	return false
+	has, err := c.chain.HasBlock(h)
+	if err != nil {
+		log.Errorf("Could not check if block exists: %v", err)
+		return false
+	}
+	return has
 }
 
-// updateChainState receives a beacon block, computes a new active state and writes it to db. Also
+// GetBlock returns the block for the hash from the local chain.
+// This method must be safe to call from a goroutine
+func (c *ChainService) GetBlock(h [32]byte) (*types.Block, error) {
+	return c.chain.GetBlock(h)
+}
+
+// CanonicalHead returns the latest block of the canonical chain.
+// This method must be safe to call from a goroutine
+func (c *ChainService) CanonicalHead() (*types.Block, error) {
+	return c.chain.CanonicalHead()
+}
+
+// CanonicalBlockBySlot returns the canonical block at the slot, or nil if
+// the slot is empty.
+// This method must be safe to call from a goroutine
+func (c *ChainService) CanonicalBlockBySlot(slot uint64) (*types.Block, error) {
+	return c.chain.CanonicalBlockBySlot(slot)
+}
+
+// LastFinalizedEpoch returns the last epoch finalized by the beacon chain.
+func (c *ChainService) LastFinalizedEpoch() uint64 {
+	return c.chain.CrystallizedState().LastFinalizedEpoch
+}
+
+// updateChainState receives a validated beacon block with its new active state and writes it to db. Also
 // it checks for if there is an epoch transition. If there is one it computes the validator rewards
 // and penalties.
 func (c *ChainService) updateChainState() {
 	for {
 		select {
-		case block := <-c.latestBeaconBlock:
+		case processed := <-c.latestBeaconBlock:
+			block := processed.block
 			activeStateHash := block.ActiveStateHash()
 			log.WithFields(logrus.Fields{"activeStateHash": activeStateHash}).Debug("Received beacon block")
 
-			// TODO: Using latest block hash for seed, this will eventually be replaced by randao
-			activeState, err := c.chain.computeNewActiveState(c.web3Service.LatestBlockHash())
-			if err != nil {
-				log.Errorf("Compute active state failed: %v", err)
-			}
-
-			err = c.chain.MutateActiveState(activeState)
+			err := c.chain.MutateActiveState(processed.activeState)
 			if err != nil {
 				log.Errorf("Write active state to disk failed: %v", err)
 			}
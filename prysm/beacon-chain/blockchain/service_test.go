@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/prysmaticlabs/prysm/beacon-chain/powchain"
+	"github.com/prysmaticlabs/prysm/beacon-chain/types"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
 	"github.com/prysmaticlabs/prysm/shared/database"
 	logTest "github.com/sirupsen/logrus/hooks/test"
 )
@@ -62,3 +65,118 @@ func TestStartStop(t *testing.T) {
 	}
 	hook.Reset()
 }
+
+func TestProcessBlockRejectsInvalidBlock(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewDB(&database.DBConfig{InMemory: true})
+	if err != nil {
+		t.Fatalf("could not setup beaconDB: %v", err)
+	}
+	// The PoW chain service is not connected, so the main chain reference
+	// of the block cannot be found.
+	web3Service, err := powchain.NewWeb3Service(ctx, &powchain.Web3ServiceConfig{Endpoint: "ws://127.0.0.1", Pubkey: "", VrcAddr: common.Address{}})
+	if err != nil {
+		t.Fatalf("unable to set up web3 service: %v", err)
+	}
+	chainService, err := NewChainService(ctx, db, web3Service)
+	if err != nil {
+		t.Fatalf("unable to setup chain service: %v", err)
+	}
+	chainService.Start()
+	defer chainService.Stop()
+
+	block := types.NewBlock(1)
+	if err := chainService.ProcessBlock(block); err == nil {
+		t.Fatal("expected block without a known main chain reference to be rejected")
+	}
+	h, err := block.Hash()
+	if err != nil {
+		t.Fatalf("could not hash block: %v", err)
+	}
+	if chainService.ContainsBlock(h) {
+		t.Error("rejected block should not have been saved")
+	}
+}
+
+func TestProcessBlockReferencingConfirmedBlock(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewDB(&database.DBConfig{InMemory: true})
+	if err != nil {
+		t.Fatalf("could not setup beaconDB: %v", err)
+	}
+	web3Service, err := powchain.NewWeb3Service(ctx, &powchain.Web3ServiceConfig{Endpoint: powchain.SimulatedEndpoint, FollowDistance: 1})
+	if err != nil {
+		t.Fatalf("unable to set up web3 service: %v", err)
+	}
+	web3Service.Start()
+	defer web3Service.Stop()
+
+	// Wait for a PoW block with enough confirmations to be referenced.
+	deadline := time.Now().Add(10 * time.Second)
+	for web3Service.ConfirmedBlock() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("no confirmed PoW block")
+		}
+		web3Service.Simulated().Commit()
+		time.Sleep(10 * time.Millisecond)
+	}
+	mainchainRef := web3Service.ConfirmedBlock().Hash()
+
+	chainService, err := NewChainService(ctx, db, web3Service)
+	if err != nil {
+		t.Fatalf("unable to setup chain service: %v", err)
+	}
+	chainService.Start()
+	defer chainService.Stop()
+
+	// Attesters and proposer are sampled from the active validators.
+	validators := []types.ValidatorRecord{{WithdrawalAddress: common.Address{'A'}}}
+	if err := chainService.chain.MutateCrystallizedState(&types.CrystallizedState{ActiveValidators: validators}); err != nil {
+		t.Fatalf("could not set crystallized state: %v", err)
+	}
+	activeStateHash, err := hashActiveState(chainService.chain.ActiveState())
+	if err != nil {
+		t.Fatalf("cannot hash active state: %v", err)
+	}
+	crystallizedStateHash, err := hashCrystallizedState(chainService.chain.CrystallizedState())
+	if err != nil {
+		t.Fatalf("cannot hash crystallized state: %v", err)
+	}
+	block, err := types.NewBlockWithData(&pb.BeaconBlockResponse{
+		SlotNumber:            1,
+		MainChainRef:          mainchainRef[:],
+		ActiveStateHash:       activeStateHash[:],
+		CrystallizedStateHash: crystallizedStateHash[:],
+	})
+	if err != nil {
+		t.Fatalf("could not create block: %v", err)
+	}
+	// The main chain reference is resolved through the PoW block cache and
+	// header batches of the web3 service.
+	if err := chainService.ProcessBlock(block); err != nil {
+		t.Fatalf("could not process block: %v", err)
+	}
+	h, err := block.Hash()
+	if err != nil {
+		t.Fatalf("could not hash block: %v", err)
+	}
+	if !chainService.ContainsBlock(h) {
+		t.Error("processed block should have been saved")
+	}
+	// Once the service is stopped, nothing applies processed blocks anymore.
+	if err := chainService.Stop(); err != nil {
+		t.Fatalf("could not stop chain service: %v", err)
+	}
+	processed := make(chan error, 1)
+	go func() {
+		processed <- chainService.ProcessBlock(block)
+	}()
+	select {
+	case err := <-processed:
+		if err == nil {
+			t.Error("expected processing to fail once the service is stopped")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("processing a block blocked after the service stopped")
+	}
+}
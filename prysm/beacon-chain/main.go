@@ -58,7 +58,7 @@ VERSION:
 	app.Usage = "this is a beacon chain implementation for Ethereum 2.0"
 	app.Action = startNode
 
-	app.Flags = []cli.Flag{cmd.DataDirFlag, utils.VrcContractFlag, utils.PubKeyFlag, utils.Web3ProviderFlag, cmd.VerbosityFlag, debug.PProfFlag, debug.PProfAddrFlag, debug.PProfPortFlag, debug.MemProfileRateFlag, debug.CPUProfileFlag, debug.TraceFlag}
+	app.Flags = []cli.Flag{cmd.DataDirFlag, cmd.NetworkIDFlag, utils.VrcContractFlag, utils.VrcDeploymentBlockFlag, utils.PubKeyFlag, utils.Web3ProviderFlag, utils.FollowDistanceFlag, cmd.P2PListenFlag, cmd.P2PExternalAddrFlag, cmd.NodeKeyFlag, cmd.StaticPeersFlag, cmd.BootstrapNodesFlag, cmd.NoMDNSFlag, cmd.DHTFlag, cmd.TargetPeersFlag, cmd.MaxPeersFlag, cmd.P2PCompressFlag, cmd.P2PRecordFlag, cmd.P2PRecordSizeFlag, cmd.P2PReplayFlag, cmd.VerbosityFlag, debug.PProfFlag, debug.PProfAddrFlag, debug.PProfPortFlag, debug.MemProfileRateFlag, debug.CPUProfileFlag, debug.TraceFlag}
 
 	app.Before = func(ctx *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
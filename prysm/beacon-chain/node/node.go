@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"syscall"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/prysmaticlabs/prysm/beacon-chain/powchain"
 	rbcsync "github.com/prysmaticlabs/prysm/beacon-chain/sync"
 	"github.com/prysmaticlabs/prysm/beacon-chain/utils"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
 	"github.com/prysmaticlabs/prysm/shared"
 	"github.com/prysmaticlabs/prysm/shared/cmd"
 	"github.com/prysmaticlabs/prysm/shared/database"
@@ -24,6 +26,7 @@ import (
 
 var log = logrus.WithField("prefix", "node")
 var beaconChainDBName = "beaconchaindata"
+var nodeKeyFileName = "beaconnodekey"
 
 // BeaconNode defines a struct that handles the services running a random beacon chain
 // full PoS node. It handles the lifecycle of the entire system and registers
@@ -126,7 +129,26 @@ func (b *BeaconNode) startDB(ctx *cli.Context) error {
 }
 
 func (b *BeaconNode) registerP2P() error {
-	beaconp2p, err := p2p.NewServer()
+	keyFile := b.ctx.GlobalString(cmd.NodeKeyFlag.Name)
+	if keyFile == "" {
+		keyFile = filepath.Join(b.ctx.GlobalString(cmd.DataDirFlag.Name), nodeKeyFileName)
+	}
+	beaconp2p, err := p2p.NewServer(&p2p.ServerConfig{
+		ListenAddrs:       b.ctx.GlobalStringSlice(cmd.P2PListenFlag.Name),
+		ExternalAddr:      b.ctx.GlobalString(cmd.P2PExternalAddrFlag.Name),
+		KeyFile:           keyFile,
+		StaticPeers:       b.ctx.GlobalStringSlice(cmd.StaticPeersFlag.Name),
+		BootstrapNodes:    b.ctx.GlobalStringSlice(cmd.BootstrapNodesFlag.Name),
+		NoMDNS:            b.ctx.GlobalBool(cmd.NoMDNSFlag.Name),
+		EnableDHT:         b.ctx.GlobalBool(cmd.DHTFlag.Name),
+		TargetPeers:       b.ctx.GlobalInt(cmd.TargetPeersFlag.Name),
+		MaxPeers:          b.ctx.GlobalInt(cmd.MaxPeersFlag.Name),
+		NetworkID:         b.ctx.GlobalUint64(cmd.NetworkIDFlag.Name),
+		EnableCompression: b.ctx.GlobalBool(cmd.P2PCompressFlag.Name),
+		RecordFile:        b.ctx.GlobalString(cmd.P2PRecordFlag.Name),
+		RecordFileSize:    int64(b.ctx.GlobalInt(cmd.P2PRecordSizeFlag.Name)) << 20,
+		ReplayPeers:       b.ctx.GlobalStringSlice(cmd.P2PReplayFlag.Name),
+	})
 	if err != nil {
 		return fmt.Errorf("could not register p2p service: %v", err)
 	}
@@ -148,13 +170,24 @@ func (b *BeaconNode) registerBlockchainService() error {
 
 func (b *BeaconNode) registerPOWChainService() error {
 	web3Service, err := powchain.NewWeb3Service(context.TODO(), &powchain.Web3ServiceConfig{
-		Endpoint: b.ctx.GlobalString(utils.Web3ProviderFlag.Name),
-		Pubkey:   b.ctx.GlobalString(utils.PubKeyFlag.Name),
-		VrcAddr:  common.HexToAddress(b.ctx.GlobalString(utils.VrcContractFlag.Name)),
+		Endpoint:        b.ctx.GlobalString(utils.Web3ProviderFlag.Name),
+		Pubkey:          b.ctx.GlobalString(utils.PubKeyFlag.Name),
+		VrcAddr:         common.HexToAddress(b.ctx.GlobalString(utils.VrcContractFlag.Name)),
+		FollowDistance:  b.ctx.GlobalUint64(utils.FollowDistanceFlag.Name),
+		DeploymentBlock: b.ctx.GlobalUint64(utils.VrcDeploymentBlockFlag.Name),
+		BeaconDB:        b.db,
 	})
 	if err != nil {
 		return fmt.Errorf("could not register proof-of-work chain web3Service: %v", err)
 	}
+	// Register the validator right away on a simulated PoW chain.
+	pubkey := b.ctx.GlobalString(utils.PubKeyFlag.Name)
+	if simulated := web3Service.Simulated(); simulated != nil && pubkey != "" {
+		if err := simulated.Deposit(common.HexToHash(pubkey)); err != nil {
+			return fmt.Errorf("could not deposit in simulated VRC: %v", err)
+		}
+		log.Infof("Deposited in simulated VRC at %#x", simulated.VRCAddress)
+	}
 	return b.services.RegisterService(web3Service)
 }
 
@@ -169,6 +202,22 @@ func (b *BeaconNode) registerSyncService() error {
 		return err
 	}
 
+	p2pService.SetStatusFunc(func() (*pb.Status, error) {
+		head, err := chainService.CanonicalHead()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := head.Hash()
+		if err != nil {
+			return nil, err
+		}
+		return &pb.Status{
+			HeadSlot:       head.SlotNumber(),
+			HeadHash:       hash[:],
+			FinalizedEpoch: chainService.LastFinalizedEpoch(),
+		}, nil
+	})
+
 	syncService := rbcsync.NewSyncService(context.Background(), rbcsync.DefaultConfig(), p2pService, chainService)
 	return b.services.RegisterService(syncService)
 }
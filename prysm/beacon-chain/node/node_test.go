@@ -2,6 +2,8 @@ package node
 
 import (
 	"flag"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/urfave/cli"
@@ -10,11 +12,17 @@ import (
 // Test that the sharding node can build with default flag values.
 func TestNode_Builds(t *testing.T) {
 	app := cli.NewApp()
+	tmp, err := ioutil.TempDir("", "beacon-node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
 	set := flag.NewFlagSet("test", 0)
+	set.String("datadir", tmp, "node data directory")
 	set.String("web3provider", "ws//127.0.0.1:8546", "web3 provider ws or IPC endpoint")
 	context := cli.NewContext(app, set, nil)
 
-	_, err := New(context)
+	_, err = New(context)
 	if err != nil {
 		t.Fatalf("Failed to create BeaconNode: %v", err)
 	}
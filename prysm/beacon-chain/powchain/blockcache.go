@@ -0,0 +1,59 @@
+package powchain
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// Number of PoW headers kept to validate the main chain references of beacon
+// blocks without querying the endpoint.
+var powBlockCacheSize = 1024
+
+// headerCache is a thread-safe LRU cache of PoW headers indexed by hash.
+type headerCache struct {
+	lock    sync.Mutex
+	size    int
+	entries *list.List // most recently used first.
+	byHash  map[common.Hash]*list.Element
+}
+
+func newHeaderCache(size int) *headerCache {
+	return &headerCache{
+		size:    size,
+		entries: list.New(),
+		byHash:  make(map[common.Hash]*list.Element),
+	}
+}
+
+// add inserts the header in the cache, evicting the least recently used
+// header if the cache is full.
+func (c *headerCache) add(header *gethTypes.Header) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	hash := header.Hash()
+	if elem, ok := c.byHash[hash]; ok {
+		c.entries.MoveToFront(elem)
+		return
+	}
+	c.byHash[hash] = c.entries.PushFront(header)
+	if c.entries.Len() > c.size {
+		oldest := c.entries.Back()
+		c.entries.Remove(oldest)
+		delete(c.byHash, oldest.Value.(*gethTypes.Header).Hash())
+	}
+}
+
+// get returns the header with the given hash, or nil if it is not cached.
+func (c *headerCache) get(hash common.Hash) *gethTypes.Header {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.byHash[hash]
+	if !ok {
+		return nil
+	}
+	c.entries.MoveToFront(elem)
+	return elem.Value.(*gethTypes.Header)
+}
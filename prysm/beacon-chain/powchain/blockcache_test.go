@@ -0,0 +1,30 @@
+package powchain
+
+import (
+	"math/big"
+	"testing"
+
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestHeaderCacheEviction(t *testing.T) {
+	headers := buildHeaders(&gethTypes.Header{Number: big.NewInt(0)}, 4, 'a')
+	cache := newHeaderCache(3)
+	for _, h := range headers[:3] {
+		cache.add(h)
+	}
+	// Using the oldest header makes the second one the least recently used.
+	if cache.get(headers[0].Hash()) != headers[0] {
+		t.Fatal("Expected header 1 to be cached")
+	}
+	cache.add(headers[3])
+
+	if cache.get(headers[1].Hash()) != nil {
+		t.Error("Expected least recently used header 2 to be evicted")
+	}
+	for _, i := range []int{0, 2, 3} {
+		if cache.get(headers[i].Hash()) != headers[i] {
+			t.Errorf("Expected header %d to be cached", i+1)
+		}
+	}
+}
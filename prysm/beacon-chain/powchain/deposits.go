@@ -0,0 +1,287 @@
+package powchain
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+// Keys of the deposit data persisted in the beacon DB.
+var (
+	lastScannedBlockKey = []byte("powchain-last-scanned-block")
+	depositCountKey     = []byte("powchain-deposit-count")
+)
+
+const depositPrefix = "powchain-deposit-"
+
+// logKey identifies a log by its transaction and its index in the block.
+type logKey struct {
+	txHash common.Hash
+	index  uint
+}
+
+func keyOf(l gethTypes.Log) logKey {
+	return logKey{txHash: l.TxHash, index: l.Index}
+}
+
+// processLog handles a ValidatorRegistered log from the VRC. Logs are kept
+// pending until their block has followDistance confirmations, and logs the
+// node removes because of a reorg are retracted.
+func (w *Web3Service) processLog(VRClog gethTypes.Log) {
+	if VRClog.Removed {
+		w.retractLog(VRClog)
+		return
+	}
+	if w.knownLog(VRClog) {
+		return
+	}
+	w.pendingDeposits = append(w.pendingDeposits, VRClog)
+	w.knownLogs[keyOf(VRClog)] = VRClog.BlockHash
+	w.confirmDeposits()
+}
+
+// knownLog reports whether the log is already pending or confirmed, as logs
+// may be received again when they are backfilled.
+func (w *Web3Service) knownLog(VRClog gethTypes.Log) bool {
+	blockHash, ok := w.knownLogs[keyOf(VRClog)]
+	return ok && blockHash == VRClog.BlockHash
+}
+
+// forgetLog removes a log that is no longer pending or confirmed from the
+// known logs.
+func (w *Web3Service) forgetLog(VRClog gethTypes.Log) {
+	if w.knownLog(VRClog) {
+		delete(w.knownLogs, keyOf(VRClog))
+	}
+}
+
+// confirmDeposits processes the pending deposits whose block is now
+// followDistance blocks deep. Deposits from blocks that are no longer part
+// of the chain are dropped.
+func (w *Web3Service) confirmDeposits() {
+	confirmed, ok := w.confirmedNumber()
+	if !ok && w.followDistance > 0 {
+		return
+	}
+	var pending []gethTypes.Log
+	for _, l := range w.pendingDeposits {
+		if w.followDistance > 0 && l.BlockNumber > confirmed {
+			pending = append(pending, l)
+			continue
+		}
+		if header := w.headers.byNumber(l.BlockNumber); header != nil && header.Hash() != l.BlockHash {
+			log.Debugf("Dropping VRC log from abandoned PoW block %#x", l.BlockHash)
+			w.forgetLog(l)
+			continue
+		}
+		w.deposits = append(w.deposits, l)
+		if err := w.saveDeposit(len(w.deposits)-1, l); err != nil {
+			log.Errorf("Could not persist VRC deposit: %v", err)
+		}
+		w.processDeposit(l)
+	}
+	w.pendingDeposits = pending
+}
+
+// processDeposit handles a confirmed deposit.
+func (w *Web3Service) processDeposit(VRClog gethTypes.Log) {
+	// public key is the second topic from validatorRegistered log and strip off 0x
+	pubKeyLog := VRClog.Topics[1].Hex()[2:]
+	if pubKeyLog == w.pubKey {
+		log.WithFields(logrus.Fields{
+			"publicKey": pubKeyLog,
+		}).Info("Validator registered in VRC with public key")
+		w.setValidatorRegistered(true)
+	}
+}
+
+// retractLog drops a pending or confirmed deposit whose log was removed from
+// the chain.
+func (w *Web3Service) retractLog(VRClog gethTypes.Log) {
+	w.retract(func(l gethTypes.Log) bool {
+		return sameLog(l, VRClog)
+	})
+}
+
+// retractBlocks drops the pending and confirmed deposits included in the
+// given blocks, which are no longer part of the chain.
+func (w *Web3Service) retractBlocks(headers []*gethTypes.Header) {
+	removed := make(map[common.Hash]bool)
+	for _, h := range headers {
+		removed[h.Hash()] = true
+	}
+	w.retract(func(l gethTypes.Log) bool {
+		return removed[l.BlockHash]
+	})
+}
+
+func (w *Web3Service) retract(match func(l gethTypes.Log) bool) {
+	var pending []gethTypes.Log
+	for _, l := range w.pendingDeposits {
+		if match(l) {
+			w.forgetLog(l)
+			continue
+		}
+		pending = append(pending, l)
+	}
+	w.pendingDeposits = pending
+
+	var deposits []gethTypes.Log
+	registered := false
+	for _, l := range w.deposits {
+		if match(l) {
+			w.forgetLog(l)
+			continue
+		}
+		deposits = append(deposits, l)
+		if l.Topics[1].Hex()[2:] == w.pubKey {
+			registered = true
+		}
+	}
+	if len(deposits) != len(w.deposits) {
+		if err := w.saveDeposits(deposits, len(w.deposits)); err != nil {
+			log.Errorf("Could not persist retracted VRC deposits: %v", err)
+		}
+	}
+	w.deposits = deposits
+
+	if w.ValidatorRegistered() && !registered {
+		log.WithFields(logrus.Fields{
+			"publicKey": w.pubKey,
+		}).Warn("Validator registration in VRC was reverted by a PoW chain reorg")
+		w.setValidatorRegistered(false)
+	}
+}
+
+// sameLog reports whether both logs are the same event of the same
+// transaction in the same block.
+func sameLog(a, b gethTypes.Log) bool {
+	return a.BlockHash == b.BlockHash && a.TxHash == b.TxHash && a.Index == b.Index
+}
+
+// checkpoint persists the number of the last block whose deposits are all
+// confirmed and persisted, so that a restarted node resumes scanning after
+// it.
+func (w *Web3Service) checkpoint() {
+	if w.db == nil || w.nextBlock == 0 {
+		return
+	}
+	last := w.nextBlock - 1
+	if w.followDistance > 0 {
+		confirmed, ok := w.confirmedNumber()
+		if !ok {
+			return
+		}
+		if confirmed < last {
+			last = confirmed
+		}
+	}
+	if last <= w.lastScanned {
+		return
+	}
+	if err := w.db.Put(lastScannedBlockKey, encodeUint64(last)); err != nil {
+		log.Errorf("Could not persist last scanned PoW block: %v", err)
+		return
+	}
+	w.lastScanned = last
+}
+
+// loadDeposits restores the deposits and the scanning progress persisted in
+// the beacon DB.
+func (w *Web3Service) loadDeposits() error {
+	has, err := w.db.Has(lastScannedBlockKey)
+	if err != nil {
+		return err
+	}
+	if has {
+		enc, err := w.db.Get(lastScannedBlockKey)
+		if err != nil {
+			return err
+		}
+		w.lastScanned = binary.BigEndian.Uint64(enc)
+		if w.lastScanned+1 > w.nextBlock {
+			w.nextBlock = w.lastScanned + 1
+		}
+	}
+
+	count, err := w.depositCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		enc, err := w.db.Get(depositKey(i))
+		if err != nil {
+			return err
+		}
+		var l gethTypes.Log
+		if err := json.Unmarshal(enc, &l); err != nil {
+			return fmt.Errorf("could not decode deposit %d: %v", i, err)
+		}
+		w.deposits = append(w.deposits, l)
+		w.knownLogs[keyOf(l)] = l.BlockHash
+		w.processDeposit(l)
+	}
+	if count > 0 {
+		log.Infof("Loaded %d VRC deposits, resuming scan from PoW block %d", count, w.nextBlock)
+	}
+	return nil
+}
+
+// saveDeposit persists the deposit at index i of the confirmed deposits.
+func (w *Web3Service) saveDeposit(i int, l gethTypes.Log) error {
+	if w.db == nil {
+		return nil
+	}
+	enc, err := json.Marshal(&l)
+	if err != nil {
+		return err
+	}
+	if err := w.db.Put(depositKey(i), enc); err != nil {
+		return err
+	}
+	return w.db.Put(depositCountKey, encodeUint64(uint64(i+1)))
+}
+
+// saveDeposits replaces the oldCount persisted deposits with the given ones.
+func (w *Web3Service) saveDeposits(deposits []gethTypes.Log, oldCount int) error {
+	if w.db == nil {
+		return nil
+	}
+	for i, l := range deposits {
+		if err := w.saveDeposit(i, l); err != nil {
+			return err
+		}
+	}
+	for i := len(deposits); i < oldCount; i++ {
+		if err := w.db.Delete(depositKey(i)); err != nil {
+			return err
+		}
+	}
+	return w.db.Put(depositCountKey, encodeUint64(uint64(len(deposits))))
+}
+
+func (w *Web3Service) depositCount() (int, error) {
+	has, err := w.db.Has(depositCountKey)
+	if err != nil || !has {
+		return 0, err
+	}
+	enc, err := w.db.Get(depositCountKey)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint64(enc)), nil
+}
+
+func depositKey(i int) []byte {
+	return append([]byte(depositPrefix), encodeUint64(uint64(i))...)
+}
+
+func encodeUint64(n uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, n)
+	return enc
+}
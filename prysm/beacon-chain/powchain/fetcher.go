@@ -0,0 +1,182 @@
+package powchain
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/prysmaticlabs/prysm/beacon-chain/types"
+)
+
+var _ = types.POWBlockFetcher(&Web3Service{})
+
+// Bounds of a batch of PoW header requests: misses are collected for at most
+// headerBatchDelay, or until headerBatchSize distinct headers are requested.
+var (
+	headerBatchDelay = 10 * time.Millisecond
+	headerBatchSize  = 64
+)
+
+var errNotConnected = errors.New("not connected to PoW chain")
+
+// headerBatcher retrieves several PoW headers with a single request. The
+// header of an unknown hash is nil.
+type headerBatcher interface {
+	HeadersByHash(ctx context.Context, hashes []common.Hash) ([]*gethTypes.Header, error)
+}
+
+// rpcClient is an ethclient that can also batch header requests.
+type rpcClient struct {
+	*ethclient.Client
+	rpc *rpc.Client
+}
+
+// HeadersByHash retrieves the headers with a batch of eth_getBlockByHash
+// calls.
+func (c *rpcClient) HeadersByHash(ctx context.Context, hashes []common.Hash) ([]*gethTypes.Header, error) {
+	headers := make([]*gethTypes.Header, len(hashes))
+	batch := make([]rpc.BatchElem, len(hashes))
+	for i, hash := range hashes {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getBlockByHash",
+			Args:   []interface{}{hash, false},
+			Result: &headers[i],
+		}
+	}
+	if err := c.rpc.BatchCallContext(ctx, batch); err != nil {
+		return nil, err
+	}
+	for _, elem := range batch {
+		if elem.Error != nil {
+			return nil, elem.Error
+		}
+	}
+	return headers, nil
+}
+
+type headerResult struct {
+	header *gethTypes.Header
+	err    error
+}
+
+// headerBatch coalesces concurrent header requests that missed the cache into
+// batched requests to the endpoint.
+type headerBatch struct {
+	lock    sync.Mutex
+	pending map[common.Hash][]chan headerResult
+	delay   time.Duration
+	size    int
+	source  func() headerBatcher
+	onFetch func(header *gethTypes.Header)
+}
+
+func newHeaderBatch(source func() headerBatcher, onFetch func(header *gethTypes.Header)) *headerBatch {
+	return &headerBatch{
+		pending: make(map[common.Hash][]chan headerResult),
+		delay:   headerBatchDelay,
+		size:    headerBatchSize,
+		source:  source,
+		onFetch: onFetch,
+	}
+}
+
+// fetch queues a request for the header and waits for the batch it belongs
+// to to be answered.
+func (b *headerBatch) fetch(ctx context.Context, hash common.Hash) (*gethTypes.Header, error) {
+	result := make(chan headerResult, 1)
+	b.lock.Lock()
+	b.pending[hash] = append(b.pending[hash], result)
+	switch len(b.pending) {
+	case 1:
+		if len(b.pending[hash]) == 1 {
+			time.AfterFunc(b.delay, b.flush)
+		}
+	case b.size:
+		go b.flush()
+	}
+	b.lock.Unlock()
+
+	select {
+	case res := <-result:
+		return res.header, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flush sends the pending requests as one batch and answers them.
+func (b *headerBatch) flush() {
+	b.lock.Lock()
+	pending := b.pending
+	b.pending = make(map[common.Hash][]chan headerResult)
+	b.lock.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	hashes := make([]common.Hash, 0, len(pending))
+	for hash := range pending {
+		hashes = append(hashes, hash)
+	}
+	var headers []*gethTypes.Header
+	err := errNotConnected
+	if source := b.source(); source != nil {
+		headers, err = source.HeadersByHash(context.Background(), hashes)
+	}
+	for i, hash := range hashes {
+		res := headerResult{err: err}
+		if err == nil {
+			res.header = headers[i]
+			if res.header != nil && b.onFetch != nil {
+				b.onFetch(res.header)
+			}
+		}
+		for _, ch := range pending[hash] {
+			ch <- res
+		}
+	}
+}
+
+// BlockByHash returns the PoW block with the given hash, or nil if the block
+// is unknown. Only the header of the block is filled. Recent blocks are
+// served from a cache populated by the head subscription, and other blocks
+// are requested in batches.
+func (w *Web3Service) BlockByHash(ctx context.Context, hash common.Hash) (*gethTypes.Block, error) {
+	header := w.blockCache.get(hash)
+	if header == nil {
+		var err error
+		if header, err = w.headerBatch.fetch(ctx, hash); err != nil {
+			return nil, err
+		}
+		if header == nil {
+			return nil, nil
+		}
+	}
+	return gethTypes.NewBlockWithHeader(header), nil
+}
+
+// CanonicalHeader returns the header of the canonical PoW chain at the given
+// number, or nil if the number is not among the recent headers kept to
+// detect reorgs.
+func (w *Web3Service) CanonicalHeader(number uint64) *gethTypes.Header {
+	w.headersLock.RLock()
+	defer w.headersLock.RUnlock()
+	return w.headers.byNumber(number)
+}
+
+func (w *Web3Service) batchSource() headerBatcher {
+	w.batcherLock.RLock()
+	defer w.batcherLock.RUnlock()
+	return w.batcher
+}
+
+func (w *Web3Service) setBatchSource(batcher headerBatcher) {
+	w.batcherLock.Lock()
+	defer w.batcherLock.Unlock()
+	w.batcher = batcher
+}
@@ -0,0 +1,130 @@
+package powchain
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+type mockBatcher struct {
+	lock    sync.Mutex
+	headers map[common.Hash]*gethTypes.Header
+	batches [][]common.Hash
+}
+
+func (m *mockBatcher) HeadersByHash(ctx context.Context, hashes []common.Hash) ([]*gethTypes.Header, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.batches = append(m.batches, hashes)
+	headers := make([]*gethTypes.Header, len(hashes))
+	for i, hash := range hashes {
+		headers[i] = m.headers[hash]
+	}
+	return headers, nil
+}
+
+func TestBlockByHashFromCache(t *testing.T) {
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: "ws://127.0.0.1"})
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	header := &gethTypes.Header{Number: big.NewInt(42)}
+	web3Service.processHeader(context.Background(), header)
+
+	// Not connected, so the block can only come from the cache.
+	block, err := web3Service.BlockByHash(context.Background(), header.Hash())
+	if err != nil {
+		t.Fatalf("Could not fetch cached block: %v", err)
+	}
+	if block.Hash() != header.Hash() {
+		t.Errorf("Expected block %#x, got %#x", header.Hash(), block.Hash())
+	}
+	if _, err := web3Service.BlockByHash(context.Background(), common.Hash{'a'}); err != errNotConnected {
+		t.Errorf("Expected %v for an uncached block, got %v", errNotConnected, err)
+	}
+}
+
+func TestBlockByHashBatchesMisses(t *testing.T) {
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: "ws://127.0.0.1"})
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	web3Service.headerBatch.delay = 50 * time.Millisecond
+	headers := buildHeaders(&gethTypes.Header{Number: big.NewInt(0)}, 3, 'a')
+	batcher := &mockBatcher{headers: make(map[common.Hash]*gethTypes.Header)}
+	for _, h := range headers[:2] {
+		batcher.headers[h.Hash()] = h
+	}
+	web3Service.setBatchSource(batcher)
+
+	// Two requests for each header, all sent within the batch delay.
+	blocks := make([]*gethTypes.Block, 6)
+	var wg sync.WaitGroup
+	for i := range blocks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			block, err := web3Service.BlockByHash(context.Background(), headers[i%3].Hash())
+			if err != nil {
+				t.Errorf("Could not fetch block: %v", err)
+			}
+			blocks[i] = block
+		}(i)
+	}
+	wg.Wait()
+
+	if len(batcher.batches) != 1 || len(batcher.batches[0]) != 3 {
+		t.Fatalf("Expected a single batch of 3 headers, got %v", batcher.batches)
+	}
+	for i, block := range blocks {
+		if i%3 == 2 {
+			if block != nil {
+				t.Errorf("Expected no block for an unknown hash, got %#x", block.Hash())
+			}
+			continue
+		}
+		if block == nil || block.Hash() != headers[i%3].Hash() {
+			t.Errorf("Expected block %#x for request %d, got %v", headers[i%3].Hash(), i, block)
+		}
+	}
+
+	// Fetched headers are cached.
+	if _, err := web3Service.BlockByHash(context.Background(), headers[0].Hash()); err != nil {
+		t.Fatalf("Could not fetch block: %v", err)
+	}
+	if len(batcher.batches) != 1 {
+		t.Errorf("Expected the block to be served from the cache, got batches %v", batcher.batches)
+	}
+}
+
+func TestBlockByHashFullBatch(t *testing.T) {
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: "ws://127.0.0.1"})
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	web3Service.headerBatch.delay = time.Hour
+	web3Service.headerBatch.size = 2
+	batcher := &mockBatcher{}
+	web3Service.setBatchSource(batcher)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := web3Service.BlockByHash(context.Background(), common.Hash{byte(i)}); err != nil {
+				t.Errorf("Could not fetch block: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(batcher.batches) != 1 || len(batcher.batches[0]) != 2 {
+		t.Errorf("Expected a full batch to be sent without waiting, got %v", batcher.batches)
+	}
+}
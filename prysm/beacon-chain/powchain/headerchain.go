@@ -0,0 +1,115 @@
+package powchain
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// Number of recent PoW headers kept to detect reorgs.
+var headerChainSize = 256
+
+// headerFetcher retrieves PoW headers that were not received from the head
+// subscription, such as the ancestors of a new head after a reorg.
+type headerFetcher interface {
+	HeaderByHash(ctx context.Context, hash common.Hash) (*gethTypes.Header, error)
+}
+
+// headerChain is a cache of the most recent headers of the canonical PoW
+// chain. The cached headers are always linked by their parent hashes, so a
+// header that is at least as deep as the length of the chain is known to be
+// part of the chain of the latest head.
+type headerChain struct {
+	headers []*gethTypes.Header // linked headers, in ascending order.
+	maxLen  int
+}
+
+func newHeaderChain(maxLen int) *headerChain {
+	return &headerChain{maxLen: maxLen}
+}
+
+// insert makes the header the head of the chain. If the header does not
+// extend the current head, its missing ancestors are retrieved with the
+// fetcher until they join the cached chain, and the headers of the
+// abandoned branch are returned. If the branch cannot be joined to the
+// cached chain, the cache is restarted from the new branch.
+func (hc *headerChain) insert(ctx context.Context, header *gethTypes.Header, fetcher headerFetcher) []*gethTypes.Header {
+	if i, ok := hc.index(header.Number.Uint64()); ok && hc.headers[i].Hash() == header.Hash() {
+		return hc.truncate(i + 1)
+	}
+
+	branch := []*gethTypes.Header{header}
+	for branch[0].Number.Sign() > 0 {
+		first := branch[0]
+		if i, ok := hc.index(first.Number.Uint64() - 1); ok && hc.headers[i].Hash() == first.ParentHash {
+			removed := hc.truncate(i + 1)
+			hc.headers = append(hc.headers, branch...)
+			hc.trim()
+			return removed
+		}
+		if len(hc.headers) == 0 || first.Number.Cmp(hc.headers[0].Number) <= 0 || len(branch) >= hc.maxLen || fetcher == nil {
+			break
+		}
+		parent, err := fetcher.HeaderByHash(ctx, first.ParentHash)
+		if err != nil || parent == nil {
+			log.Warnf("Could not retrieve PoW header %#x: %v", first.ParentHash, err)
+			break
+		}
+		branch = append([]*gethTypes.Header{parent}, branch...)
+	}
+
+	// Cached headers at the heights of the new branch are abandoned. Older
+	// ones cannot be verified anymore and are dropped as well.
+	var removed []*gethTypes.Header
+	for _, h := range hc.headers {
+		if h.Number.Cmp(branch[0].Number) >= 0 {
+			removed = append(removed, h)
+		}
+	}
+	hc.headers = branch
+	hc.trim()
+	return removed
+}
+
+// head returns the latest header, or nil if the chain is empty.
+func (hc *headerChain) head() *gethTypes.Header {
+	if len(hc.headers) == 0 {
+		return nil
+	}
+	return hc.headers[len(hc.headers)-1]
+}
+
+// byNumber returns the cached header at the given number, or nil if the
+// number is not cached.
+func (hc *headerChain) byNumber(number uint64) *gethTypes.Header {
+	i, ok := hc.index(number)
+	if !ok {
+		return nil
+	}
+	return hc.headers[i]
+}
+
+func (hc *headerChain) index(number uint64) (int, bool) {
+	if len(hc.headers) == 0 {
+		return 0, false
+	}
+	first := hc.headers[0].Number.Uint64()
+	if number < first || number-first >= uint64(len(hc.headers)) {
+		return 0, false
+	}
+	return int(number - first), true
+}
+
+// truncate drops the headers from index i on and returns them.
+func (hc *headerChain) truncate(i int) []*gethTypes.Header {
+	removed := append([]*gethTypes.Header{}, hc.headers[i:]...)
+	hc.headers = hc.headers[:i]
+	return removed
+}
+
+func (hc *headerChain) trim() {
+	if len(hc.headers) > hc.maxLen {
+		hc.headers = hc.headers[len(hc.headers)-hc.maxLen:]
+	}
+}
@@ -0,0 +1,105 @@
+package powchain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+type mockFetcher struct {
+	headers map[common.Hash]*gethTypes.Header
+}
+
+func (m *mockFetcher) HeaderByHash(ctx context.Context, hash common.Hash) (*gethTypes.Header, error) {
+	h, ok := m.headers[hash]
+	if !ok {
+		return nil, errors.New("header not found")
+	}
+	return h, nil
+}
+
+// buildHeaders returns n headers extending parent. The extra data tells
+// apart headers of different branches at the same height.
+func buildHeaders(parent *gethTypes.Header, n int, extra byte) []*gethTypes.Header {
+	var headers []*gethTypes.Header
+	for i := 0; i < n; i++ {
+		header := &gethTypes.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+			Extra:      []byte{extra},
+		}
+		headers = append(headers, header)
+		parent = header
+	}
+	return headers
+}
+
+func TestHeaderChainReorg(t *testing.T) {
+	genesis := &gethTypes.Header{Number: big.NewInt(0)}
+	main := buildHeaders(genesis, 5, 'a')
+	fork := buildHeaders(main[1], 4, 'b')
+
+	fetcher := &mockFetcher{headers: make(map[common.Hash]*gethTypes.Header)}
+	for _, h := range fork {
+		fetcher.headers[h.Hash()] = h
+	}
+
+	hc := newHeaderChain(10)
+	for _, h := range main {
+		if removed := hc.insert(context.Background(), h, fetcher); len(removed) != 0 {
+			t.Fatalf("Expected no headers to be removed, got %d", len(removed))
+		}
+	}
+
+	// Only the head of the fork is received, its ancestors are fetched.
+	removed := hc.insert(context.Background(), fork[3], fetcher)
+	if len(removed) != 3 {
+		t.Fatalf("Expected 3 headers to be removed, got %d", len(removed))
+	}
+	for i, h := range removed {
+		if h.Hash() != main[i+2].Hash() {
+			t.Errorf("Removed header %d mismatch. wanted=%x, got=%x", i, main[i+2].Hash(), h.Hash())
+		}
+	}
+	if hc.head().Hash() != fork[3].Hash() {
+		t.Errorf("Expected fork head to be the new head")
+	}
+	if hc.byNumber(3).Hash() != fork[0].Hash() {
+		t.Errorf("Expected fork header at number 3")
+	}
+	if hc.byNumber(2).Hash() != main[1].Hash() {
+		t.Errorf("Expected common ancestor at number 2")
+	}
+
+	// Receiving a known header again doesn't change the chain.
+	if removed := hc.insert(context.Background(), fork[3], fetcher); len(removed) != 0 {
+		t.Errorf("Expected no headers to be removed, got %d", len(removed))
+	}
+}
+
+func TestHeaderChainUnknownAncestors(t *testing.T) {
+	genesis := &gethTypes.Header{Number: big.NewInt(0)}
+	main := buildHeaders(genesis, 3, 'a')
+	fork := buildHeaders(main[0], 4, 'b')
+
+	hc := newHeaderChain(10)
+	for _, h := range main {
+		hc.insert(context.Background(), h, nil)
+	}
+
+	// Without a fetcher the chain restarts from the new head, retracting the
+	// cached headers at or above its height.
+	if removed := hc.insert(context.Background(), fork[1], nil); len(removed) != 1 {
+		t.Fatalf("Expected 1 header to be removed, got %d", len(removed))
+	}
+	if hc.byNumber(2) != nil {
+		t.Error("Unverifiable headers should have been dropped")
+	}
+	if hc.head().Hash() != fork[1].Hash() {
+		t.Error("Expected fork head to be the new head")
+	}
+}
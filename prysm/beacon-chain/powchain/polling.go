@@ -0,0 +1,134 @@
+package powchain
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Interval between two polls of an HTTP endpoint.
+var pollingInterval = 2 * time.Second
+
+// Maximum number of blocks covered by a single eth_getLogs query.
+var maxLogQueryRange uint64 = 1000
+
+// historyReader is the part of an ethclient used to retrieve headers and
+// logs on demand, when polling an endpoint or backfilling logs.
+type historyReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*gethTypes.Header, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]gethTypes.Log, error)
+}
+
+// pollingClient provides header and log subscriptions for endpoints that
+// cannot push notifications, such as HTTP. New heads are found by polling for
+// the latest header, and logs by querying the blocks added since the last
+// poll with eth_getLogs. It implements the types.Reader and types.Logger
+// interfaces so the polled data follows the same path as subscriptions.
+type pollingClient struct {
+	backend  historyReader
+	interval time.Duration
+	maxRange uint64
+}
+
+func newPollingClient(backend historyReader) *pollingClient {
+	return &pollingClient{
+		backend:  backend,
+		interval: pollingInterval,
+		maxRange: maxLogQueryRange,
+	}
+}
+
+// SubscribeNewHead sends the latest header to ch whenever the head of the
+// chain changes between two polls.
+func (p *pollingClient) SubscribeNewHead(ctx context.Context, ch chan<- *gethTypes.Header) (ethereum.Subscription, error) {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		var lastHash common.Hash
+		for {
+			header, err := p.backend.HeaderByNumber(ctx, nil)
+			if err != nil {
+				log.Warnf("Could not poll latest PoW chain header: %v", err)
+			} else if header.Hash() != lastHash {
+				lastHash = header.Hash()
+				select {
+				case ch <- header:
+				case <-quit:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-quit:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}), nil
+}
+
+// SubscribeFilterLogs sends the logs matching the query to ch, for every
+// block added to the chain after the subscription is made. Blocks are queried
+// in ranges of at most maxRange blocks.
+func (p *pollingClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- gethTypes.Log) (ethereum.Subscription, error) {
+	head, err := p.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	from := new(big.Int).Add(head.Number, big.NewInt(1)).Uint64()
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-quit:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			head, err := p.backend.HeaderByNumber(ctx, nil)
+			if err != nil {
+				log.Warnf("Could not poll latest PoW chain header: %v", err)
+				continue
+			}
+			for from <= head.Number.Uint64() {
+				to := from + p.maxRange - 1
+				if to > head.Number.Uint64() {
+					to = head.Number.Uint64()
+				}
+				query := q
+				query.FromBlock = new(big.Int).SetUint64(from)
+				query.ToBlock = new(big.Int).SetUint64(to)
+				logs, err := p.backend.FilterLogs(ctx, query)
+				if err != nil {
+					log.Warnf("Could not query logs of PoW blocks %d to %d: %v", from, to, err)
+					break
+				}
+				for _, l := range logs {
+					select {
+					case ch <- l:
+					case <-quit:
+						return nil
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+				from = to + 1
+			}
+		}
+	}), nil
+}
@@ -0,0 +1,101 @@
+package powchain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+type mockBackend struct {
+	lock    sync.Mutex
+	head    *big.Int
+	logs    []gethTypes.Log
+	queries []ethereum.FilterQuery
+	// failFrom makes log queries from this block on fail, if set.
+	failFrom uint64
+}
+
+func (m *mockBackend) setHead(n int64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.head = big.NewInt(n)
+}
+
+func (m *mockBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*gethTypes.Header, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return &gethTypes.Header{Number: new(big.Int).Set(m.head)}, nil
+}
+
+func (m *mockBackend) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]gethTypes.Log, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.queries = append(m.queries, q)
+	if m.failFrom != 0 && q.FromBlock.Uint64() >= m.failFrom {
+		return nil, errors.New("log query failed")
+	}
+	var logs []gethTypes.Log
+	for _, l := range m.logs {
+		if l.BlockNumber >= q.FromBlock.Uint64() && (q.ToBlock == nil || l.BlockNumber <= q.ToBlock.Uint64()) {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+func TestPollNewHeads(t *testing.T) {
+	backend := &mockBackend{head: big.NewInt(10)}
+	poller := &pollingClient{backend: backend, interval: time.Millisecond, maxRange: 10}
+
+	ch := make(chan *gethTypes.Header)
+	sub, err := poller.SubscribeNewHead(context.Background(), ch)
+	if err != nil {
+		t.Fatalf("Could not subscribe to new heads: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if header := <-ch; header.Number.Int64() != 10 {
+		t.Errorf("Expected head 10, got %v", header.Number)
+	}
+	backend.setHead(12)
+	if header := <-ch; header.Number.Int64() != 12 {
+		t.Errorf("Expected head 12, got %v", header.Number)
+	}
+}
+
+func TestPollLogs(t *testing.T) {
+	backend := &mockBackend{
+		head: big.NewInt(10),
+		logs: []gethTypes.Log{{BlockNumber: 9}, {BlockNumber: 11}, {BlockNumber: 20}, {BlockNumber: 35}},
+	}
+	poller := &pollingClient{backend: backend, interval: time.Millisecond, maxRange: 10}
+
+	ch := make(chan gethTypes.Log)
+	sub, err := poller.SubscribeFilterLogs(context.Background(), ethereum.FilterQuery{}, ch)
+	if err != nil {
+		t.Fatalf("Could not subscribe to logs: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Logs of blocks before the subscription are not sent.
+	backend.setHead(35)
+	for _, want := range []uint64{11, 20, 35} {
+		if l := <-ch; l.BlockNumber != want {
+			t.Errorf("Expected log of block %d, got %d", want, l.BlockNumber)
+		}
+	}
+
+	backend.lock.Lock()
+	defer backend.lock.Unlock()
+	for _, q := range backend.queries {
+		if n := q.ToBlock.Uint64() - q.FromBlock.Uint64() + 1; n > poller.maxRange {
+			t.Errorf("Expected queries of at most %d blocks, got %d", poller.maxRange, n)
+		}
+	}
+}
@@ -2,21 +2,43 @@ package powchain
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	gethTypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/prysmaticlabs/prysm/beacon-chain/types"
+	"github.com/prysmaticlabs/prysm/shared/database"
 	"github.com/sirupsen/logrus"
 )
 
 var log = logrus.WithField("prefix", "powchain")
 
+// Bounds of the delay between two attempts to connect to the PoW chain.
+var (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = 2 * time.Minute
+)
+
+// powClient is a connection to a PoW chain RPC endpoint.
+type powClient interface {
+	types.Reader
+	types.Logger
+	historyReader
+	headerFetcher
+	headerBatcher
+	Close()
+}
+
 // Web3Service fetches important information about the canonical
 // Ethereum PoW chain via a web3 endpoint using an ethclient. The Random
 // Beacon Chain requires synchronization with the PoW chain's current
@@ -30,10 +52,42 @@ type Web3Service struct {
 	logChan             chan gethTypes.Log
 	pubKey              string
 	endpoint            string
-	validatorRegistered bool
 	vrcAddress          common.Address
-	blockNumber         *big.Int    // the latest PoW chain blocknumber.
-	blockHash           common.Hash // the latest PoW chain blockhash.
+	chainInfoLock       sync.RWMutex
+	validatorRegistered bool
+	head                ChainHead         // the latest PoW chain head.
+	confirmed           *gethTypes.Header // the latest block with followDistance confirmations.
+	headFeed            event.Feed
+	followDistance      uint64
+	headersLock         sync.RWMutex
+	headers             *headerChain
+	fetcher             headerFetcher
+	pendingDeposits     []gethTypes.Log        // VRC logs not yet followDistance blocks deep.
+	deposits            []gethTypes.Log        // confirmed VRC logs.
+	knownLogs           map[logKey]common.Hash // block hashes of the pending and confirmed VRC logs.
+	dial                func(ctx context.Context, endpoint string) (powClient, error)
+	history             historyReader
+	simulated           *SimulatedBackend
+	blockCache          *headerCache
+	headerBatch         *headerBatch
+	batcherLock         sync.RWMutex
+	batcher             headerBatcher // batches header requests of the current connection.
+	connected           bool          // whether the current connection was set up successfully.
+	db                  ethdb.Database
+	nextBlock           uint64 // first PoW block whose logs were not scanned.
+	lastScanned         uint64 // last PoW block whose deposits are persisted.
+	backfillHead        uint64 // PoW chain head the running backfill scans up to.
+	minBackoff          time.Duration
+	maxBackoff          time.Duration
+	healthLock          sync.RWMutex
+	health              error
+}
+
+// ChainHead is a snapshot of the latest PoW chain head.
+type ChainHead struct {
+	Number *big.Int
+	Hash   common.Hash
+	Time   time.Time
 }
 
 // Web3ServiceConfig defines a config struct for web3 service to use through its life cycle.
@@ -41,16 +95,29 @@ type Web3ServiceConfig struct {
 	Endpoint string
 	Pubkey   string
 	VrcAddr  common.Address
+	// FollowDistance is the number of confirmations a PoW block needs before
+	// it is considered as a main chain reference and its deposits are
+	// processed.
+	FollowDistance uint64
+	// DeploymentBlock is the PoW block the VRC was deployed in, from which
+	// deposits are scanned on the first start.
+	DeploymentBlock uint64
+	// BeaconDB persists the processed deposits and the scanning progress.
+	// It is optional, and not used with the simulated PoW chain.
+	BeaconDB *database.DB
 }
 
 // NewWeb3Service sets up a new instance with an ethclient when
-// given a web3 endpoint as a string.
+// given a web3 endpoint as a string. HTTP endpoints are polled for new
+// heads and logs, as they cannot push notifications. The SimulatedEndpoint
+// makes the service follow an in-process PoW chain with the VRC deployed.
 func NewWeb3Service(ctx context.Context, config *Web3ServiceConfig) (*Web3Service, error) {
-	if !strings.HasPrefix(config.Endpoint, "ws") && !strings.HasPrefix(config.Endpoint, "ipc") {
-		return nil, fmt.Errorf("web3service requires either an IPC or WebSocket endpoint, provided %s", config.Endpoint)
+	simulated := config.Endpoint == SimulatedEndpoint
+	if !strings.HasPrefix(config.Endpoint, "ws") && !strings.HasPrefix(config.Endpoint, "ipc") && !isHTTPEndpoint(config.Endpoint) && !simulated {
+		return nil, fmt.Errorf("web3service requires either an IPC, WebSocket or HTTP endpoint, provided %s", config.Endpoint)
 	}
 	web3ctx, cancel := context.WithCancel(ctx)
-	return &Web3Service{
+	w := &Web3Service{
 		ctx:                 web3ctx,
 		cancel:              cancel,
 		headerChan:          make(chan *gethTypes.Header),
@@ -58,10 +125,40 @@ func NewWeb3Service(ctx context.Context, config *Web3ServiceConfig) (*Web3Servic
 		pubKey:              config.Pubkey,
 		endpoint:            config.Endpoint,
 		validatorRegistered: false,
-		blockNumber:         nil,
-		blockHash:           common.BytesToHash([]byte{}),
 		vrcAddress:          config.VrcAddr,
-	}, nil
+		followDistance:      config.FollowDistance,
+		headers:             newHeaderChain(headerChainSize + int(config.FollowDistance)),
+		blockCache:          newHeaderCache(powBlockCacheSize),
+		knownLogs:           make(map[logKey]common.Hash),
+		dial:                dialClient,
+		nextBlock:           config.DeploymentBlock,
+		minBackoff:          minReconnectBackoff,
+		maxBackoff:          maxReconnectBackoff,
+		health:              errors.New("not connected to PoW chain"),
+	}
+	w.headerBatch = newHeaderBatch(w.batchSource, w.blockCache.add)
+	if simulated {
+		backend, err := NewSimulatedBackend()
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("could not set up simulated PoW chain: %v", err)
+		}
+		w.simulated = backend
+		w.vrcAddress = backend.VRCAddress
+		w.dial = func(ctx context.Context, endpoint string) (powClient, error) {
+			return backend, nil
+		}
+	}
+	// The simulated PoW chain starts anew with every service, so the deposits
+	// and progress persisted for a previous chain do not apply to it.
+	if config.BeaconDB != nil && !simulated {
+		w.db = config.BeaconDB.DB()
+		if err := w.loadDeposits(); err != nil {
+			cancel()
+			return nil, fmt.Errorf("could not load VRC deposits: %v", err)
+		}
+	}
+	return w, nil
 }
 
 // Start a web3 service's main event loop.
@@ -69,74 +166,295 @@ func (w *Web3Service) Start() {
 	log.WithFields(logrus.Fields{
 		"endpoint": w.endpoint,
 	}).Info("Starting service")
-	rpcClient, err := rpc.Dial(w.endpoint)
-	if err != nil {
-		log.Errorf("Cannot connect to PoW chain RPC client: %v", err)
-		return
+	if w.simulated != nil {
+		go w.simulated.mine(w.ctx, simulatedBlockInterval)
 	}
-	client := ethclient.NewClient(rpcClient)
-	go w.fetchChainInfo(w.ctx, client, client)
+	go w.run()
 }
 
 // Stop the web3 service's main event loop and associated goroutines.
 func (w *Web3Service) Stop() error {
 	defer w.cancel()
-	defer close(w.headerChan)
 	log.Info("Stopping service")
 	return nil
 }
 
-func (w *Web3Service) fetchChainInfo(ctx context.Context, reader types.Reader, logger types.Logger) {
-	if _, err := reader.SubscribeNewHead(w.ctx, w.headerChan); err != nil {
+// Simulated returns the in-process PoW chain the service follows, or nil if
+// it follows an external endpoint.
+func (w *Web3Service) Simulated() *SimulatedBackend {
+	return w.simulated
+}
+
+// Status returns nil while the service is following the PoW chain, or the
+// reason it is not.
+func (w *Web3Service) Status() error {
+	w.healthLock.RLock()
+	defer w.healthLock.RUnlock()
+	return w.health
+}
+
+func (w *Web3Service) setStatus(err error) {
+	w.healthLock.Lock()
+	defer w.healthLock.Unlock()
+	w.health = err
+}
+
+// run connects to the PoW chain endpoint and follows the chain until the
+// service is stopped. Whenever the connection or a subscription fails, the
+// service reconnects with an exponential backoff.
+func (w *Web3Service) run() {
+	backoff := w.minBackoff
+	for {
+		client, err := w.dial(w.ctx, w.endpoint)
+		if err != nil {
+			log.Errorf("Cannot connect to PoW chain RPC client: %v", err)
+			w.setStatus(fmt.Errorf("cannot connect to PoW chain: %v", err))
+		} else {
+			var reader types.Reader = client
+			var logger types.Logger = client
+			if isHTTPEndpoint(w.endpoint) {
+				log.Info("Polling HTTP endpoint for PoW chain updates")
+				poller := newPollingClient(client)
+				reader, logger = poller, poller
+			}
+			w.fetcher = client
+			w.history = client
+			w.setBatchSource(client)
+			err = w.fetchChainInfo(w.ctx, reader, logger)
+			w.setBatchSource(nil)
+			client.Close()
+			if w.ctx.Err() != nil {
+				return
+			}
+			w.setStatus(fmt.Errorf("lost connection to PoW chain: %v", err))
+			if w.connected {
+				// Start over with a short backoff after a working connection.
+				backoff = w.minBackoff
+				w.connected = false
+			}
+		}
+
+		log.Infof("Reconnecting to PoW chain in %v", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+			return
+		}
+		backoff *= 2
+		if backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+	}
+}
+
+// fetchChainInfo subscribes to PoW chain heads and VRC logs and processes
+// them until the context is canceled or a subscription fails. Logs emitted
+// while the service was not subscribed are backfilled.
+func (w *Web3Service) fetchChainInfo(ctx context.Context, reader types.Reader, logger types.Logger) error {
+	headSub, err := reader.SubscribeNewHead(w.ctx, w.headerChan)
+	if err != nil {
 		log.Errorf("Unable to subscribe to incoming PoW chain headers: %v", err)
-		return
+		return err
 	}
+	defer headSub.Unsubscribe()
 	query := ethereum.FilterQuery{
 		Addresses: []common.Address{
 			w.vrcAddress,
 		},
 	}
-	_, err := logger.SubscribeFilterLogs(context.Background(), query, w.logChan)
+	logSub, err := logger.SubscribeFilterLogs(ctx, query, w.logChan)
 	if err != nil {
 		log.Errorf("Unable to query logs from VRC: %v", err)
-		return
+		return err
 	}
+	defer logSub.Unsubscribe()
+
+	if err := w.backfillLogs(ctx, query); err != nil {
+		log.Errorf("Unable to backfill logs from VRC: %v", err)
+		return err
+	}
+	w.connected = true
+	w.setStatus(nil)
+
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			return ctx.Err()
+		case err := <-headSub.Err():
+			log.Errorf("PoW chain head subscription failed: %v", err)
+			return err
+		case err := <-logSub.Err():
+			log.Errorf("VRC log subscription failed: %v", err)
+			return err
 		case header := <-w.headerChan:
-			w.blockNumber = header.Number
-			w.blockHash = header.Hash()
-			log.WithFields(logrus.Fields{
-				"blockNumber": w.blockNumber,
-				"blockHash":   w.blockHash.Hex(),
-			}).Debug("Latest web3 chain event")
+			w.processHeader(ctx, header)
 		case VRClog := <-w.logChan:
-			// public key is the second topic from validatorRegistered log and strip off 0x
-			pubKeyLog := VRClog.Topics[1].Hex()[2:]
-			if pubKeyLog == w.pubKey {
-				log.WithFields(logrus.Fields{
-					"publicKey": pubKeyLog,
-				}).Info("Validator registered in VRC with public key")
-				w.validatorRegistered = true
-				w.logChan = nil
-			}
+			w.processLog(VRClog)
 		}
 	}
 }
 
+// backfillLogs processes the VRC logs of the blocks from the first block
+// not scanned yet up to the current head, in ranges of at most
+// maxLogQueryRange blocks. This covers the deposits made before the node
+// started, as well as those missed while it was disconnected.
+func (w *Web3Service) backfillLogs(ctx context.Context, query ethereum.FilterQuery) error {
+	if w.history == nil {
+		return nil
+	}
+	head, err := w.history.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if w.nextBlock <= head.Number.Uint64() {
+		log.Infof("Scanning VRC logs from PoW block %d to %d", w.nextBlock, head.Number)
+	}
+	// The deposits of the scanned ranges are confirmed against the head and
+	// persisted as the scan progresses, so that an interrupted scan resumes
+	// where it stopped.
+	w.backfillHead = head.Number.Uint64()
+	defer func() { w.backfillHead = 0 }()
+	for w.nextBlock <= head.Number.Uint64() {
+		to := w.nextBlock + maxLogQueryRange - 1
+		if to > head.Number.Uint64() {
+			to = head.Number.Uint64()
+		}
+		query.FromBlock = new(big.Int).SetUint64(w.nextBlock)
+		query.ToBlock = new(big.Int).SetUint64(to)
+		logs, err := w.history.FilterLogs(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, l := range logs {
+			w.processLog(l)
+		}
+		w.nextBlock = to + 1
+		w.checkpoint()
+	}
+	w.processHeader(ctx, head)
+	return nil
+}
+
+// processHeader records a new PoW chain head, retracting the deposits of the
+// blocks it reorganized out and confirming the deposits that are now deep
+// enough.
+func (w *Web3Service) processHeader(ctx context.Context, header *gethTypes.Header) {
+	w.blockCache.add(header)
+	w.headersLock.Lock()
+	removed := w.headers.insert(ctx, header, w.fetcher)
+	w.headersLock.Unlock()
+	if len(removed) > 0 {
+		log.WithFields(logrus.Fields{
+			"depth":     len(removed),
+			"newHead":   header.Hash().Hex(),
+			"newNumber": header.Number,
+		}).Warn("PoW chain reorg detected")
+		w.retractBlocks(removed)
+	}
+	head := ChainHead{
+		Number: header.Number,
+		Hash:   header.Hash(),
+	}
+	if header.Time != nil {
+		head.Time = time.Unix(header.Time.Int64(), 0)
+	}
+	var confirmed *gethTypes.Header
+	if number, ok := w.confirmedNumber(); ok {
+		confirmed = w.headers.byNumber(number)
+	}
+	w.chainInfoLock.Lock()
+	w.head = head
+	w.confirmed = confirmed
+	w.chainInfoLock.Unlock()
+
+	// Logs of the head block may still be on their way.
+	if header.Number.Uint64() > w.nextBlock {
+		w.nextBlock = header.Number.Uint64()
+	}
+	log.WithFields(logrus.Fields{
+		"blockNumber": head.Number,
+		"blockHash":   head.Hash.Hex(),
+	}).Debug("Latest web3 chain event")
+	w.confirmDeposits()
+	w.checkpoint()
+	w.headFeed.Send(head)
+}
+
+// ConfirmedBlock returns the PoW block that is followDistance blocks behind
+// the latest head, which is a candidate for main chain references. It
+// returns nil if the block is not known yet.
+func (w *Web3Service) ConfirmedBlock() *gethTypes.Header {
+	w.chainInfoLock.RLock()
+	defer w.chainInfoLock.RUnlock()
+	return w.confirmed
+}
+
+// confirmedNumber returns the number of the latest PoW block with
+// followDistance confirmations, or false if the chain is not that long yet.
+// The head is the latest processed head, or the head of the running
+// backfill if it is higher.
+func (w *Web3Service) confirmedNumber() (uint64, bool) {
+	headNumber, known := w.backfillHead, w.backfillHead > 0
+	if head := w.headers.head(); head != nil {
+		if head.Number.Uint64() > headNumber {
+			headNumber = head.Number.Uint64()
+		}
+		known = true
+	}
+	if !known || headNumber < w.followDistance {
+		return 0, false
+	}
+	return headNumber - w.followDistance, true
+}
+
+// dialClient connects to a PoW chain RPC endpoint.
+func dialClient(ctx context.Context, endpoint string) (powClient, error) {
+	client, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &rpcClient{Client: ethclient.NewClient(client), rpc: client}, nil
+}
+
+// isHTTPEndpoint reports whether the endpoint is served over HTTP and
+// needs to be polled.
+func isHTTPEndpoint(endpoint string) bool {
+	return strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://")
+}
+
+// Head returns a snapshot of the latest PoW chain head. Its number is nil
+// until the first head is received.
+func (w *Web3Service) Head() ChainHead {
+	w.chainInfoLock.RLock()
+	defer w.chainInfoLock.RUnlock()
+	return w.head
+}
+
+// SubscribeHeads sends a snapshot of the PoW chain head to ch whenever the
+// head changes.
+func (w *Web3Service) SubscribeHeads(ch chan<- ChainHead) event.Subscription {
+	return w.headFeed.Subscribe(ch)
+}
+
 // LatestBlockNumber is a getter for blockNumber to make it read-only.
 func (w *Web3Service) LatestBlockNumber() *big.Int {
-	return w.blockNumber
+	return w.Head().Number
 }
 
 // LatestBlockHash is a getter for blockHash to make it read-only.
 func (w *Web3Service) LatestBlockHash() common.Hash {
-	return w.blockHash
+	return w.Head().Hash
 }
 
 // ValidatorRegistered is a getter for validatorRegistered to make it read-only.
 func (w *Web3Service) ValidatorRegistered() bool {
+	w.chainInfoLock.RLock()
+	defer w.chainInfoLock.RUnlock()
 	return w.validatorRegistered
 }
+
+func (w *Web3Service) setValidatorRegistered(registered bool) {
+	w.chainInfoLock.Lock()
+	defer w.chainInfoLock.Unlock()
+	w.validatorRegistered = registered
+}
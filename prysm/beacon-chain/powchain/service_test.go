@@ -6,10 +6,13 @@ import (
 	"math/big"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	gethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/prysmaticlabs/prysm/shared/database"
 	"github.com/prysmaticlabs/prysm/shared/testutil"
 	logTest "github.com/sirupsen/logrus/hooks/test"
 )
@@ -23,7 +26,7 @@ func (b *badReader) SubscribeNewHead(ctx context.Context, ch chan<- *gethTypes.H
 type goodReader struct{}
 
 func (g *goodReader) SubscribeNewHead(ctx context.Context, ch chan<- *gethTypes.Header) (ethereum.Subscription, error) {
-	return nil, nil
+	return emptySubscription(), nil
 }
 
 type badLogger struct{}
@@ -35,25 +38,34 @@ func (b *badLogger) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQu
 type goodLogger struct{}
 
 func (g *goodLogger) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- gethTypes.Log) (ethereum.Subscription, error) {
-	return nil, nil
+	return emptySubscription(), nil
+}
+
+// emptySubscription returns a subscription that delivers nothing until it is
+// unsubscribed.
+func emptySubscription() ethereum.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
 }
 
 func TestNewWeb3Service(t *testing.T) {
 	endpoint := "http://127.0.0.1"
 	ctx := context.Background()
-	if _, err := NewWeb3Service(ctx, &Web3ServiceConfig{endpoint, "", common.Address{}}); err == nil {
-		t.Errorf("passing in an HTTP endpoint should throw an error, received nil")
+	if _, err := NewWeb3Service(ctx, &Web3ServiceConfig{Endpoint: endpoint}); err != nil {
+		t.Errorf("passing in an HTTP endpoint should not throw error, received %v", err)
 	}
 	endpoint = "ftp://127.0.0.1"
-	if _, err := NewWeb3Service(ctx, &Web3ServiceConfig{endpoint, "", common.Address{}}); err == nil {
-		t.Errorf("passing in a non-ws, wss, or ipc endpoint should throw an error, received nil")
+	if _, err := NewWeb3Service(ctx, &Web3ServiceConfig{Endpoint: endpoint}); err == nil {
+		t.Errorf("passing in a non-ws, ipc or http endpoint should throw an error, received nil")
 	}
 	endpoint = "ws://127.0.0.1"
-	if _, err := NewWeb3Service(ctx, &Web3ServiceConfig{endpoint, "", common.Address{}}); err != nil {
+	if _, err := NewWeb3Service(ctx, &Web3ServiceConfig{Endpoint: endpoint}); err != nil {
 		t.Errorf("passing in as ws endpoint should not throw error, received %v", err)
 	}
 	endpoint = "ipc://geth.ipc"
-	if _, err := NewWeb3Service(ctx, &Web3ServiceConfig{endpoint, "", common.Address{}}); err != nil {
+	if _, err := NewWeb3Service(ctx, &Web3ServiceConfig{Endpoint: endpoint}); err != nil {
 		t.Errorf("passing in an ipc endpoint should not throw error, received %v", err)
 	}
 }
@@ -62,7 +74,7 @@ func TestStart(t *testing.T) {
 	hook := logTest.NewGlobal()
 
 	endpoint := "ws://127.0.0.1"
-	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{endpoint, "", common.Address{}})
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: endpoint})
 	if err != nil {
 		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
 	}
@@ -81,7 +93,7 @@ func TestStop(t *testing.T) {
 	hook := logTest.NewGlobal()
 
 	endpoint := "ws://127.0.0.1"
-	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{endpoint, "", common.Address{}})
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: endpoint})
 	if err != nil {
 		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
 	}
@@ -106,7 +118,7 @@ func TestStop(t *testing.T) {
 func TestBadReader(t *testing.T) {
 	hook := logTest.NewGlobal()
 	endpoint := "ws://127.0.0.1"
-	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{endpoint, "", common.Address{}})
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: endpoint})
 	if err != nil {
 		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
 	}
@@ -121,7 +133,7 @@ func TestBadReader(t *testing.T) {
 
 func TestLatestMainchainInfo(t *testing.T) {
 	endpoint := "ws://127.0.0.1"
-	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{endpoint, "", common.Address{}})
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: endpoint})
 	if err != nil {
 		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
 	}
@@ -140,19 +152,19 @@ func TestLatestMainchainInfo(t *testing.T) {
 	cancel()
 	exitRoutine <- true
 
-	if web3Service.blockNumber.Cmp(header.Number) != 0 {
-		t.Errorf("block number not set, expected %v, got %v", header.Number, web3Service.blockNumber)
+	if web3Service.LatestBlockNumber().Cmp(header.Number) != 0 {
+		t.Errorf("block number not set, expected %v, got %v", header.Number, web3Service.LatestBlockNumber())
 	}
 
-	if web3Service.blockHash.Hex() != header.Hash().Hex() {
-		t.Errorf("block hash not set, expected %v, got %v", header.Hash().Hex(), web3Service.blockHash.Hex())
+	if web3Service.LatestBlockHash().Hex() != header.Hash().Hex() {
+		t.Errorf("block hash not set, expected %v, got %v", header.Hash().Hex(), web3Service.LatestBlockHash().Hex())
 	}
 }
 
 func TestBadLogger(t *testing.T) {
 	hook := logTest.NewGlobal()
 	endpoint := "ws://127.0.0.1"
-	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{endpoint, "", common.Address{}})
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: endpoint})
 	if err != nil {
 		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
 	}
@@ -168,7 +180,7 @@ func TestBadLogger(t *testing.T) {
 func TestGoodLogger(t *testing.T) {
 	hook := logTest.NewGlobal()
 	endpoint := "ws://127.0.0.1"
-	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{endpoint, "", common.Address{}})
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: endpoint})
 	if err != nil {
 		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
 	}
@@ -199,8 +211,8 @@ func TestGoodLogger(t *testing.T) {
 		t.Errorf("incorrect pubKey, expected %s, got %s", lastEntry.Data["publicKey"], web3Service.pubKey)
 	}
 
-	if !web3Service.validatorRegistered {
-		t.Errorf("validatorRegistered status expected true, got %v", web3Service.validatorRegistered)
+	if !web3Service.ValidatorRegistered() {
+		t.Errorf("validatorRegistered status expected true, got %v", web3Service.ValidatorRegistered())
 	}
 
 	hook.Reset()
@@ -209,7 +221,7 @@ func TestGoodLogger(t *testing.T) {
 func TestHeaderAfterValidation(t *testing.T) {
 	hook := logTest.NewGlobal()
 	endpoint := "ws://127.0.0.1"
-	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{endpoint, "", common.Address{}})
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: endpoint})
 	if err != nil {
 		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
 	}
@@ -236,15 +248,405 @@ func TestHeaderAfterValidation(t *testing.T) {
 
 	testutil.AssertLogsContain(t, hook, "Validator registered in VRC with public key")
 
-	if !web3Service.validatorRegistered {
-		t.Errorf("validatorRegistered status expected true, got %v", web3Service.validatorRegistered)
+	if !web3Service.ValidatorRegistered() {
+		t.Errorf("validatorRegistered status expected true, got %v", web3Service.ValidatorRegistered())
+	}
+
+	if web3Service.LatestBlockNumber().Cmp(header.Number) != 0 {
+		t.Errorf("block number not set, expected %v, got %v", header.Number, web3Service.LatestBlockNumber())
+	}
+
+	if web3Service.LatestBlockHash().Hex() != header.Hash().Hex() {
+		t.Errorf("block hash not set, expected %v, got %v", header.Hash().Hex(), web3Service.LatestBlockHash().Hex())
+	}
+}
+
+func TestFollowDistance(t *testing.T) {
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: "ws://127.0.0.1", FollowDistance: 2})
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	web3Service.pubKey = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	pubkey := common.HexToHash(web3Service.pubKey)
+
+	genesis := &gethTypes.Header{Number: big.NewInt(0)}
+	headers := buildHeaders(genesis, 4, 'a')
+	web3Service.processHeader(context.Background(), headers[0])
+	web3Service.processLog(gethTypes.Log{
+		Topics:      []common.Hash{{}, pubkey},
+		BlockNumber: 1,
+		BlockHash:   headers[0].Hash(),
+	})
+
+	if web3Service.ConfirmedBlock() != nil {
+		t.Error("No block should be confirmed yet")
+	}
+	web3Service.processHeader(context.Background(), headers[1])
+	if web3Service.ValidatorRegistered() {
+		t.Error("Deposit should not be processed before it has enough confirmations")
+	}
+	web3Service.processHeader(context.Background(), headers[2])
+	if !web3Service.ValidatorRegistered() {
+		t.Error("Deposit should be processed once it has enough confirmations")
+	}
+	if confirmed := web3Service.ConfirmedBlock(); confirmed == nil || confirmed.Hash() != headers[0].Hash() {
+		t.Errorf("Expected block 1 to be confirmed, got %v", confirmed)
+	}
+}
+
+func TestRetractRemovedDeposit(t *testing.T) {
+	hook := logTest.NewGlobal()
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: "ws://127.0.0.1"})
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	web3Service.pubKey = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	pubkey := common.HexToHash(web3Service.pubKey)
+
+	depositLog := gethTypes.Log{Topics: []common.Hash{{}, pubkey}, TxHash: common.Hash{'t'}}
+	web3Service.processLog(depositLog)
+	if !web3Service.ValidatorRegistered() {
+		t.Fatal("validatorRegistered status expected true")
+	}
+
+	depositLog.Removed = true
+	web3Service.processLog(depositLog)
+	if web3Service.ValidatorRegistered() {
+		t.Error("validatorRegistered status expected false after the log was removed")
+	}
+	if len(web3Service.deposits) != 0 {
+		t.Errorf("Expected deposit to be retracted, got %d deposits", len(web3Service.deposits))
+	}
+	testutil.AssertLogsContain(t, hook, "Validator registration in VRC was reverted")
+	hook.Reset()
+}
+
+func TestRetractReorgedDeposit(t *testing.T) {
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: "ws://127.0.0.1", FollowDistance: 1})
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	web3Service.pubKey = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	pubkey := common.HexToHash(web3Service.pubKey)
+
+	genesis := &gethTypes.Header{Number: big.NewInt(0)}
+	main := buildHeaders(genesis, 2, 'a')
+	fork := buildHeaders(genesis, 3, 'b')
+
+	web3Service.processHeader(context.Background(), main[0])
+	web3Service.processLog(gethTypes.Log{
+		Topics:      []common.Hash{{}, pubkey},
+		BlockNumber: 1,
+		BlockHash:   main[0].Hash(),
+	})
+	web3Service.processHeader(context.Background(), main[1])
+	if !web3Service.ValidatorRegistered() {
+		t.Fatal("validatorRegistered status expected true")
+	}
+
+	web3Service.fetcher = &mockFetcher{headers: map[common.Hash]*gethTypes.Header{
+		fork[0].Hash(): fork[0],
+		fork[1].Hash(): fork[1],
+	}}
+	web3Service.processHeader(context.Background(), fork[2])
+	if web3Service.ValidatorRegistered() {
+		t.Error("validatorRegistered status expected false after the deposit block was reorganized out")
+	}
+	if web3Service.LatestBlockHash() != fork[2].Hash() {
+		t.Errorf("Expected fork head to be the latest block")
+	}
+}
+
+// mockClient is a PoW chain connection whose head subscription fails once
+// the fail channel is closed.
+type mockClient struct {
+	mockBackend
+	fail   chan struct{}
+	closed bool
+}
+
+func (m *mockClient) SubscribeNewHead(ctx context.Context, ch chan<- *gethTypes.Header) (ethereum.Subscription, error) {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		select {
+		case <-m.fail:
+			return errors.New("connection lost")
+		case <-quit:
+			return nil
+		}
+	}), nil
+}
+
+func (m *mockClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- gethTypes.Log) (ethereum.Subscription, error) {
+	return emptySubscription(), nil
+}
+
+func (m *mockClient) HeaderByHash(ctx context.Context, hash common.Hash) (*gethTypes.Header, error) {
+	return nil, errors.New("header not found")
+}
+
+func (m *mockClient) HeadersByHash(ctx context.Context, hashes []common.Hash) ([]*gethTypes.Header, error) {
+	return make([]*gethTypes.Header, len(hashes)), nil
+}
+
+func (m *mockClient) Close() {
+	m.closed = true
+}
+
+func TestReconnect(t *testing.T) {
+	hook := logTest.NewGlobal()
+
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: "ws://127.0.0.1"})
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	web3Service.minBackoff = time.Millisecond
+	web3Service.pubKey = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	pubkey := common.HexToHash(web3Service.pubKey)
+	if web3Service.Status() == nil {
+		t.Error("Service should not be healthy before connecting")
+	}
+
+	first := &mockClient{
+		fail:        make(chan struct{}),
+		mockBackend: mockBackend{head: big.NewInt(41)},
+	}
+	second := &mockClient{
+		fail: make(chan struct{}),
+		mockBackend: mockBackend{
+			head: big.NewInt(44),
+			logs: []gethTypes.Log{{Topics: []common.Hash{{}, pubkey}, BlockNumber: 43}},
+		},
+	}
+	dials := make(chan powClient, 3)
+	dials <- nil
+	dials <- first
+	dials <- second
+	web3Service.dial = func(ctx context.Context, endpoint string) (powClient, error) {
+		client := <-dials
+		if client == nil {
+			return nil, errors.New("connection refused")
+		}
+		return client, nil
+	}
+
+	exitRoutine := make(chan bool)
+	go func() {
+		web3Service.run()
+		exitRoutine <- true
+	}()
+
+	web3Service.headerChan <- &gethTypes.Header{Number: big.NewInt(42)}
+	if err := web3Service.Status(); err != nil {
+		t.Errorf("Service should be healthy once connected, got %v", err)
+	}
+
+	// The deposit made while disconnected is backfilled.
+	close(first.fail)
+	deadline := time.Now().Add(time.Second)
+	for len(dials) > 0 || web3Service.Status() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the service to reconnect")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	web3Service.cancel()
+	<-exitRoutine
+
+	if !first.closed {
+		t.Error("Failed connection should have been closed")
+	}
+	second.lock.Lock()
+	queries := second.queries
+	second.lock.Unlock()
+	if len(queries) != 1 || queries[0].FromBlock.Int64() != 42 {
+		t.Errorf("Expected logs to be backfilled from block 42, got queries %v", queries)
+	}
+	if !web3Service.ValidatorRegistered() {
+		t.Error("Backfilled deposit should have been processed")
+	}
+	testutil.AssertLogsContain(t, hook, "Cannot connect to PoW chain RPC client")
+	testutil.AssertLogsContain(t, hook, "PoW chain head subscription failed")
+	hook.Reset()
+}
+
+func TestBackfillDeposits(t *testing.T) {
+	db, err := database.NewDB(&database.DBConfig{InMemory: true})
+	if err != nil {
+		t.Fatalf("Could not set up in-memory db: %v", err)
+	}
+	config := &Web3ServiceConfig{
+		Endpoint:        "ws://127.0.0.1",
+		Pubkey:          "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		FollowDistance:  16,
+		DeploymentBlock: 100,
+		BeaconDB:        db,
+	}
+	web3Service, err := NewWeb3Service(context.Background(), config)
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	pubkey := common.HexToHash(config.Pubkey)
+	backend := &mockBackend{
+		head: big.NewInt(2600),
+		logs: []gethTypes.Log{
+			{Topics: []common.Hash{{}, {}}, BlockNumber: 50},
+			{Topics: []common.Hash{{}, {}}, BlockNumber: 150, Index: 1},
+			{Topics: []common.Hash{{}, pubkey}, BlockNumber: 2500, Index: 2},
+		},
+	}
+	web3Service.history = backend
+
+	if err := web3Service.backfillLogs(context.Background(), ethereum.FilterQuery{}); err != nil {
+		t.Fatalf("Could not backfill logs: %v", err)
+	}
+	if len(backend.queries) != 3 || backend.queries[0].FromBlock.Uint64() != 100 {
+		t.Errorf("Expected 3 queries starting at the deployment block, got %v", backend.queries)
+	}
+	if len(web3Service.deposits) != 2 || !web3Service.ValidatorRegistered() {
+		t.Fatalf("Expected the 2 deposits after deployment to be processed, got %v", web3Service.deposits)
+	}
+
+	// A restarted service resumes from the persisted progress, which stops
+	// at the last confirmed block.
+	restarted, err := NewWeb3Service(context.Background(), config)
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	if len(restarted.deposits) != 2 || !restarted.ValidatorRegistered() {
+		t.Errorf("Expected the 2 persisted deposits to be loaded, got %v", restarted.deposits)
+	}
+	backend.queries = nil
+	backend.setHead(2610)
+	restarted.history = backend
+	if err := restarted.backfillLogs(context.Background(), ethereum.FilterQuery{}); err != nil {
+		t.Fatalf("Could not backfill logs: %v", err)
+	}
+	if len(backend.queries) != 1 || backend.queries[0].FromBlock.Uint64() != 2585 {
+		t.Errorf("Expected logs to be scanned from block 2585, got %v", backend.queries)
+	}
+	if len(restarted.deposits) != 2 {
+		t.Errorf("Deposits should not be processed twice, got %v", restarted.deposits)
+	}
+}
+
+func TestInterruptedBackfill(t *testing.T) {
+	db, err := database.NewDB(&database.DBConfig{InMemory: true})
+	if err != nil {
+		t.Fatalf("Could not set up in-memory db: %v", err)
+	}
+	config := &Web3ServiceConfig{
+		Endpoint:        "ws://127.0.0.1",
+		Pubkey:          "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		FollowDistance:  16,
+		DeploymentBlock: 100,
+		BeaconDB:        db,
+	}
+	web3Service, err := NewWeb3Service(context.Background(), config)
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	pubkey := common.HexToHash(config.Pubkey)
+	// The scan fails before reaching the head.
+	backend := &mockBackend{
+		head: big.NewInt(2600),
+		logs: []gethTypes.Log{
+			{Topics: []common.Hash{{}, {}}, BlockNumber: 150, Index: 1},
+			{Topics: []common.Hash{{}, pubkey}, BlockNumber: 1500, Index: 2},
+			{Topics: []common.Hash{{}, {}}, BlockNumber: 2500, Index: 3},
+		},
+		failFrom: 2100,
+	}
+	web3Service.history = backend
+	if err := web3Service.backfillLogs(context.Background(), ethereum.FilterQuery{}); err == nil {
+		t.Fatal("Expected the backfill to fail")
 	}
 
-	if web3Service.blockNumber.Cmp(header.Number) != 0 {
-		t.Errorf("block number not set, expected %v, got %v", header.Number, web3Service.blockNumber)
+	// The deposits of the ranges scanned before the failure were persisted.
+	restarted, err := NewWeb3Service(context.Background(), config)
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	if len(restarted.deposits) != 2 || !restarted.ValidatorRegistered() {
+		t.Fatalf("Expected the 2 scanned deposits to be loaded, got %v", restarted.deposits)
+	}
+	backend.queries = nil
+	backend.failFrom = 0
+	restarted.history = backend
+	if err := restarted.backfillLogs(context.Background(), ethereum.FilterQuery{}); err != nil {
+		t.Fatalf("Could not backfill logs: %v", err)
+	}
+	if len(backend.queries) == 0 || backend.queries[0].FromBlock.Uint64() != 2100 {
+		t.Errorf("Expected logs to be scanned from block 2100, got %v", backend.queries)
 	}
+	if len(restarted.deposits) != 3 {
+		t.Errorf("Expected the 3 deposits to be processed once, got %v", restarted.deposits)
+	}
+}
+
+func TestSubscribeHeads(t *testing.T) {
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: "ws://127.0.0.1"})
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	heads := make(chan ChainHead, 1)
+	sub := web3Service.SubscribeHeads(heads)
+	defer sub.Unsubscribe()
+
+	header := &gethTypes.Header{Number: big.NewInt(42), Time: big.NewInt(1530000000)}
+	web3Service.processHeader(context.Background(), header)
+
+	head := <-heads
+	if head.Number.Cmp(header.Number) != 0 || head.Hash != header.Hash() {
+		t.Errorf("Expected head %d %#x, got %d %#x", header.Number, header.Hash(), head.Number, head.Hash)
+	}
+	if head.Time.Unix() != 1530000000 {
+		t.Errorf("Expected head timestamp 1530000000, got %d", head.Time.Unix())
+	}
+	if web3Service.Head() != head {
+		t.Errorf("Head snapshot %v does not match the announced head %v", web3Service.Head(), head)
+	}
+}
+
+func TestConcurrentChainInfo(t *testing.T) {
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{Endpoint: "ws://127.0.0.1", FollowDistance: 2})
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	web3Service.pubKey = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	pubkey := common.HexToHash(web3Service.pubKey)
+	headers := buildHeaders(&gethTypes.Header{Number: big.NewInt(0)}, 20, 'a')
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, header := range headers {
+			web3Service.processLog(gethTypes.Log{
+				Topics:      []common.Hash{{}, pubkey},
+				BlockNumber: header.Number.Uint64(),
+				BlockHash:   header.Hash(),
+			})
+			web3Service.processHeader(context.Background(), header)
+		}
+	}()
 
-	if web3Service.blockHash.Hex() != header.Hash().Hex() {
-		t.Errorf("block hash not set, expected %v, got %v", header.Hash().Hex(), web3Service.blockHash.Hex())
+	// Readers run alongside the goroutine processing the chain, so the race
+	// detector catches unsynchronized accesses.
+	for {
+		web3Service.Head()
+		web3Service.LatestBlockNumber()
+		web3Service.LatestBlockHash()
+		web3Service.ValidatorRegistered()
+		web3Service.ConfirmedBlock()
+		select {
+		case <-done:
+			if web3Service.LatestBlockHash() != headers[len(headers)-1].Hash() {
+				t.Errorf("Expected head %#x, got %#x", headers[len(headers)-1].Hash(), web3Service.LatestBlockHash())
+			}
+			if !web3Service.ValidatorRegistered() {
+				t.Error("validatorRegistered status expected true")
+			}
+			return
+		default:
+		}
 	}
 }
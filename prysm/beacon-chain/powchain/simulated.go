@@ -0,0 +1,224 @@
+package powchain
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/prysmaticlabs/prysm/contracts"
+)
+
+// SimulatedEndpoint is the web3 endpoint that makes the service follow an
+// in-process simulated PoW chain instead of connecting to a node.
+const SimulatedEndpoint = "simulated"
+
+// Interval between two blocks of the simulated PoW chain.
+var simulatedBlockInterval = time.Second
+
+var simulatedBalance, _ = new(big.Int).SetString("1000000000000000000000000", 10) // 1M ETH.
+
+// SimulatedBackend is an in-process PoW chain with the VRC deployed, built on
+// go-ethereum's simulated backend. It implements the client interfaces used
+// by the web3 service, so a beacon node can run and receive deposits without
+// any external PoW node.
+//
+// The simulated backend does not expose its headers, so the chain is
+// represented by headers derived from the block numbers, and the block hashes
+// of the logs are rewritten to match them. The simulated chain never reorgs,
+// which keeps both chains consistent.
+type SimulatedBackend struct {
+	*backends.SimulatedBackend
+	// VRCAddress is the address of the deployed VRC.
+	VRCAddress common.Address
+	// VRC is a binding to the deployed VRC.
+	VRC        *contracts.ValidatorRegistration
+	txOpts     *bind.TransactOpts
+	commitLock sync.Mutex // serializes commits, so heads are announced in order.
+	lock       sync.RWMutex
+	headers    []*gethTypes.Header
+	headFeed   event.Feed
+}
+
+// NewSimulatedBackend creates a simulated PoW chain and deploys the VRC with
+// a funded account, which is used to make deposits.
+func NewSimulatedBackend() (*SimulatedBackend, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	txOpts := bind.NewKeyedTransactor(key)
+	s := &SimulatedBackend{
+		SimulatedBackend: backends.NewSimulatedBackend(core.GenesisAlloc{
+			txOpts.From: {Balance: simulatedBalance},
+		}),
+		txOpts:  txOpts,
+		headers: []*gethTypes.Header{{Number: big.NewInt(0), Time: big.NewInt(time.Now().Unix())}},
+	}
+	s.VRCAddress, _, s.VRC, err = contracts.DeployValidatorRegistration(txOpts, s.SimulatedBackend)
+	if err != nil {
+		return nil, err
+	}
+	s.Commit()
+	return s, nil
+}
+
+// Commit mines the pending transactions in a new block and announces the new
+// head.
+func (s *SimulatedBackend) Commit() {
+	s.commitLock.Lock()
+	defer s.commitLock.Unlock()
+
+	s.lock.Lock()
+	parent := s.headers[len(s.headers)-1]
+	header := &gethTypes.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+		Time:       big.NewInt(time.Now().Unix()),
+	}
+	// The header is known before the block is mined, so that logs emitted
+	// by the block can be rewritten.
+	s.headers = append(s.headers, header)
+	s.lock.Unlock()
+
+	s.SimulatedBackend.Commit()
+	s.headFeed.Send(header)
+}
+
+// Deposit registers the validator with the given public key in the VRC and
+// mines the deposit.
+func (s *SimulatedBackend) Deposit(pubKey [32]byte) error {
+	amount, err := s.VRC.VALIDATORDEPOSIT(&bind.CallOpts{})
+	if err != nil {
+		return err
+	}
+	opts := *s.txOpts
+	opts.Value = amount
+	if _, err := s.VRC.Deposit(&opts, pubKey, big.NewInt(0), s.txOpts.From, [32]byte{}); err != nil {
+		return err
+	}
+	s.Commit()
+	return nil
+}
+
+// mine commits a block every interval until the context is canceled.
+func (s *SimulatedBackend) mine(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Commit()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SubscribeNewHead sends every new header of the simulated chain to ch.
+func (s *SimulatedBackend) SubscribeNewHead(ctx context.Context, ch chan<- *gethTypes.Header) (ethereum.Subscription, error) {
+	return s.headFeed.Subscribe(ch), nil
+}
+
+// SubscribeFilterLogs sends the logs matching the query to ch as blocks are
+// mined.
+func (s *SimulatedBackend) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- gethTypes.Log) (ethereum.Subscription, error) {
+	logs := make(chan gethTypes.Log)
+	sub, err := s.SimulatedBackend.SubscribeFilterLogs(ctx, q, logs)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case l := <-logs:
+				select {
+				case ch <- s.rewriteLog(l):
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// FilterLogs returns the logs matching the query.
+func (s *SimulatedBackend) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]gethTypes.Log, error) {
+	logs, err := s.SimulatedBackend.FilterLogs(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	for i := range logs {
+		logs[i] = s.rewriteLog(logs[i])
+	}
+	return logs, nil
+}
+
+// HeaderByNumber returns the header with the given number, or the latest
+// header if number is nil.
+func (s *SimulatedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*gethTypes.Header, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if number == nil {
+		return s.headers[len(s.headers)-1], nil
+	}
+	if !number.IsUint64() || number.Uint64() >= uint64(len(s.headers)) {
+		return nil, ethereum.NotFound
+	}
+	return s.headers[number.Uint64()], nil
+}
+
+// HeaderByHash returns the header with the given hash.
+func (s *SimulatedBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*gethTypes.Header, error) {
+	if header := s.headerByHash(hash); header != nil {
+		return header, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+// HeadersByHash returns the headers with the given hashes, nil for unknown
+// hashes.
+func (s *SimulatedBackend) HeadersByHash(ctx context.Context, hashes []common.Hash) ([]*gethTypes.Header, error) {
+	headers := make([]*gethTypes.Header, len(hashes))
+	for i, hash := range hashes {
+		headers[i] = s.headerByHash(hash)
+	}
+	return headers, nil
+}
+
+// Close is a no-op, the simulated chain lives as long as the backend.
+func (s *SimulatedBackend) Close() {}
+
+func (s *SimulatedBackend) headerByHash(hash common.Hash) *gethTypes.Header {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for i := len(s.headers) - 1; i >= 0; i-- {
+		if s.headers[i].Hash() == hash {
+			return s.headers[i]
+		}
+	}
+	return nil
+}
+
+// rewriteLog makes the log reference the header of its block.
+func (s *SimulatedBackend) rewriteLog(l gethTypes.Log) gethTypes.Log {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if l.BlockNumber < uint64(len(s.headers)) {
+		l.BlockHash = s.headers[l.BlockNumber].Hash()
+	}
+	return l
+}
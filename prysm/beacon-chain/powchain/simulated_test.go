@@ -0,0 +1,103 @@
+package powchain
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prysmaticlabs/prysm/shared/database"
+)
+
+func TestSimulatedDeposits(t *testing.T) {
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{
+		Endpoint:       SimulatedEndpoint,
+		Pubkey:         "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		FollowDistance: 1,
+	})
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	backend := web3Service.Simulated()
+	if backend == nil {
+		t.Fatal("Expected the service to follow a simulated PoW chain")
+	}
+	if web3Service.vrcAddress != backend.VRCAddress {
+		t.Errorf("Expected the service to follow the simulated VRC at %#x, got %#x", backend.VRCAddress, web3Service.vrcAddress)
+	}
+
+	// A deposit made before the service starts is backfilled, and one made
+	// afterwards is received from the log subscription.
+	if err := backend.Deposit([32]byte{'a'}); err != nil {
+		t.Fatalf("Could not deposit: %v", err)
+	}
+	exitRoutine := make(chan bool)
+	go func() {
+		web3Service.run()
+		exitRoutine <- true
+	}()
+	defer func() {
+		web3Service.cancel()
+		<-exitRoutine
+	}()
+	waitFor(t, "the service to connect", func() bool { return web3Service.Status() == nil })
+
+	if err := backend.Deposit(common.HexToHash(web3Service.pubKey)); err != nil {
+		t.Fatalf("Could not deposit: %v", err)
+	}
+	if web3Service.ValidatorRegistered() {
+		t.Error("Deposit should not be processed before its block is confirmed")
+	}
+	backend.Commit()
+	waitFor(t, "the deposit to be processed", web3Service.ValidatorRegistered)
+
+	head, err := backend.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Could not get the simulated chain head: %v", err)
+	}
+	if web3Service.LatestBlockHash() != head.Hash() {
+		t.Errorf("Expected head %#x, got %#x", head.Hash(), web3Service.LatestBlockHash())
+	}
+}
+
+func TestSimulatedIgnoresPersistedDeposits(t *testing.T) {
+	db, err := database.NewDB(&database.DBConfig{InMemory: true})
+	if err != nil {
+		t.Fatalf("Could not set up in-memory db: %v", err)
+	}
+	// Progress persisted while following another PoW chain.
+	if err := db.DB().Put(lastScannedBlockKey, encodeUint64(500)); err != nil {
+		t.Fatalf("Could not persist progress: %v", err)
+	}
+	web3Service, err := NewWeb3Service(context.Background(), &Web3ServiceConfig{
+		Endpoint: SimulatedEndpoint,
+		BeaconDB: db,
+	})
+	if err != nil {
+		t.Fatalf("unable to setup web3 PoW chain service: %v", err)
+	}
+	if web3Service.nextBlock != 0 || web3Service.lastScanned != 0 {
+		t.Errorf("Expected the simulated chain to be scanned from the start, got next block %d", web3Service.nextBlock)
+	}
+
+	web3Service.nextBlock = 600
+	web3Service.checkpoint()
+	enc, err := db.DB().Get(lastScannedBlockKey)
+	if err != nil {
+		t.Fatalf("Could not get progress: %v", err)
+	}
+	if !bytes.Equal(enc, encodeUint64(500)) {
+		t.Errorf("Expected the progress of the simulated chain not to be persisted, got %x", enc)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,258 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/beacon-chain/types"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+	"github.com/prysmaticlabs/prysm/shared/p2p"
+)
+
+// batch is a range of slots requested from a peer during initial sync.
+type batch struct {
+	startSlot uint64
+	count     uint64
+	peer      p2p.Peer  // peer the batch was last requested from.
+	attempts  int       // number of requests sent so far.
+	deadline  time.Time // time after which the batch is requested from another peer.
+}
+
+// batchResult is a downloaded batch waiting to be applied.
+type batchResult struct {
+	res      *pb.BeaconBlockRangeResponse
+	peer     p2p.Peer // peer that served the batch.
+	attempts int      // number of requests sent for the batch.
+}
+
+// peerHead is a peer to sync from and the head slot it reported.
+type peerHead struct {
+	peer p2p.Peer
+	slot uint64
+}
+
+// chainTip is the last block applied to the local chain during initial sync.
+type chainTip struct {
+	slot uint64
+	hash [32]byte
+}
+
+// initialSync catches the local chain up with the head of the network before
+// gossip is processed. It asks peers for their chain head, downloads the
+// missing slots in batches spread across the peers that answered, and
+// applies the blocks in slot order. It returns once the local head is within
+// the sync threshold of the best head reported by peers, when no peer
+// answers, or when the download stalls.
+func (ss *Service) initialSync(done <-chan struct{}) {
+	chainHeadSub := ss.p2p.Feed(pb.ChainHeadResponse{}).Subscribe(ss.chainHeadBuf)
+	blockRangeSub := ss.p2p.Feed(pb.BeaconBlockRangeResponse{}).Subscribe(ss.blockRangeBuf)
+	defer chainHeadSub.Unsubscribe()
+	defer blockRangeSub.Unsubscribe()
+
+	for {
+		peers, networkHead, ok := ss.requestChainHeads(done)
+		if !ok {
+			return
+		}
+		head, err := ss.chainService.CanonicalHead()
+		if err != nil {
+			log.Errorf("Could not retrieve canonical head: %v", err)
+			return
+		}
+		if len(peers) == 0 || head.SlotNumber()+ss.syncThreshold >= networkHead {
+			log.Infof("Chain head at slot %d is synced, switching to regular sync", head.SlotNumber())
+			return
+		}
+		h, err := head.Hash()
+		if err != nil {
+			log.Errorf("Could not hash canonical head: %v", err)
+			return
+		}
+		log.Infof("Starting initial sync from slot %d to slot %d with %d peers", head.SlotNumber(), networkHead, len(peers))
+		if !ss.syncToSlot(done, peers, chainTip{slot: head.SlotNumber(), hash: h}, networkHead) {
+			return
+		}
+	}
+}
+
+// requestChainHeads returns the peers to sync from with their head slots, and
+// the highest slot they reported, or false if the service was stopped. The chain heads are taken
+// from the statuses of the peers if any is known. Otherwise every peer is
+// asked for its chain head and the answers are collected until the head
+// request timeout.
+func (ss *Service) requestChainHeads(done <-chan struct{}) ([]peerHead, uint64, bool) {
+	var peers []peerHead
+	var networkHead uint64
+	for _, status := range ss.p2p.PeerStatuses() {
+		peers = append(peers, peerHead{peer: status.Peer, slot: status.Status.HeadSlot})
+		if status.Status.HeadSlot > networkHead {
+			networkHead = status.Status.HeadSlot
+		}
+	}
+	if len(peers) > 0 {
+		return peers, networkHead, true
+	}
+
+	ss.p2p.Broadcast(&pb.ChainHeadRequest{})
+	timeout := time.After(ss.headRequestTimeout)
+	for {
+		select {
+		case <-done:
+			return nil, 0, false
+		case <-timeout:
+			return peers, networkHead, true
+		case msg := <-ss.chainHeadBuf:
+			data, ok := msg.Data.(*pb.ChainHeadResponse)
+			// TODO: Handle this at p2p layer.
+			if !ok {
+				log.Error("Received malformed chain head p2p message")
+				ss.p2p.ReportPeer(msg.Peer, malformedMessagePenalty)
+				continue
+			}
+			peers = append(peers, peerHead{peer: msg.Peer, slot: data.Slot})
+			if data.Slot > networkHead {
+				networkHead = data.Slot
+			}
+		}
+	}
+}
+
+// syncToSlot downloads and applies every slot after the tip up to target.
+// Batches are only requested from peers whose head is at or past the end of
+// the batch, and target must not exceed the highest head of the peers. It
+// returns false if the service was stopped or a batch could not be
+// downloaded after the maximum number of attempts.
+func (ss *Service) syncToSlot(done <-chan struct{}, peers []peerHead, tip chainTip, target uint64) bool {
+	batches := make(map[uint64]*batch)
+	results := make(map[uint64]*batchResult)
+	nextSlot := tip.slot + 1
+	applyFrom := tip.slot + 1
+
+	requests := 0
+	send := func(b *batch) {
+		end := b.startSlot + b.count - 1
+		peer := peers[requests%len(peers)]
+		for i := 1; peer.slot < end && i < len(peers); i++ {
+			peer = peers[(requests+i)%len(peers)]
+		}
+		requests++
+		b.peer = peer.peer
+		b.attempts++
+		b.deadline = time.Now().Add(ss.requestTimeout)
+		ss.p2p.Send(&pb.BeaconBlockRangeRequest{StartSlot: b.startSlot, Count: b.count}, peer.peer)
+	}
+
+	retryTicker := time.NewTicker(ss.requestTimeout)
+	defer retryTicker.Stop()
+	for applyFrom <= target {
+		for len(batches)+len(results) < ss.maxPendingBatches && nextSlot <= target {
+			count := ss.batchSize
+			if remaining := target - nextSlot + 1; remaining < count {
+				count = remaining
+			}
+			b := &batch{startSlot: nextSlot, count: count}
+			batches[b.startSlot] = b
+			send(b)
+			nextSlot += count
+		}
+
+		select {
+		case <-done:
+			return false
+		case now := <-retryTicker.C:
+			for _, b := range batches {
+				if now.Before(b.deadline) {
+					continue
+				}
+				if b.attempts >= ss.maxRequestAttempts {
+					log.Warnf("Initial sync stalled requesting slots %d to %d, switching to regular sync", b.startSlot, b.startSlot+b.count-1)
+					return false
+				}
+				send(b)
+			}
+		case msg := <-ss.blockRangeBuf:
+			data, ok := msg.Data.(*pb.BeaconBlockRangeResponse)
+			// TODO: Handle this at p2p layer.
+			if !ok {
+				log.Error("Received malformed beacon block range p2p message")
+				ss.p2p.ReportPeer(msg.Peer, malformedMessagePenalty)
+				continue
+			}
+			b, ok := batches[data.StartSlot]
+			if !ok || msg.Peer.ID != b.peer.ID || data.Count == 0 || data.Count > b.count {
+				log.Debugf("Dropping unsolicited block range starting at slot %d from peer %s", data.StartSlot, msg.Peer.ID)
+				continue
+			}
+			delete(batches, b.startSlot)
+			if data.Count < b.count {
+				// The peer served fewer slots than requested, ask for the rest.
+				rest := &batch{startSlot: b.startSlot + data.Count, count: b.count - data.Count}
+				batches[rest.startSlot] = rest
+				send(rest)
+			}
+			results[data.StartSlot] = &batchResult{res: data, peer: msg.Peer, attempts: b.attempts}
+
+			for {
+				r, ok := results[applyFrom]
+				if !ok {
+					break
+				}
+				delete(results, applyFrom)
+				if err := ss.applyBlockRange(r.res, &tip); err != nil {
+					// Earlier ranges may have been served without blocks, so
+					// every slot after the tip is requested again.
+					retryFrom := tip.slot + 1
+					log.Warnf("Discarding invalid blocks from slot %d: %v", retryFrom, err)
+					ss.p2p.ReportPeer(r.peer, invalidBlockPenalty)
+					if r.attempts >= ss.maxRequestAttempts {
+						log.Warnf("Initial sync stalled at slot %d, switching to regular sync", retryFrom)
+						return false
+					}
+					// Request the slots that were not applied again, from another peer.
+					retry := &batch{
+						startSlot: retryFrom,
+						count:     r.res.StartSlot + r.res.Count - retryFrom,
+						attempts:  r.attempts,
+					}
+					batches[retry.startSlot] = retry
+					send(retry)
+					applyFrom = retryFrom
+					break
+				}
+				applyFrom = r.res.StartSlot + r.res.Count
+				log.Infof("Synced up to slot %d of %d", applyFrom-1, target)
+			}
+		}
+	}
+	return true
+}
+
+// applyBlockRange validates the blocks of a range response and forwards
+// them to the local chain in slot order, advancing the tip. Each block must
+// lie within the range and extend the tip.
+func (ss *Service) applyBlockRange(res *pb.BeaconBlockRangeResponse, tip *chainTip) error {
+	for _, data := range res.Blocks {
+		block, err := types.NewBlockWithData(data)
+		if err != nil {
+			return fmt.Errorf("could not instantiate new block from proto: %v", err)
+		}
+		slot := block.SlotNumber()
+		if slot <= tip.slot || slot < res.StartSlot || slot >= res.StartSlot+res.Count {
+			return fmt.Errorf("block at slot %d is out of order", slot)
+		}
+		if !bytes.Equal(data.ParentHash, tip.hash[:]) {
+			return fmt.Errorf("block at slot %d does not extend the local chain", slot)
+		}
+		h, err := block.Hash()
+		if err != nil {
+			return fmt.Errorf("could not hash block: %v", err)
+		}
+		if err := ss.chainService.ProcessBlock(block); err != nil {
+			return fmt.Errorf("could not process block at slot %d: %v", slot, err)
+		}
+		tip.slot = slot
+		tip.hash = h
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prysmaticlabs/prysm/beacon-chain/types"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+	"github.com/prysmaticlabs/prysm/shared/p2p"
+	"github.com/prysmaticlabs/prysm/shared/testutil"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+)
+
+// buildChain returns a chain of blocks extending the genesis block at the
+// given slots.
+func buildChain(t *testing.T, slots []uint64) []*types.Block {
+	genesis, err := types.NewGenesisBlock()
+	if err != nil {
+		t.Fatalf("Could not create genesis block: %v", err)
+	}
+	parent, err := genesis.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chain []*types.Block
+	for _, slot := range slots {
+		parentHash := parent
+		block, err := types.NewBlockWithData(&pb.BeaconBlockResponse{
+			SlotNumber: slot,
+			ParentHash: parentHash[:],
+		})
+		if err != nil {
+			t.Fatalf("Could not create block: %v", err)
+		}
+		parent, err = block.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		chain = append(chain, block)
+	}
+	return chain
+}
+
+// connect wires a local sync service to a remote one serving from its chain,
+// delivering the remote's answers to the local service.
+func connect(local *Service, localP2P *mockP2P, remote *Service, remoteP2P *mockP2P) {
+	localP2P.onSend = func(msg interface{}) {
+		switch m := msg.(type) {
+		case *pb.ChainHeadRequest:
+			go remote.ReceiveChainHeadRequest(p2p.Message{Data: m})
+		case *pb.BeaconBlockRangeRequest:
+			go remote.ReceiveBlockRangeRequest(m, p2p.Message{Data: m})
+		}
+	}
+	remoteP2P.onSend = func(msg interface{}) {
+		switch m := msg.(type) {
+		case *pb.ChainHeadResponse:
+			local.chainHeadBuf <- p2p.Message{Peer: p2p.Peer{}, Data: m}
+		case *pb.BeaconBlockRangeResponse:
+			local.blockRangeBuf <- p2p.Message{Peer: p2p.Peer{}, Data: m}
+		}
+	}
+}
+
+func TestInitialSync(t *testing.T) {
+	hook := logTest.NewGlobal()
+
+	// Slots 3 and 6 are empty.
+	chain := buildChain(t, []uint64{1, 2, 4, 5, 7, 8, 9, 10, 11})
+	remoteChain := &mockChainService{}
+	for _, block := range chain {
+		if err := remoteChain.ProcessBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	localP2P, remoteP2P := &mockP2P{}, &mockP2P{}
+	localChain := &mockChainService{}
+	local := NewSyncService(context.Background(), testConfig(), localP2P, localChain)
+	remote := NewSyncService(context.Background(), testConfig(), remoteP2P, remoteChain)
+	connect(local, localP2P, remote, remoteP2P)
+
+	local.initialSync(local.ctx.Done())
+
+	if len(localChain.processedHashes) != len(chain) {
+		t.Fatalf("Expected %d blocks to be synced, got %d", len(chain), len(localChain.processedHashes))
+	}
+	for i, block := range chain {
+		h, err := block.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if localChain.processedHashes[i] != h {
+			t.Errorf("Block %d applied out of order. wanted=%x, got=%x", i, h, localChain.processedHashes[i])
+		}
+	}
+	testutil.AssertLogsContain(t, hook, "Starting initial sync from slot 0 to slot 11")
+	testutil.AssertLogsContain(t, hook, "Synced up to slot 11 of 11")
+	testutil.AssertLogsContain(t, hook, "is synced, switching to regular sync")
+	hook.Reset()
+}
+
+func TestInitialSyncRejectsInvalidChain(t *testing.T) {
+	hook := logTest.NewGlobal()
+
+	chain := buildChain(t, []uint64{1, 2, 3, 4, 5})
+	// Block 3 does not extend block 2.
+	chain[2], _ = types.NewBlockWithData(&pb.BeaconBlockResponse{SlotNumber: 3, ParentHash: []byte{'A'}})
+	remoteChain := &mockChainService{}
+	for _, block := range chain {
+		if err := remoteChain.ProcessBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	localP2P, remoteP2P := &mockP2P{}, &mockP2P{}
+	localChain := &mockChainService{}
+	local := NewSyncService(context.Background(), testConfig(), localP2P, localChain)
+	remote := NewSyncService(context.Background(), testConfig(), remoteP2P, remoteChain)
+	connect(local, localP2P, remote, remoteP2P)
+
+	local.initialSync(local.ctx.Done())
+
+	if len(localChain.processedHashes) != 2 {
+		t.Errorf("Expected only the 2 valid blocks to be synced, got %d", len(localChain.processedHashes))
+	}
+	if score := localP2P.score(p2p.Peer{}); score != local.maxRequestAttempts*invalidBlockPenalty {
+		t.Errorf("Expected the serving peer to be penalized by %d, got %d", local.maxRequestAttempts*invalidBlockPenalty, score)
+	}
+	testutil.AssertLogsContain(t, hook, "does not extend the local chain")
+	testutil.AssertLogsContain(t, hook, "Initial sync stalled at slot 3")
+	hook.Reset()
+}
+
+func TestInitialSyncFromPeerStatuses(t *testing.T) {
+	hook := logTest.NewGlobal()
+
+	chain := buildChain(t, []uint64{1, 2, 3, 4, 5})
+	remoteChain := &mockChainService{}
+	for _, block := range chain {
+		if err := remoteChain.ProcessBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	localP2P, remoteP2P := &mockP2P{}, &mockP2P{}
+	localChain := &mockChainService{}
+	local := NewSyncService(context.Background(), testConfig(), localP2P, localChain)
+	remote := NewSyncService(context.Background(), testConfig(), remoteP2P, remoteChain)
+	connect(local, localP2P, remote, remoteP2P)
+	localP2P.statuses = []p2p.PeerStatus{{Peer: p2p.Peer{}, Status: &pb.Status{HeadSlot: 5}}}
+	onSend := localP2P.onSend
+	localP2P.onSend = func(msg interface{}) {
+		if _, ok := msg.(*pb.ChainHeadRequest); ok {
+			t.Error("Expected the chain heads to be taken from the peer statuses")
+		}
+		onSend(msg)
+	}
+
+	local.initialSync(local.ctx.Done())
+
+	if len(localChain.processedHashes) != len(chain) {
+		t.Errorf("Expected %d blocks to be synced, got %d", len(chain), len(localChain.processedHashes))
+	}
+	testutil.AssertLogsContain(t, hook, "Starting initial sync from slot 0 to slot 5 with 1 peers")
+	hook.Reset()
+}
+
+func TestInitialSyncWithoutPeers(t *testing.T) {
+	hook := logTest.NewGlobal()
+
+	localChain := &mockChainService{}
+	local := NewSyncService(context.Background(), testConfig(), &mockP2P{}, localChain)
+
+	local.initialSync(local.ctx.Done())
+
+	if len(localChain.processedHashes) != 0 {
+		t.Errorf("Expected no blocks to be synced, got %d", len(localChain.processedHashes))
+	}
+	testutil.AssertLogsContain(t, hook, "Chain head at slot 0 is synced, switching to regular sync")
+	hook.Reset()
+}
+
+func TestServeBlockRange(t *testing.T) {
+	chain := buildChain(t, []uint64{1, 3})
+	ms := &mockChainService{}
+	for _, block := range chain {
+		if err := ms.ProcessBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mp := &mockP2P{}
+	ss := NewSyncService(context.Background(), testConfig(), mp, ms)
+
+	// Requests larger than the batch size are truncated.
+	if err := ss.ReceiveBlockRangeRequest(&pb.BeaconBlockRangeRequest{StartSlot: 0, Count: 100}, p2p.Message{}); err != nil {
+		t.Fatalf("Could not serve block range request: %v", err)
+	}
+	// Requests past the canonical head at slot 3 are truncated at the head.
+	if err := ss.ReceiveBlockRangeRequest(&pb.BeaconBlockRangeRequest{StartSlot: 1, Count: 4}, p2p.Message{}); err != nil {
+		t.Fatalf("Could not serve block range request: %v", err)
+	}
+	if err := ss.ReceiveBlockRangeRequest(&pb.BeaconBlockRangeRequest{StartSlot: 4, Count: 4}, p2p.Message{}); err != nil {
+		t.Fatalf("Could not serve block range request: %v", err)
+	}
+	sent := mp.sentMessages()
+	if len(sent) != 3 {
+		t.Fatalf("Expected 3 responses to be sent, got %d", len(sent))
+	}
+	for i, want := range []struct{ start, count uint64 }{{0, ss.batchSize}, {1, 3}, {4, 0}} {
+		res, ok := sent[i].(*pb.BeaconBlockRangeResponse)
+		if !ok {
+			t.Fatalf("Expected a block range response to be sent, got %T", sent[i])
+		}
+		if res.StartSlot != want.start || res.Count != want.count {
+			t.Errorf("Expected range [%d, %d), got start %d count %d", want.start, want.start+want.count, res.StartSlot, res.Count)
+		}
+	}
+	res := sent[1].(*pb.BeaconBlockRangeResponse)
+	if len(res.Blocks) != 2 || res.Blocks[0].SlotNumber != 1 || res.Blocks[1].SlotNumber != 3 {
+		t.Errorf("Expected blocks at slots 1 and 3, got %v", res.Blocks)
+	}
+	if blocks := sent[2].(*pb.BeaconBlockRangeResponse).Blocks; len(blocks) != 0 {
+		t.Errorf("Expected no blocks past the canonical head, got %v", blocks)
+	}
+}
+
+func TestSyncToSlotRequestsPeersPastBatch(t *testing.T) {
+	mp := &mockP2P{}
+	ss := NewSyncService(context.Background(), testConfig(), mp, &mockChainService{})
+	behind, ahead := p2p.Peer{ID: "behind"}, p2p.Peer{ID: "ahead"}
+	peers := []peerHead{{peer: ahead, slot: 8}, {peer: behind, slot: 3}}
+
+	// The service is stopped once the first batches are requested.
+	done := make(chan struct{})
+	close(done)
+	ss.syncToSlot(done, peers, chainTip{}, 8)
+
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	if len(mp.sent) != 2 {
+		t.Fatalf("Expected 2 batches to be requested, got %d", len(mp.sent))
+	}
+	// The peer behind does not have any of the batches.
+	for i, to := range mp.sentTo {
+		req := mp.sent[i].(*pb.BeaconBlockRangeRequest)
+		if to != ahead {
+			t.Errorf("Expected slots %d to %d to be requested from %s, got %s", req.StartSlot, req.StartSlot+req.Count-1, ahead.ID, to.ID)
+		}
+	}
+}
+
+func TestSyncToSlotDropsRangesFromOtherPeers(t *testing.T) {
+	chain := buildChain(t, []uint64{1, 2, 3, 4})
+	remoteChain := &mockChainService{}
+	for _, block := range chain {
+		if err := remoteChain.ProcessBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	localP2P, remoteP2P := &mockP2P{}, &mockP2P{}
+	localChain := &mockChainService{}
+	local := NewSyncService(context.Background(), testConfig(), localP2P, localChain)
+	remote := NewSyncService(context.Background(), testConfig(), remoteP2P, remoteChain)
+	connect(local, localP2P, remote, remoteP2P)
+	onSend := localP2P.onSend
+	localP2P.onSend = func(msg interface{}) {
+		req, ok := msg.(*pb.BeaconBlockRangeRequest)
+		if !ok {
+			onSend(msg)
+			return
+		}
+		// Another peer answers first, claiming the range has no blocks.
+		go func() {
+			spoofed := &pb.BeaconBlockRangeResponse{StartSlot: req.StartSlot, Count: req.Count}
+			local.blockRangeBuf <- p2p.Message{Peer: p2p.Peer{ID: "other"}, Data: spoofed}
+			onSend(msg)
+		}()
+	}
+
+	// The chain extends the genesis block.
+	var tip chainTip
+	copy(tip.hash[:], chain[0].Proto().ParentHash)
+	if !local.syncToSlot(local.ctx.Done(), []peerHead{{slot: 4}}, tip, 4) {
+		t.Fatal("Expected the chain to be synced")
+	}
+	if len(localChain.processedHashes) != len(chain) {
+		t.Errorf("Expected %d blocks to be synced, got %d", len(chain), len(localChain.processedHashes))
+	}
+}
+
+func TestSyncToSlotRetriesSkippedSlots(t *testing.T) {
+	chain := buildChain(t, []uint64{1, 2, 3, 4, 5, 6, 7, 8})
+	remoteChain := &mockChainService{}
+	for _, block := range chain {
+		if err := remoteChain.ProcessBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	localP2P, remoteP2P := &mockP2P{}, &mockP2P{}
+	localChain := &mockChainService{}
+	local := NewSyncService(context.Background(), testConfig(), localP2P, localChain)
+	remote := NewSyncService(context.Background(), testConfig(), remoteP2P, remoteChain)
+	connect(local, localP2P, remote, remoteP2P)
+	onSend := localP2P.onSend
+	withheld := false
+	localP2P.onSend = func(msg interface{}) {
+		req, ok := msg.(*pb.BeaconBlockRangeRequest)
+		if !ok || req.StartSlot != 1 || withheld {
+			onSend(msg)
+			return
+		}
+		// The first batch is answered without its blocks, so the blocks of the
+		// second batch do not extend the local chain.
+		withheld = true
+		go func() {
+			empty := &pb.BeaconBlockRangeResponse{StartSlot: req.StartSlot, Count: req.Count}
+			local.blockRangeBuf <- p2p.Message{Peer: p2p.Peer{}, Data: empty}
+		}()
+	}
+
+	// The chain extends the genesis block.
+	var tip chainTip
+	copy(tip.hash[:], chain[0].Proto().ParentHash)
+	if !local.syncToSlot(local.ctx.Done(), []peerHead{{slot: 8}}, tip, 8) {
+		t.Fatal("Expected the chain to be synced")
+	}
+	if len(localChain.processedHashes) != len(chain) {
+		t.Errorf("Expected %d blocks to be synced, got %d", len(chain), len(localChain.processedHashes))
+	}
+}
+
+// waitForHandlers broadcasts chain head requests from a probe node until a
+// running sync service answers, so that its handlers are subscribed.
+func waitForHandlers(t *testing.T, probe *p2p.SimulatedNode) {
+	ch := make(chan p2p.Message, 1)
+	sub := probe.Subscribe(pb.ChainHeadResponse{}, ch)
+	defer sub.Unsubscribe()
+	timeout := time.After(5 * time.Second)
+	for {
+		probe.Broadcast(&pb.ChainHeadRequest{})
+		select {
+		case <-ch:
+			return
+		case <-timeout:
+			t.Fatal("Sync service did not answer chain head requests")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestInitialSyncSimulatedNetwork(t *testing.T) {
+	hook := logTest.NewGlobal()
+
+	network := p2p.NewSimulatedNetwork(p2p.SimulatedNetworkConfig{Latency: time.Millisecond})
+	defer network.Close()
+	remoteNode, localNode, isolatedNode, probe := network.NewNode(), network.NewNode(), network.NewNode(), network.NewNode()
+
+	chain := buildChain(t, []uint64{1, 2, 4, 5, 6, 8})
+	remoteChain := &mockChainService{}
+	for _, block := range chain {
+		if err := remoteChain.ProcessBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	remote := NewSyncService(context.Background(), testConfig(), remoteNode, remoteChain)
+	go remote.run(remote.ctx.Done())
+	defer remote.Stop()
+	waitForHandlers(t, probe)
+
+	network.Partition([]*p2p.SimulatedNode{remoteNode, localNode}, []*p2p.SimulatedNode{isolatedNode})
+
+	localChain := &mockChainService{}
+	local := NewSyncService(context.Background(), testConfig(), localNode, localChain)
+	local.initialSync(local.ctx.Done())
+
+	if len(localChain.processedHashes) != len(chain) {
+		t.Errorf("Expected %d blocks to be synced, got %d", len(chain), len(localChain.processedHashes))
+	}
+	testutil.AssertLogsContain(t, hook, "Starting initial sync from slot 0 to slot 8 with 1 peers")
+
+	// The isolated node does not reach the remote node.
+	isolatedChain := &mockChainService{}
+	isolated := NewSyncService(context.Background(), testConfig(), isolatedNode, isolatedChain)
+	isolated.initialSync(isolated.ctx.Done())
+
+	if len(isolatedChain.processedHashes) != 0 {
+		t.Errorf("Expected no blocks to be synced by the isolated node, got %d", len(isolatedChain.processedHashes))
+	}
+	hook.Reset()
+}
@@ -0,0 +1,35 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/prysmaticlabs/prysm/shared/p2p"
+)
+
+// rateLimiter limits the number of messages accepted from each peer within
+// fixed intervals. It is not safe for concurrent use.
+type rateLimiter struct {
+	limit       int
+	interval    time.Duration
+	windowStart time.Time
+	counts      map[p2p.Peer]int
+}
+
+func newRateLimiter(limit int, interval time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:    limit,
+		interval: interval,
+		counts:   make(map[p2p.Peer]int),
+	}
+}
+
+// allow counts a message from the peer and reports whether it is within the
+// limit of the current interval.
+func (rl *rateLimiter) allow(peer p2p.Peer, now time.Time) bool {
+	if now.Sub(rl.windowStart) >= rl.interval {
+		rl.windowStart = now
+		rl.counts = make(map[p2p.Peer]int)
+	}
+	rl.counts[peer]++
+	return rl.counts[peer] <= rl.limit
+}
@@ -0,0 +1,51 @@
+package sync
+
+import "sync"
+
+// seenCache remembers the hashes of the most recent blocks handled by the
+// service, so that each block is processed and relayed at most once. Block
+// hash announcements carry the hash of the block they announce, so one cache
+// serves both the hash and the block handlers. Once the cache is full, the
+// oldest hash is evicted.
+type seenCache struct {
+	lock  sync.Mutex
+	items map[[32]byte]struct{}
+	order [][32]byte // ring buffer of cached hashes, oldest at next.
+	next  int
+}
+
+func newSeenCache(size int) *seenCache {
+	return &seenCache{
+		items: make(map[[32]byte]struct{}, size),
+		order: make([][32]byte, 0, size),
+	}
+}
+
+// add marks the hash as seen and reports whether it had been seen before.
+func (c *seenCache) add(h [32]byte) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.items[h]; ok {
+		return true
+	}
+	if cap(c.order) == 0 {
+		return false
+	}
+	if len(c.order) < cap(c.order) {
+		c.order = append(c.order, h)
+	} else {
+		delete(c.items, c.order[c.next])
+		c.order[c.next] = h
+		c.next = (c.next + 1) % len(c.order)
+	}
+	c.items[h] = struct{}{}
+	return false
+}
+
+// contains reports whether the hash has been seen.
+func (c *seenCache) contains(h [32]byte) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	_, ok := c.items[h]
+	return ok
+}
@@ -0,0 +1,23 @@
+package sync
+
+import "testing"
+
+func TestSeenCacheEviction(t *testing.T) {
+	c := newSeenCache(2)
+	a, b, d := [32]byte{'a'}, [32]byte{'b'}, [32]byte{'d'}
+
+	if c.add(a) {
+		t.Error("Hash should not have been seen before")
+	}
+	if !c.add(a) {
+		t.Error("Hash should have been seen before")
+	}
+	c.add(b)
+	c.add(d)
+	if c.contains(a) {
+		t.Error("Oldest hash should have been evicted")
+	}
+	if !c.contains(b) || !c.contains(d) {
+		t.Error("Most recent hashes should be cached")
+	}
+}
@@ -3,7 +3,10 @@ package sync
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/prysmaticlabs/prysm/beacon-chain/types"
 	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
 	"github.com/prysmaticlabs/prysm/shared/p2p"
@@ -12,6 +15,21 @@ import (
 
 var log = logrus.WithField("prefix", "sync")
 
+var (
+	announceMeter          = metrics.NewRegisteredMeter("sync/announces", nil)
+	duplicateAnnounceMeter = metrics.NewRegisteredMeter("sync/announces/duplicates", nil)
+	blockMeter             = metrics.NewRegisteredMeter("sync/blocks", nil)
+	duplicateBlockMeter    = metrics.NewRegisteredMeter("sync/blocks/duplicates", nil)
+)
+
+// Score adjustments reported to the p2p layer for the behaviour of peers.
+const (
+	validBlockReward        = 1
+	malformedMessagePenalty = -20
+	invalidBlockPenalty     = -50
+	rateLimitPenalty        = -5
+)
+
 // Service is the gateway and the bridge between the p2p network and the local beacon chain.
 // In broad terms, a new block is synced in 4 steps:
 //     1. Receive a block hash from a peer
@@ -31,17 +49,81 @@ type Service struct {
 	chainService         types.ChainService
 	announceBlockHashBuf chan p2p.Message
 	blockBuf             chan p2p.Message
+	blockRequestBuf      chan p2p.Message
+	chainHeadRequestBuf  chan p2p.Message
+	chainHeadBuf         chan p2p.Message
+	blockRangeRequestBuf chan p2p.Message
+	blockRangeBuf        chan p2p.Message
+	requestTimeout       time.Duration
+	maxRequestAttempts   int
+	headRequestTimeout   time.Duration
+	batchSize            uint64
+	maxPendingBatches    int
+	syncThreshold        uint64
+	hashLimiter          *rateLimiter
+	blockLimiter         *rateLimiter
+	seen                 *seenCache
+	pendingLock          sync.Mutex
+	pending              map[[32]byte]*pendingRequest
+}
+
+// pendingRequest tracks an in-flight request for the full data of an
+// announced block.
+type pendingRequest struct {
+	peers    []p2p.Peer // peers that announced the block, tried in order.
+	attempts int        // number of requests sent so far.
+	deadline time.Time  // time after which the request is retried.
 }
 
-// Config allows the channel's buffer sizes to be changed.
+// Config allows the channel's buffer sizes, the block request policy, the
+// initial sync parameters and the per peer rate limits to be changed.
 type Config struct {
-	HashBufferSize  int
-	BlockBufferSize int
+	HashBufferSize         int
+	BlockBufferSize        int
+	BlockRequestBufferSize int
+	RequestTimeout         time.Duration
+	MaxRequestAttempts     int
+	// HeadRequestTimeout is how long initial sync waits for peers to report
+	// their chain head.
+	HeadRequestTimeout time.Duration
+	// BatchSize is the number of slots requested from a peer at once.
+	BatchSize uint64
+	// MaxPendingBatches is the number of batches downloaded in parallel.
+	MaxPendingBatches int
+	// SyncThreshold is the distance in slots from the network head at which
+	// initial sync hands over to regular sync.
+	SyncThreshold uint64
+	// RateLimitInterval is the interval over which messages from a peer are
+	// counted against the rate limits.
+	RateLimitInterval time.Duration
+	// MaxHashesPerInterval is the number of block hash announcements
+	// accepted from a peer per interval.
+	MaxHashesPerInterval int
+	// MaxBlocksPerInterval is the number of blocks accepted from a peer per
+	// interval.
+	MaxBlocksPerInterval int
+	// SeenCacheSize is the number of recently handled block hashes
+	// remembered to suppress duplicates.
+	SeenCacheSize int
 }
 
 // DefaultConfig provides the default configuration for a sync service.
 func DefaultConfig() Config {
-	return Config{100, 100}
+	return Config{
+		HashBufferSize:         100,
+		BlockBufferSize:        100,
+		BlockRequestBufferSize: 100,
+		RequestTimeout:         5 * time.Second,
+		MaxRequestAttempts:     3,
+		HeadRequestTimeout:     5 * time.Second,
+		BatchSize:              64,
+		MaxPendingBatches:      4,
+		SyncThreshold:          3,
+		RateLimitInterval:      10 * time.Second,
+		MaxHashesPerInterval:   50,
+		MaxBlocksPerInterval:   50,
+		SeenCacheSize:          4096,
+	}
 }
 
 // NewSyncService accepts a context and returns a new Service.
@@ -54,13 +136,32 @@ func NewSyncService(ctx context.Context, cfg Config, beaconp2p types.P2P, cs typ
 		chainService:         cs,
 		announceBlockHashBuf: make(chan p2p.Message, cfg.HashBufferSize),
 		blockBuf:             make(chan p2p.Message, cfg.BlockBufferSize),
+		blockRequestBuf:      make(chan p2p.Message, cfg.BlockRequestBufferSize),
+		chainHeadRequestBuf:  make(chan p2p.Message, cfg.BlockRequestBufferSize),
+		chainHeadBuf:         make(chan p2p.Message, cfg.BlockRequestBufferSize),
+		blockRangeRequestBuf: make(chan p2p.Message, cfg.BlockRequestBufferSize),
+		blockRangeBuf:        make(chan p2p.Message, cfg.BlockBufferSize),
+		requestTimeout:       cfg.RequestTimeout,
+		maxRequestAttempts:   cfg.MaxRequestAttempts,
+		headRequestTimeout:   cfg.HeadRequestTimeout,
+		batchSize:            cfg.BatchSize,
+		maxPendingBatches:    cfg.MaxPendingBatches,
+		syncThreshold:        cfg.SyncThreshold,
+		hashLimiter:          newRateLimiter(cfg.MaxHashesPerInterval, cfg.RateLimitInterval),
+		blockLimiter:         newRateLimiter(cfg.MaxBlocksPerInterval, cfg.RateLimitInterval),
+		seen:                 newSeenCache(cfg.SeenCacheSize),
+		pending:              make(map[[32]byte]*pendingRequest),
 	}
 }
 
-// Start begins the block processing goroutine.
+// Start begins the block processing goroutine. The local chain is first
+// caught up with the network head before gossip is processed.
 func (ss *Service) Start() {
 	log.Info("Starting service")
-	go ss.run(ss.ctx.Done())
+	go func() {
+		ss.initialSync(ss.ctx.Done())
+		ss.run(ss.ctx.Done())
+	}()
 }
 
 // Stop kills the block processing goroutine, but does not wait until the goroutine exits.
@@ -70,71 +171,278 @@ func (ss *Service) Stop() error {
 	return nil
 }
 
-// ReceiveBlockHash accepts a block hash.
+// ReceiveBlockHash accepts a block hash announced by a peer.
 // New hashes are forwarded to other peers in the network (unimplemented), and
-// the contents of the block are requested if the local chain doesn't have the block.
-func (ss *Service) ReceiveBlockHash(data *pb.BeaconBlockHashAnnounce) error {
-	var h [32]byte
-	copy(h[:], data.Hash[:32])
-	if ss.chainService.ContainsBlock(h) {
+// the contents of the block are requested from the peer if the local chain
+// doesn't have the block. Further announcements of a hash that is already
+// being requested are remembered as fallbacks in case the request times out.
+// Announcements of blocks that were already handled are ignored.
+func (ss *Service) ReceiveBlockHash(data *pb.BeaconBlockHashAnnounce, peer p2p.Peer) error {
+	h, err := toHash(data.Hash)
+	if err != nil {
+		ss.p2p.ReportPeer(peer, malformedMessagePenalty)
+		return err
+	}
+	announceMeter.Mark(1)
+	if ss.seen.contains(h) || ss.chainService.ContainsBlock(h) {
+		duplicateAnnounceMeter.Mark(1)
 		return nil
 	}
+
+	ss.pendingLock.Lock()
+	if req, ok := ss.pending[h]; ok {
+		if len(req.peers) < ss.maxRequestAttempts {
+			req.peers = append(req.peers, peer)
+		}
+		ss.pendingLock.Unlock()
+		duplicateAnnounceMeter.Mark(1)
+		return nil
+	}
+	ss.pending[h] = &pendingRequest{
+		peers:    []p2p.Peer{peer},
+		attempts: 1,
+		deadline: time.Now().Add(ss.requestTimeout),
+	}
+	ss.pendingLock.Unlock()
+
 	log.Info("Requesting full block data from sender")
-	// TODO: Request the full block data from peer that sent the block hash.
+	ss.p2p.Send(&pb.BeaconBlockRequest{Hash: h[:]}, peer)
+	return nil
+}
+
+// ReceiveBlockRequest serves a peer's request for the full data of a block
+// from the local chain. Requests for unknown blocks are ignored.
+func (ss *Service) ReceiveBlockRequest(data *pb.BeaconBlockRequest, req p2p.Message) error {
+	h, err := toHash(data.Hash)
+	if err != nil {
+		return err
+	}
+	if !ss.chainService.ContainsBlock(h) {
+		log.Debugf("Ignoring request for unknown block: %x", h)
+		return nil
+	}
+	block, err := ss.chainService.GetBlock(h)
+	if err != nil {
+		return fmt.Errorf("could not retrieve block: %v", err)
+	}
+	log.Debugf("Sending requested block to peer: %x", h)
+	ss.p2p.Reply(req, block.Proto())
+	return nil
+}
+
+// ReceiveChainHeadRequest answers a peer's request for the head of the local
+// canonical chain.
+func (ss *Service) ReceiveChainHeadRequest(req p2p.Message) error {
+	head, err := ss.chainService.CanonicalHead()
+	if err != nil {
+		return fmt.Errorf("could not retrieve canonical head: %v", err)
+	}
+	h, err := head.Hash()
+	if err != nil {
+		return fmt.Errorf("could not hash canonical head: %v", err)
+	}
+	ss.p2p.Reply(req, &pb.ChainHeadResponse{Slot: head.SlotNumber(), Hash: h[:]})
+	return nil
+}
+
+// ReceiveBlockRangeRequest serves a peer's request for the canonical blocks
+// of a range of slots. Ranges larger than the batch size or reaching past the
+// canonical head are truncated, and the response reports the number of slots
+// it covers.
+func (ss *Service) ReceiveBlockRangeRequest(data *pb.BeaconBlockRangeRequest, req p2p.Message) error {
+	head, err := ss.chainService.CanonicalHead()
+	if err != nil {
+		return fmt.Errorf("could not retrieve canonical head: %v", err)
+	}
+	count := data.Count
+	if count > ss.batchSize {
+		count = ss.batchSize
+	}
+	if data.StartSlot > head.SlotNumber() {
+		count = 0
+	} else if available := head.SlotNumber() + 1 - data.StartSlot; count > available {
+		count = available
+	}
+	res := &pb.BeaconBlockRangeResponse{StartSlot: data.StartSlot, Count: count}
+	for slot := data.StartSlot; slot < data.StartSlot+count; slot++ {
+		block, err := ss.chainService.CanonicalBlockBySlot(slot)
+		if err != nil {
+			return fmt.Errorf("could not retrieve block at slot %d: %v", slot, err)
+		}
+		if block != nil {
+			res.Blocks = append(res.Blocks, block.Proto())
+		}
+	}
+	ss.p2p.Reply(req, res)
 	return nil
 }
 
 // ReceiveBlock accepts a block to potentially be included in the local chain.
-// The service will filter blocks that have not been requested (unimplemented).
-func (ss *Service) ReceiveBlock(data *pb.BeaconBlockResponse) error {
+// The service drops blocks that have not been requested, and handles each
+// block at most once. Peers are rewarded for blocks accepted by the local
+// chain and penalized for rejected ones.
+func (ss *Service) ReceiveBlock(data *pb.BeaconBlockResponse, peer p2p.Peer) error {
 	block, err := types.NewBlockWithData(data)
 	if err != nil {
+		ss.p2p.ReportPeer(peer, malformedMessagePenalty)
 		return fmt.Errorf("could not instantiate new block from proto: %v", err)
 	}
 	h, err := block.Hash()
 	if err != nil {
 		return fmt.Errorf("could not hash block: %v", err)
 	}
-	if ss.chainService.ContainsBlock(h) {
+	blockMeter.Mark(1)
+	if ss.seen.contains(h) {
+		duplicateBlockMeter.Mark(1)
+		return nil
+	}
+
+	ss.pendingLock.Lock()
+	_, requested := ss.pending[h]
+	delete(ss.pending, h)
+	ss.pendingLock.Unlock()
+
+	if !requested {
+		log.Debugf("Dropping unsolicited block: %x", h)
+		return nil
+	}
+	if ss.seen.add(h) || ss.chainService.ContainsBlock(h) {
+		duplicateBlockMeter.Mark(1)
 		return nil
 	}
+	if err := ss.chainService.ProcessBlock(block); err != nil {
+		ss.p2p.ReportPeer(peer, invalidBlockPenalty)
+		return err
+	}
+	ss.p2p.ReportPeer(peer, validBlockReward)
 	log.Infof("Broadcasting block hash to peers: %x", h)
 	ss.p2p.Broadcast(&pb.BeaconBlockHashAnnounce{
 		Hash: h[:],
 	})
-	return ss.chainService.ProcessBlock(block)
+	return nil
+}
+
+// retryRequests resends block requests that have not been answered before
+// their deadline, trying the next peer that announced the block. Requests
+// are abandoned once the maximum number of attempts is reached.
+func (ss *Service) retryRequests(now time.Time) {
+	type retry struct {
+		hash [32]byte
+		peer p2p.Peer
+	}
+	var retries []retry
+
+	ss.pendingLock.Lock()
+	for h, req := range ss.pending {
+		if now.Before(req.deadline) {
+			continue
+		}
+		if req.attempts >= ss.maxRequestAttempts {
+			log.Warnf("Block request timed out after %d attempts: %x", req.attempts, h)
+			delete(ss.pending, h)
+			continue
+		}
+		peer := req.peers[req.attempts%len(req.peers)]
+		req.attempts++
+		req.deadline = now.Add(ss.requestTimeout)
+		retries = append(retries, retry{hash: h, peer: peer})
+	}
+	ss.pendingLock.Unlock()
+
+	for _, r := range retries {
+		log.Debugf("Retrying block request: %x", r.hash)
+		ss.p2p.Send(&pb.BeaconBlockRequest{Hash: r.hash[:]}, r.peer)
+	}
 }
 
 func (ss *Service) run(done <-chan struct{}) {
 	announceBlockHashSub := ss.p2p.Feed(pb.BeaconBlockHashAnnounce{}).Subscribe(ss.announceBlockHashBuf)
 	blockSub := ss.p2p.Feed(pb.BeaconBlockResponse{}).Subscribe(ss.blockBuf)
+	blockRequestSub := ss.p2p.Feed(pb.BeaconBlockRequest{}).Subscribe(ss.blockRequestBuf)
+	chainHeadRequestSub := ss.p2p.Feed(pb.ChainHeadRequest{}).Subscribe(ss.chainHeadRequestBuf)
+	blockRangeRequestSub := ss.p2p.Feed(pb.BeaconBlockRangeRequest{}).Subscribe(ss.blockRangeRequestBuf)
 	defer announceBlockHashSub.Unsubscribe()
 	defer blockSub.Unsubscribe()
+	defer blockRequestSub.Unsubscribe()
+	defer chainHeadRequestSub.Unsubscribe()
+	defer blockRangeRequestSub.Unsubscribe()
+
+	retryTicker := time.NewTicker(ss.requestTimeout)
+	defer retryTicker.Stop()
 	for {
 		select {
 		case <-done:
 			log.Infof("Exiting goroutine")
 			return
+		case now := <-retryTicker.C:
+			ss.retryRequests(now)
 		case msg := <-ss.announceBlockHashBuf:
-			data, ok := msg.Data.(pb.BeaconBlockHashAnnounce)
+			if !ss.hashLimiter.allow(msg.Peer, time.Now()) {
+				log.Debugf("Dropping block hash announcement from rate limited peer %s", msg.Peer.ID)
+				ss.p2p.ReportPeer(msg.Peer, rateLimitPenalty)
+				continue
+			}
+			data, ok := msg.Data.(*pb.BeaconBlockHashAnnounce)
 			// TODO: Handle this at p2p layer.
 			if !ok {
 				log.Error("Received malformed beacon block hash announcement p2p message")
+				ss.p2p.ReportPeer(msg.Peer, malformedMessagePenalty)
 				continue
 			}
-			if err := ss.ReceiveBlockHash(&data); err != nil {
+			if err := ss.ReceiveBlockHash(data, msg.Peer); err != nil {
 				log.Errorf("Could not receive incoming block hash: %v", err)
 			}
+		case msg := <-ss.blockRequestBuf:
+			data, ok := msg.Data.(*pb.BeaconBlockRequest)
+			// TODO: Handle this at p2p layer.
+			if !ok {
+				log.Error("Received malformed beacon block request p2p message")
+				ss.p2p.ReportPeer(msg.Peer, malformedMessagePenalty)
+				continue
+			}
+			if err := ss.ReceiveBlockRequest(data, msg); err != nil {
+				log.Errorf("Could not serve block request: %v", err)
+			}
+		case msg := <-ss.chainHeadRequestBuf:
+			if err := ss.ReceiveChainHeadRequest(msg); err != nil {
+				log.Errorf("Could not serve chain head request: %v", err)
+			}
+		case msg := <-ss.blockRangeRequestBuf:
+			data, ok := msg.Data.(*pb.BeaconBlockRangeRequest)
+			// TODO: Handle this at p2p layer.
+			if !ok {
+				log.Error("Received malformed beacon block range request p2p message")
+				ss.p2p.ReportPeer(msg.Peer, malformedMessagePenalty)
+				continue
+			}
+			if err := ss.ReceiveBlockRangeRequest(data, msg); err != nil {
+				log.Errorf("Could not serve block range request: %v", err)
+			}
 		case msg := <-ss.blockBuf:
-			data, ok := msg.Data.(pb.BeaconBlockResponse)
+			if !ss.blockLimiter.allow(msg.Peer, time.Now()) {
+				log.Debugf("Dropping block from rate limited peer %s", msg.Peer.ID)
+				ss.p2p.ReportPeer(msg.Peer, rateLimitPenalty)
+				continue
+			}
+			data, ok := msg.Data.(*pb.BeaconBlockResponse)
 			// TODO: Handle this at p2p layer.
 			if !ok {
 				log.Errorf("Received malformed beacon block p2p message")
+				ss.p2p.ReportPeer(msg.Peer, malformedMessagePenalty)
 				continue
 			}
-			if err := ss.ReceiveBlock(&data); err != nil {
+			if err := ss.ReceiveBlock(data, msg.Peer); err != nil {
 				log.Errorf("Could not receive incoming block: %v", err)
 			}
 		}
 	}
 }
+
+func toHash(b []byte) ([32]byte, error) {
+	var h [32]byte
+	if len(b) != len(h) {
+		return h, fmt.Errorf("expected a 32 byte hash, received %d bytes", len(b))
+	}
+	copy(h[:], b)
+	return h, nil
+}
@@ -1,9 +1,13 @@
 package sync
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/event"
 	blake2b "github.com/minio/blake2b-simd"
@@ -14,19 +18,78 @@ import (
 	logTest "github.com/sirupsen/logrus/hooks/test"
 )
 
-type mockP2P struct{}
+type mockP2P struct {
+	lock   sync.Mutex
+	sent   []interface{}
+	sentTo []p2p.Peer
+	scores map[p2p.Peer]int
+	// onSend is called with every message sent or broadcast, if set.
+	onSend   func(msg interface{})
+	statuses []p2p.PeerStatus
+}
 
 func (mp *mockP2P) Feed(msg interface{}) *event.Feed {
 	return new(event.Feed)
 }
 
-func (mp *mockP2P) Broadcast(msg interface{}) {}
+func (mp *mockP2P) Send(msg interface{}, peer p2p.Peer) {
+	mp.lock.Lock()
+	mp.sent = append(mp.sent, msg)
+	mp.sentTo = append(mp.sentTo, peer)
+	mp.lock.Unlock()
+	if mp.onSend != nil {
+		mp.onSend(msg)
+	}
+}
+
+func (mp *mockP2P) Reply(req p2p.Message, msg interface{}) {
+	mp.Send(msg, req.Peer)
+}
+
+func (mp *mockP2P) Broadcast(msg interface{}) {
+	if mp.onSend != nil {
+		mp.onSend(msg)
+	}
+}
+
+func (mp *mockP2P) ReportPeer(peer p2p.Peer, delta int) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	if mp.scores == nil {
+		mp.scores = make(map[p2p.Peer]int)
+	}
+	mp.scores[peer] += delta
+}
+
+func (mp *mockP2P) PeerStatuses() []p2p.PeerStatus {
+	return mp.statuses
+}
+
+func (mp *mockP2P) score(peer p2p.Peer) int {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	return mp.scores[peer]
+}
+
+func (mp *mockP2P) sentMessages() []interface{} {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	return mp.sent
+}
 
 type mockChainService struct {
 	processedHashes [][32]byte
+	blocks          map[[32]byte]*types.Block
+	canonical       map[uint64]*types.Block
+	head            *types.Block
+	// rejectBlocks makes ProcessBlock fail, as for invalid blocks.
+	rejectBlocks bool
 }
 
 func (ms *mockChainService) ProcessBlock(b *types.Block) error {
+	if ms.rejectBlocks {
+		return errors.New("invalid block")
+	}
 	h, err := b.Hash()
 	if err != nil {
 		return err
@@ -36,9 +99,39 @@ func (ms *mockChainService) ProcessBlock(b *types.Block) error {
 		ms.processedHashes = [][32]byte{}
 	}
 	ms.processedHashes = append(ms.processedHashes, h)
+	if ms.blocks == nil {
+		ms.blocks = make(map[[32]byte]*types.Block)
+	}
+	ms.blocks[h] = b
+	if ms.canonical == nil {
+		ms.canonical = make(map[uint64]*types.Block)
+	}
+	ms.canonical[b.SlotNumber()] = b
+	if ms.head == nil || b.SlotNumber() >= ms.head.SlotNumber() {
+		ms.head = b
+	}
 	return nil
 }
 
+func (ms *mockChainService) CanonicalHead() (*types.Block, error) {
+	if ms.head == nil {
+		return types.NewGenesisBlock()
+	}
+	return ms.head, nil
+}
+
+func (ms *mockChainService) CanonicalBlockBySlot(slot uint64) (*types.Block, error) {
+	return ms.canonical[slot], nil
+}
+
+func (ms *mockChainService) GetBlock(h [32]byte) (*types.Block, error) {
+	b, ok := ms.blocks[h]
+	if !ok {
+		return nil, errors.New("block not found")
+	}
+	return b, nil
+}
+
 func (ms *mockChainService) ContainsBlock(h [32]byte) bool {
 	for _, h1 := range ms.processedHashes {
 		if h == h1 {
@@ -52,12 +145,47 @@ func (ms *mockChainService) ProcessedHashes() [][32]byte {
 	return ms.processedHashes
 }
 
+func testConfig() Config {
+	// set the channel's buffer to 0 to make channel interactions blocking
+	return Config{
+		HashBufferSize:         0,
+		BlockBufferSize:        0,
+		BlockRequestBufferSize: 0,
+		RequestTimeout:         time.Minute,
+		MaxRequestAttempts:     3,
+		HeadRequestTimeout:     10 * time.Millisecond,
+		BatchSize:              4,
+		MaxPendingBatches:      2,
+		SyncThreshold:          1,
+		RateLimitInterval:      time.Minute,
+		MaxHashesPerInterval:   10,
+		MaxBlocksPerInterval:   10,
+		SeenCacheSize:          100,
+	}
+}
+
+// requestBlock makes the service expect the block, as if its hash had been
+// announced by a peer.
+func requestBlock(t *testing.T, ss *Service, data *pb.BeaconBlockResponse) [32]byte {
+	block, err := types.NewBlockWithData(data)
+	if err != nil {
+		t.Fatalf("Could not instantiate new block from proto: %v", err)
+	}
+	h, err := block.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.ReceiveBlockHash(&pb.BeaconBlockHashAnnounce{Hash: h[:]}, p2p.Peer{}); err != nil {
+		t.Fatalf("Could not receive block hash: %v", err)
+	}
+	return h
+}
+
 func TestProcessBlockHash(t *testing.T) {
 	hook := logTest.NewGlobal()
 
-	// set the channel's buffer to 0 to make channel interactions blocking
-	cfg := Config{HashBufferSize: 0, BlockBufferSize: 0}
-	ss := NewSyncService(context.Background(), cfg, &mockP2P{}, &mockChainService{})
+	mp := &mockP2P{}
+	ss := NewSyncService(context.Background(), testConfig(), mp, &mockChainService{})
 
 	exitRoutine := make(chan bool)
 
@@ -73,7 +201,7 @@ func TestProcessBlockHash(t *testing.T) {
 
 	msg := p2p.Message{
 		Peer: p2p.Peer{},
-		Data: hashAnnounce,
+		Data: &hashAnnounce,
 	}
 
 	// if a new hash is processed
@@ -83,15 +211,26 @@ func TestProcessBlockHash(t *testing.T) {
 	<-exitRoutine
 
 	testutil.AssertLogsContain(t, hook, "Requesting full block data from sender")
+
+	sent := mp.sentMessages()
+	if len(sent) != 1 {
+		t.Fatalf("Expected 1 block request to be sent, got %d", len(sent))
+	}
+	req, ok := sent[0].(*pb.BeaconBlockRequest)
+	if !ok {
+		t.Fatalf("Expected a block request to be sent, got %T", sent[0])
+	}
+	if !bytes.Equal(req.Hash, announceHash[:]) {
+		t.Errorf("Requested wrong block hash. wanted=%x, got=%x", announceHash, req.Hash)
+	}
 	hook.Reset()
 }
 
 func TestProcessBlock(t *testing.T) {
 	hook := logTest.NewGlobal()
 
-	cfg := Config{HashBufferSize: 0, BlockBufferSize: 0}
 	ms := &mockChainService{}
-	ss := NewSyncService(context.Background(), cfg, &mockP2P{}, ms)
+	ss := NewSyncService(context.Background(), testConfig(), &mockP2P{}, ms)
 
 	exitRoutine := make(chan bool)
 
@@ -103,10 +242,11 @@ func TestProcessBlock(t *testing.T) {
 	blockResponse := pb.BeaconBlockResponse{
 		MainChainRef: []byte{1, 2, 3, 4, 5},
 	}
+	requestBlock(t, ss, &blockResponse)
 
 	msg := p2p.Message{
 		Peer: p2p.Peer{},
-		Data: blockResponse,
+		Data: &blockResponse,
 	}
 
 	ss.blockBuf <- msg
@@ -134,9 +274,8 @@ func TestProcessBlock(t *testing.T) {
 func TestProcessMultipleBlocks(t *testing.T) {
 	hook := logTest.NewGlobal()
 
-	cfg := Config{HashBufferSize: 0, BlockBufferSize: 0}
 	ms := &mockChainService{}
-	ss := NewSyncService(context.Background(), cfg, &mockP2P{}, ms)
+	ss := NewSyncService(context.Background(), testConfig(), &mockP2P{}, ms)
 
 	exitRoutine := make(chan bool)
 
@@ -148,19 +287,21 @@ func TestProcessMultipleBlocks(t *testing.T) {
 	blockResponse1 := pb.BeaconBlockResponse{
 		MainChainRef: []byte{1, 2, 3, 4, 5},
 	}
+	requestBlock(t, ss, &blockResponse1)
 
 	msg1 := p2p.Message{
 		Peer: p2p.Peer{},
-		Data: blockResponse1,
+		Data: &blockResponse1,
 	}
 
 	blockResponse2 := pb.BeaconBlockResponse{
 		MainChainRef: []byte{6, 7, 8, 9, 10},
 	}
+	requestBlock(t, ss, &blockResponse2)
 
 	msg2 := p2p.Message{
 		Peer: p2p.Peer{},
-		Data: blockResponse2,
+		Data: &blockResponse2,
 	}
 
 	ss.blockBuf <- msg1
@@ -202,9 +343,8 @@ func TestProcessMultipleBlocks(t *testing.T) {
 func TestProcessSameBlock(t *testing.T) {
 	hook := logTest.NewGlobal()
 
-	cfg := Config{HashBufferSize: 0, BlockBufferSize: 0}
 	ms := &mockChainService{}
-	ss := NewSyncService(context.Background(), cfg, &mockP2P{}, ms)
+	ss := NewSyncService(context.Background(), testConfig(), &mockP2P{}, ms)
 
 	exitRoutine := make(chan bool)
 
@@ -216,10 +356,11 @@ func TestProcessSameBlock(t *testing.T) {
 	blockResponse := pb.BeaconBlockResponse{
 		MainChainRef: []byte{1, 2, 3},
 	}
+	requestBlock(t, ss, &blockResponse)
 
 	msg := p2p.Message{
 		Peer: p2p.Peer{},
-		Data: blockResponse,
+		Data: &blockResponse,
 	}
 	ss.blockBuf <- msg
 	ss.blockBuf <- msg
@@ -246,3 +387,210 @@ func TestProcessSameBlock(t *testing.T) {
 	}
 	hook.Reset()
 }
+
+func TestDropUnsolicitedBlock(t *testing.T) {
+	hook := logTest.NewGlobal()
+
+	ms := &mockChainService{}
+	ss := NewSyncService(context.Background(), testConfig(), &mockP2P{}, ms)
+
+	exitRoutine := make(chan bool)
+
+	go func() {
+		ss.run(ss.ctx.Done())
+		exitRoutine <- true
+	}()
+
+	blockResponse := pb.BeaconBlockResponse{
+		MainChainRef: []byte{1, 2, 3},
+	}
+
+	msg := p2p.Message{
+		Peer: p2p.Peer{},
+		Data: &blockResponse,
+	}
+	ss.blockBuf <- msg
+	ss.cancel()
+	<-exitRoutine
+
+	testutil.AssertLogsDoNotContain(t, hook, "Broadcasting block hash to peers")
+	if len(ms.processedHashes) != 0 {
+		t.Errorf("Unsolicited block should not have been processed")
+	}
+	hook.Reset()
+}
+
+func TestServeBlockRequest(t *testing.T) {
+	mp := &mockP2P{}
+	ms := &mockChainService{}
+	ss := NewSyncService(context.Background(), testConfig(), mp, ms)
+
+	exitRoutine := make(chan bool)
+
+	go func() {
+		ss.run(ss.ctx.Done())
+		exitRoutine <- true
+	}()
+
+	block, err := types.NewBlockWithData(&pb.BeaconBlockResponse{
+		MainChainRef: []byte{1, 2, 3},
+	})
+	if err != nil {
+		t.Fatalf("Could not instantiate new block from proto: %v", err)
+	}
+	if err := ms.ProcessBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	h, err := block.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := blake2b.Sum256([]byte{'u'})
+
+	ss.blockRequestBuf <- p2p.Message{
+		Peer: p2p.Peer{},
+		Data: &pb.BeaconBlockRequest{Hash: unknown[:]},
+	}
+	ss.blockRequestBuf <- p2p.Message{
+		Peer: p2p.Peer{},
+		Data: &pb.BeaconBlockRequest{Hash: h[:]},
+	}
+	ss.cancel()
+	<-exitRoutine
+
+	sent := mp.sentMessages()
+	if len(sent) != 1 {
+		t.Fatalf("Expected 1 block to be sent, got %d", len(sent))
+	}
+	res, ok := sent[0].(*pb.BeaconBlockResponse)
+	if !ok {
+		t.Fatalf("Expected a block response to be sent, got %T", sent[0])
+	}
+	if !bytes.Equal(res.MainChainRef, block.Proto().MainChainRef) {
+		t.Errorf("Sent wrong block. wanted=%v, got=%v", block.Proto(), res)
+	}
+}
+
+func TestRetryRequests(t *testing.T) {
+	hook := logTest.NewGlobal()
+
+	mp := &mockP2P{}
+	ss := NewSyncService(context.Background(), testConfig(), mp, &mockChainService{})
+
+	h := requestBlock(t, ss, &pb.BeaconBlockResponse{MainChainRef: []byte{1}})
+
+	// Nothing is resent before the deadline.
+	ss.retryRequests(time.Now())
+	if n := len(mp.sentMessages()); n != 1 {
+		t.Fatalf("Expected 1 request before the deadline, got %d", n)
+	}
+
+	now := time.Now()
+	for i := 0; i < ss.maxRequestAttempts; i++ {
+		now = now.Add(2 * ss.requestTimeout)
+		ss.retryRequests(now)
+	}
+	if n := len(mp.sentMessages()); n != ss.maxRequestAttempts {
+		t.Errorf("Expected %d requests, got %d", ss.maxRequestAttempts, n)
+	}
+	if _, ok := ss.pending[h]; ok {
+		t.Error("Request should have been abandoned after the maximum number of attempts")
+	}
+	testutil.AssertLogsContain(t, hook, "Block request timed out")
+	hook.Reset()
+}
+
+func TestRateLimitBlockHashes(t *testing.T) {
+	mp := &mockP2P{}
+	cfg := testConfig()
+	cfg.MaxHashesPerInterval = 1
+	ss := NewSyncService(context.Background(), cfg, mp, &mockChainService{})
+
+	exitRoutine := make(chan bool)
+
+	go func() {
+		ss.run(ss.ctx.Done())
+		exitRoutine <- true
+	}()
+
+	peer := p2p.Peer{ID: "a"}
+	for _, b := range []byte{1, 2} {
+		h := blake2b.Sum256([]byte{b})
+		ss.announceBlockHashBuf <- p2p.Message{
+			Peer: peer,
+			Data: &pb.BeaconBlockHashAnnounce{Hash: h[:]},
+		}
+	}
+	// Other peers have their own limit.
+	h := blake2b.Sum256([]byte{3})
+	ss.announceBlockHashBuf <- p2p.Message{
+		Peer: p2p.Peer{ID: "b"},
+		Data: &pb.BeaconBlockHashAnnounce{Hash: h[:]},
+	}
+	ss.cancel()
+	<-exitRoutine
+
+	if n := len(mp.sentMessages()); n != 2 {
+		t.Errorf("Expected 2 block requests to be sent, got %d", n)
+	}
+	if score := mp.score(peer); score != rateLimitPenalty {
+		t.Errorf("Expected rate limited peer to be penalized by %d, got %d", rateLimitPenalty, score)
+	}
+}
+
+func TestPenalizeInvalidBlock(t *testing.T) {
+	hook := logTest.NewGlobal()
+
+	mp := &mockP2P{}
+	ms := &mockChainService{rejectBlocks: true}
+	ss := NewSyncService(context.Background(), testConfig(), mp, ms)
+
+	data := &pb.BeaconBlockResponse{MainChainRef: []byte{1, 2, 3}}
+	requestBlock(t, ss, data)
+
+	peer := p2p.Peer{ID: "a"}
+	if err := ss.ReceiveBlock(data, peer); err == nil {
+		t.Fatal("Expected invalid block to be rejected")
+	}
+	if score := mp.score(peer); score != invalidBlockPenalty {
+		t.Errorf("Expected peer to be penalized by %d, got %d", invalidBlockPenalty, score)
+	}
+	testutil.AssertLogsDoNotContain(t, hook, "Broadcasting block hash to peers")
+
+	// Malformed announcements are penalized as well.
+	if err := ss.ReceiveBlockHash(&pb.BeaconBlockHashAnnounce{Hash: []byte{1}}, peer); err == nil {
+		t.Fatal("Expected malformed block hash to be rejected")
+	}
+	if score := mp.score(peer); score != invalidBlockPenalty+malformedMessagePenalty {
+		t.Errorf("Expected peer to be penalized by %d, got %d", invalidBlockPenalty+malformedMessagePenalty, score)
+	}
+	hook.Reset()
+}
+
+func TestSuppressDuplicateBlocks(t *testing.T) {
+	mp := &mockP2P{}
+	ms := &mockChainService{rejectBlocks: true}
+	ss := NewSyncService(context.Background(), testConfig(), mp, ms)
+
+	data := &pb.BeaconBlockResponse{MainChainRef: []byte{1, 2, 3}}
+	h := requestBlock(t, ss, data)
+
+	if err := ss.ReceiveBlock(data, p2p.Peer{}); err == nil {
+		t.Fatal("Expected invalid block to be rejected")
+	}
+	// The block is not processed again, even though the chain rejected it.
+	if err := ss.ReceiveBlock(data, p2p.Peer{}); err != nil {
+		t.Fatalf("Expected duplicate block to be ignored, got %v", err)
+	}
+	if score := mp.score(p2p.Peer{}); score != invalidBlockPenalty {
+		t.Errorf("Expected peer to be penalized once by %d, got %d", invalidBlockPenalty, score)
+	}
+
+	// Later announcements of the block are not requested again.
+	if err := ss.ReceiveBlockHash(&pb.BeaconBlockHashAnnounce{Hash: h[:]}, p2p.Peer{}); err != nil {
+		t.Fatalf("Could not receive block hash: %v", err)
+	}
+	if n := len(mp.sentMessages()); n != 1 {
+		t.Errorf("Expected only the first block request to be sent, got %d", n)
+	}
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"encoding/binary"
 	"fmt"
 	"time"
 
@@ -21,7 +22,7 @@ type AggregateVote struct {
 	ShardID        uint32 // Shard ID of the voted shard.
 	ShardBlockHash []byte // ShardBlockHash is the shard block hash of the voted shard.
 	SignerBitmask  []byte // SignerBitmask is the bit mask of every validator that signed.
-	AggregateSig   []uint // AggregateSig is the aggregated signatures of individual shard.
+	AggregateSig   []byte // AggregateSig is the aggregated BLS signature of individual shard.
 }
 
 // NewBlock creates a new beacon block given certain arguments.
@@ -46,6 +47,16 @@ func NewGenesisBlock() (*Block, error) {
 	return &Block{data: &pb.BeaconBlockResponse{Timestamp: protoGenesis}}, nil
 }
 
+// Proto returns the underlying protobuf data of the block.
+func (b *Block) Proto() *pb.BeaconBlockResponse {
+	return b.data
+}
+
+// Marshal encodes the block into its protobuf wire format.
+func (b *Block) Marshal() ([]byte, error) {
+	return proto.Marshal(b.data)
+}
+
 // Hash generates the blake2b hash of the block
 func (b *Block) Hash() ([32]byte, error) {
 	data, err := proto.Marshal(b.data)
@@ -67,6 +78,26 @@ func (b *Block) SlotNumber() uint64 {
 	return b.data.SlotNumber
 }
 
+// AttestationBitmask returns the bitmask of attesters that signed the block,
+// with the first attester in the most significant bit.
+func (b *Block) AttestationBitmask() []byte {
+	return b.data.AttestationBitmask
+}
+
+// AttestationAggregateSig returns the aggregated BLS signature of the attesters.
+func (b *Block) AttestationAggregateSig() []byte {
+	return b.data.AttestationAggregateSig
+}
+
+// AttestationMessage returns the message attesters sign for the block, the
+// blake2b hash of the big-endian slot number and the parent hash.
+func (b *Block) AttestationMessage() [32]byte {
+	msg := make([]byte, 8, 8+len(b.data.ParentHash))
+	binary.BigEndian.PutUint64(msg, b.data.SlotNumber)
+	msg = append(msg, b.data.ParentHash...)
+	return blake2b.Sum256(msg)
+}
+
 // MainChainRef returns a keccak256 hash corresponding to a PoW chain block.
 func (b *Block) MainChainRef() common.Hash {
 	return common.BytesToHash(b.data.MainChainRef)
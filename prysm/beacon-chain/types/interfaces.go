@@ -7,12 +7,18 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	gethTypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/prysmaticlabs/prysm/shared/p2p"
 )
 
-// P2P defines a struct that can subscribe to feeds, request data, and broadcast data.
+// P2P defines a struct that can subscribe to feeds, request data, broadcast data
+// and report the behaviour of peers.
 type P2P interface {
 	Feed(msg interface{}) *event.Feed
+	Send(msg interface{}, peer p2p.Peer)
+	Reply(req p2p.Message, msg interface{})
 	Broadcast(msg interface{})
+	ReportPeer(peer p2p.Peer, delta int)
+	PeerStatuses() []p2p.PeerStatus
 }
 
 // ChainService is the interface for the local beacon chain.
@@ -20,6 +26,9 @@ type ChainService interface {
 	ProcessedHashes() [][32]byte
 	ProcessBlock(b *Block) error
 	ContainsBlock(h [32]byte) bool
+	GetBlock(h [32]byte) (*Block, error)
+	CanonicalHead() (*Block, error)
+	CanonicalBlockBySlot(slot uint64) (*Block, error)
 }
 
 // Reader defines a struct that can fetch latest header events from a web3 endpoint.
@@ -27,9 +36,13 @@ type Reader interface {
 	SubscribeNewHead(ctx context.Context, ch chan<- *gethTypes.Header) (ethereum.Subscription, error)
 }
 
-// POWBlockFetcher defines a struct that can retrieve mainchain blocks.
+// POWBlockFetcher defines a struct that can retrieve mainchain blocks, the
+// headers of the canonical mainchain, and the latest mainchain block deep
+// enough to be referenced by beacon blocks.
 type POWBlockFetcher interface {
 	BlockByHash(ctx context.Context, hash common.Hash) (*gethTypes.Block, error)
+	CanonicalHeader(number uint64) *gethTypes.Header
+	ConfirmedBlock() *gethTypes.Header
 }
 
 // Logger subscribe filtered log on the PoW chain
@@ -2,7 +2,6 @@ package types
 
 import (
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/p2p/enr"
 )
 
 // ActiveState contains fields of current state of beacon chain,
@@ -30,7 +29,7 @@ type CrystallizedState struct {
 
 // ValidatorRecord contains information about a validator
 type ValidatorRecord struct {
-	PubKey            enr.Secp256k1  // PubKey is the validator's public key.
+	PubKey            []byte         // PubKey is the validator's compressed BLS public key.
 	WithdrawalShard   uint16         // WithdrawalShard is the shard balance will be sent to after withdrawal.
 	WithdrawalAddress common.Address // WithdrawalAddress is the address balance will be sent to after withdrawal.
 	RandaoCommitment  common.Hash    // RandaoCommitment is validator's current RANDAO beacon commitment.
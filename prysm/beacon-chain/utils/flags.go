@@ -8,14 +8,25 @@ var (
 	// Web3ProviderFlag defines a flag for a mainchain RPC endpoint.
 	Web3ProviderFlag = cli.StringFlag{
 		Name:  "web3provider",
-		Usage: "A mainchain web3 provider string endpoint. Can either be an IPC file string or a WebSocket endpoint. Uses WebSockets by default at ws://127.0.0.1:8546. Cannot be an HTTP endpoint.",
+		Usage: "A mainchain web3 provider string endpoint. Can either be an IPC file string, a WebSocket endpoint, an HTTP endpoint, which is polled for updates, or \"simulated\" for an in-process PoW chain with the VRC deployed. Uses WebSockets by default at ws://127.0.0.1:8546.",
 		Value: "ws://127.0.0.1:8546",
 	}
+	// FollowDistanceFlag defines the number of confirmations a PoW block needs.
+	FollowDistanceFlag = cli.Uint64Flag{
+		Name:  "followdistance",
+		Usage: "Number of confirmations a PoW block needs before the beacon chain node references it and processes its VRC deposits. Protects against PoW chain reorgs.",
+		Value: 16,
+	}
 	// VrcContractFlag defines a flag for VRC contract address.
 	VrcContractFlag = cli.StringFlag{
 		Name:  "vrcaddr",
 		Usage: "Validator registration contract address. Beacon chain node will listen logs coming from VRC to determine when validator is eligible to participate.",
 	}
+	// VrcDeploymentBlockFlag defines the PoW block the VRC was deployed in.
+	VrcDeploymentBlockFlag = cli.Uint64Flag{
+		Name:  "vrcdeployblock",
+		Usage: "PoW block number the validator registration contract was deployed in. Deposits are scanned from this block on the first start.",
+	}
 	// PubKeyFlag defines a flag for validator's public key on the mainchain
 	PubKeyFlag = cli.StringFlag{
 		Name:  "pubkey",
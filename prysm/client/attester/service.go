@@ -18,12 +18,12 @@ var log = logrus.WithField("prefix", "attester")
 type Attester struct {
 	config    *params.Config
 	smcClient *mainchain.SMCClient
-	p2p       *p2p.Server
+	p2p       p2p.P2P
 	dbService *database.DB
 }
 
 // NewAttester creates a new attester instance.
-func NewAttester(config *params.Config, smcClient *mainchain.SMCClient, p2p *p2p.Server, dbService *database.DB) (*Attester, error) {
+func NewAttester(config *params.Config, smcClient *mainchain.SMCClient, p2p p2p.P2P, dbService *database.DB) (*Attester, error) {
 	return &Attester{config, smcClient, p2p, dbService}, nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
@@ -34,6 +35,7 @@ import (
 var log = logrus.WithField("prefix", "node")
 
 const shardChainDBName = "shardchaindata"
+const nodeKeyFileName = "shardnodekey"
 
 // ShardEthereum is a service that is registered and started when geth is launched.
 // it contains APIs and fields that handle the different components of the sharded
@@ -64,7 +66,7 @@ func New(ctx *cli.Context) (*ShardEthereum, error) {
 		return nil, err
 	}
 
-	if err := shardEthereum.registerP2P(); err != nil {
+	if err := shardEthereum.registerP2P(ctx); err != nil {
 		return nil, err
 	}
 
@@ -150,8 +152,31 @@ func (s *ShardEthereum) startDB(ctx *cli.Context) error {
 }
 
 // registerP2P attaches a p2p server to the ShardEthereum instance.
-func (s *ShardEthereum) registerP2P() error {
-	shardp2p, err := p2p.NewServer()
+func (s *ShardEthereum) registerP2P(ctx *cli.Context) error {
+	keyFile := ctx.GlobalString(cmd.NodeKeyFlag.Name)
+	if keyFile == "" {
+		path := node.DefaultDataDir()
+		if ctx.GlobalIsSet(cmd.DataDirFlag.Name) {
+			path = ctx.GlobalString(cmd.DataDirFlag.Name)
+		}
+		keyFile = filepath.Join(path, nodeKeyFileName)
+	}
+	shardp2p, err := p2p.NewServer(&p2p.ServerConfig{
+		ListenAddrs:       ctx.GlobalStringSlice(cmd.P2PListenFlag.Name),
+		ExternalAddr:      ctx.GlobalString(cmd.P2PExternalAddrFlag.Name),
+		KeyFile:           keyFile,
+		StaticPeers:       ctx.GlobalStringSlice(cmd.StaticPeersFlag.Name),
+		BootstrapNodes:    ctx.GlobalStringSlice(cmd.BootstrapNodesFlag.Name),
+		NoMDNS:            ctx.GlobalBool(cmd.NoMDNSFlag.Name),
+		EnableDHT:         ctx.GlobalBool(cmd.DHTFlag.Name),
+		TargetPeers:       ctx.GlobalInt(cmd.TargetPeersFlag.Name),
+		MaxPeers:          ctx.GlobalInt(cmd.MaxPeersFlag.Name),
+		NetworkID:         ctx.GlobalUint64(cmd.NetworkIDFlag.Name),
+		EnableCompression: ctx.GlobalBool(cmd.P2PCompressFlag.Name),
+		RecordFile:        ctx.GlobalString(cmd.P2PRecordFlag.Name),
+		RecordFileSize:    int64(ctx.GlobalInt(cmd.P2PRecordSizeFlag.Name)) << 20,
+		ReplayPeers:       ctx.GlobalStringSlice(cmd.P2PReplayFlag.Name),
+	})
 	if err != nil {
 		return fmt.Errorf("could not register shardp2p service: %v", err)
 	}
@@ -20,7 +20,7 @@ var log = logrus.WithField("prefix", "observer")
 // in a sharded system. Must satisfy the Service interface defined in
 // sharding/service.go.
 type Observer struct {
-	p2p       *p2p.Server
+	p2p       p2p.P2P
 	dbService *database.DB
 	shardID   int
 	shard     *types.Shard
@@ -32,7 +32,7 @@ type Observer struct {
 
 // NewObserver creates a struct instance of a observer service,
 // it will have access to a p2p server and a shardChainDB.
-func NewObserver(p2p *p2p.Server, dbService *database.DB, shardID int, sync *syncer.Syncer, client *mainchain.SMCClient) (*Observer, error) {
+func NewObserver(p2p p2p.P2P, dbService *database.DB, shardID int, sync *syncer.Syncer, client *mainchain.SMCClient) (*Observer, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Observer{p2p, dbService, shardID, nil, ctx, cancel, sync, client}, nil
 }
@@ -19,7 +19,7 @@ func TestStartStop(t *testing.T) {
 
 	hook := logTest.NewGlobal()
 
-	server, err := p2p.NewServer()
+	server, err := p2p.NewServer(&p2p.ServerConfig{})
 	if err != nil {
 		t.Fatalf("Unable to setup p2p server: %v", err)
 	}
@@ -28,7 +28,7 @@ var log = logrus.WithField("prefix", "proposer")
 type Proposer struct {
 	config    *params.Config
 	client    mainchain.FullClient
-	p2p       *p2p.Server
+	p2p       p2p.P2P
 	txpool    *txpool.TXPool
 	txpoolSub event.Subscription
 	dbService *database.DB
@@ -43,7 +43,7 @@ type Proposer struct {
 // NewProposer creates a struct instance of a proposer service.
 // It will have access to a mainchain client, a p2p network,
 // and a shard transaction pool.
-func NewProposer(config *params.Config, client mainchain.FullClient, p2p *p2p.Server, txpool *txpool.TXPool, dbService *database.DB, shardID int, sync *syncer.Syncer) (*Proposer, error) {
+func NewProposer(config *params.Config, client mainchain.FullClient, p2p p2p.P2P, txpool *txpool.TXPool, dbService *database.DB, shardID int, sync *syncer.Syncer) (*Proposer, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Proposer{
 		config:    config,
@@ -66,7 +66,7 @@ func (p *Proposer) Start() {
 	log.Info("Starting proposer service")
 	p.shard = types.NewShard(big.NewInt(int64(p.shardID)), p.dbService.DB())
 	p.msgChan = make(chan p2p.Message, 20)
-	p.txpoolSub = p.p2p.Subscribe(pb.Transaction{}, p.msgChan)
+	p.txpoolSub = p.p2p.SubscribeShard(uint64(p.shardID), pb.Transaction{}, p.msgChan)
 	go p.proposeCollations()
 }
 
@@ -84,7 +84,7 @@ func (p *Proposer) Stop() error {
 // proposeCollations listens to the transaction feed and submits collations over an interval.
 func (p *Proposer) proposeCollations() {
 	ch := make(chan p2p.Message, 20)
-	sub := p.p2p.Subscribe(pb.Transaction{}, ch)
+	sub := p.p2p.SubscribeShard(uint64(p.shardID), pb.Transaction{}, ch)
 	collation := []*gethTypes.Transaction{}
 	sizeOfCollation := int64(0)
 	period, err := p.currentPeriod(p.ctx)
@@ -27,7 +27,7 @@ func settingUpProposer(t *testing.T) (*Proposer, *internal.MockClient) {
 	backend, smc := internal.SetupMockClient(t)
 	node := &internal.MockClient{SMC: smc, T: t, Backend: backend}
 
-	server, err := p2p.NewServer()
+	server, err := p2p.NewServer(&p2p.ServerConfig{})
 	if err != nil {
 		t.Fatalf("Failed to start server %v", err)
 	}
@@ -88,7 +88,7 @@ func TestProposerRoundTrip(t *testing.T) {
 	defer func() {
 		fakeProposer.dbService.Close()
 		fakeProposer.txpool.Stop()
-		fakeProposer.p2p.Stop()
+		fakeProposer.p2p.(*p2p.Server).Stop()
 	}()
 
 	input := make([]byte, 0, 2000)
@@ -104,7 +104,7 @@ func TestProposerRoundTrip(t *testing.T) {
 	defer fakeProposer.Stop()
 
 	for i := 0; i < 4; i++ {
-		fakeProposer.p2p.Broadcast(&tx)
+		fakeProposer.p2p.BroadcastShard(uint64(fakeProposer.shardID), &tx)
 	}
 
 	if _, err := waitForLogMsg(hook, "Collation created"); err != nil {
@@ -118,7 +118,7 @@ func TestIncompleteCollation(t *testing.T) {
 	defer func() {
 		fakeProposer.dbService.Close()
 		fakeProposer.txpool.Stop()
-		fakeProposer.p2p.Stop()
+		fakeProposer.p2p.(*p2p.Server).Stop()
 	}()
 
 	input := make([]byte, 0, 2000)
@@ -133,7 +133,7 @@ func TestIncompleteCollation(t *testing.T) {
 	fakeProposer.Start()
 
 	for i := 0; i < 3; i++ {
-		fakeProposer.p2p.Broadcast(&tx)
+		fakeProposer.p2p.BroadcastShard(uint64(fakeProposer.shardID), &tx)
 	}
 	fakeProposer.Stop()
 
@@ -154,7 +154,7 @@ func TestCollationWitInDiffPeriod(t *testing.T) {
 	defer func() {
 		fakeProposer.dbService.Close()
 		fakeProposer.txpool.Stop()
-		fakeProposer.p2p.Stop()
+		fakeProposer.p2p.(*p2p.Server).Stop()
 	}()
 
 	input := make([]byte, 0, 2000)
@@ -173,7 +173,7 @@ func TestCollationWitInDiffPeriod(t *testing.T) {
 		node.CommitWithBlock()
 	}
 
-	fakeProposer.p2p.Broadcast(&tx)
+	fakeProposer.p2p.BroadcastShard(uint64(fakeProposer.shardID), &tx)
 
 	if _, err := waitForLogMsg(hook, "Collation created"); err != nil {
 		t.Fatal(err.Error())
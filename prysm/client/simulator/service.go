@@ -27,7 +27,7 @@ var log = logrus.WithField("prefix", "simulator")
 type Simulator struct {
 	config  *params.Config
 	client  *mainchain.SMCClient
-	p2p     *p2p.Server
+	p2p     p2p.P2P
 	shardID int
 	ctx     context.Context
 	cancel  context.CancelFunc
@@ -37,7 +37,7 @@ type Simulator struct {
 // NewSimulator creates a struct instance of a simulator service.
 // It will have access to config, a mainchain client, a p2p server,
 // and a shardID.
-func NewSimulator(config *params.Config, client *mainchain.SMCClient, p2p *p2p.Server, shardID int, delay time.Duration) (*Simulator, error) {
+func NewSimulator(config *params.Config, client *mainchain.SMCClient, p2p p2p.P2P, shardID int, delay time.Duration) (*Simulator, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Simulator{config, client, p2p, shardID, ctx, cancel, delay}, nil
 }
@@ -60,8 +60,8 @@ func (s *Simulator) Stop() error {
 }
 
 // simulateAttesterRequests simulates
-// requests for collation bodies that will be relayed to the appropriate proposer
-// by the p2p feed layer.
+// requests for collation bodies that are sent to the peers announcing the
+// period of the collation in their status.
 func (s *Simulator) simulateAttesterRequests(fetcher mainchain.RecordFetcher, reader mainchain.Reader, delayChan <-chan time.Time, done <-chan struct{}) {
 	for {
 		select {
@@ -88,12 +88,19 @@ func (s *Simulator) simulateAttesterRequests(fetcher mainchain.RecordFetcher, re
 				continue
 			}
 
-			if req != nil {
-				s.p2p.Broadcast(req)
-				log.Debug("Sent request for collation body via a shardp2p broadcast")
-			} else {
+			if req == nil {
 				log.Warn("Syncer generated nil CollationBodyRequest")
+				continue
+			}
+			peers := syncer.PeersWithPeriod(s.p2p.PeerStatuses(), req.ShardId, req.Period)
+			if len(peers) == 0 {
+				log.Debugf("No peer has the collation of period %d", req.Period)
+				continue
+			}
+			for _, peer := range peers {
+				s.p2p.Send(req, peer)
 			}
+			log.Debugf("Sent request for collation body to %d peers", len(peers))
 		}
 	}
 }
@@ -109,7 +116,7 @@ func (s *Simulator) broadcastTransactions(delayChan <-chan time.Time, done <-cha
 			return
 		case <-delayChan:
 			tx := createTestTx()
-			s.p2p.Broadcast(tx)
+			s.p2p.BroadcastShard(uint64(s.shardID), tx)
 			log.Debug("Transaction broadcasted")
 		}
 	}
@@ -17,6 +17,7 @@ import (
 	"github.com/prysmaticlabs/prysm/client/mainchain"
 	"github.com/prysmaticlabs/prysm/client/params"
 	"github.com/prysmaticlabs/prysm/client/types"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
 	"github.com/prysmaticlabs/prysm/shared"
 	"github.com/prysmaticlabs/prysm/shared/p2p"
 	"github.com/sirupsen/logrus"
@@ -97,7 +98,7 @@ func TestStartStop(t *testing.T) {
 	hook := logTest.NewGlobal()
 
 	shardID := 0
-	server, err := p2p.NewServer()
+	server, err := p2p.NewServer(&p2p.ServerConfig{})
 	if err != nil {
 		t.Fatalf("Unable to setup p2p server: %v", err)
 	}
@@ -136,7 +137,7 @@ func TestSimulateAttesterRequests_FaultyReader(t *testing.T) {
 	hook := logTest.NewGlobal()
 
 	shardID := 0
-	server, err := p2p.NewServer()
+	server, err := p2p.NewServer(&p2p.ServerConfig{})
 	if err != nil {
 		t.Fatalf("Unable to setup p2p server: %v", err)
 	}
@@ -174,7 +175,7 @@ func TestSimulateAttesterRequests_FaultyCaller(t *testing.T) {
 	hook := logTest.NewGlobal()
 
 	shardID := 0
-	server, err := p2p.NewServer()
+	server, err := p2p.NewServer(&p2p.ServerConfig{})
 	if err != nil {
 		t.Fatalf("Unable to setup p2p server: %v", err)
 	}
@@ -206,18 +207,24 @@ func TestSimulateAttesterRequests_FaultyCaller(t *testing.T) {
 }
 
 // This test checks the proper functioning of the simulateAttesterRequests goroutine
-// by listening to the requestSent channel which occurs after successful
-// construction and sending of a request via p2p.
+// by checking that the request is sent to the peers that announced the period
+// of the collation only.
 func TestSimulateAttesterRequests(t *testing.T) {
-	hook := logTest.NewGlobal()
-
 	shardID := 0
-	server, err := p2p.NewServer()
-	if err != nil {
-		t.Fatalf("Unable to setup p2p server: %v", err)
-	}
+	network := p2p.NewSimulatedNetwork(p2p.SimulatedNetworkConfig{})
+	defer network.Close()
+	node, holder, other := network.NewNode(), network.NewNode(), network.NewNode()
+	holder.SetStatusFunc(func() (*pb.Status, error) {
+		return &pb.Status{Shards: []*pb.ShardStatus{{ShardId: uint64(shardID), LatestPeriod: 0}}}, nil
+	})
+	other.SetStatusFunc(func() (*pb.Status, error) {
+		return &pb.Status{}, nil
+	})
+	holderRequests, otherRequests := make(chan p2p.Message, 1), make(chan p2p.Message, 1)
+	holder.Subscribe(pb.CollationBodyRequest{}, holderRequests)
+	other.Subscribe(pb.CollationBodyRequest{}, otherRequests)
 
-	simulator, err := NewSimulator(params.DefaultConfig(), &mainchain.SMCClient{}, server, shardID, 0)
+	simulator, err := NewSimulator(params.DefaultConfig(), &mainchain.SMCClient{}, node, shardID, 0)
 	if err != nil {
 		t.Fatalf("Unable to setup simulator service: %v", err)
 	}
@@ -236,14 +243,20 @@ func TestSimulateAttesterRequests(t *testing.T) {
 	delayChan <- time.Time{}
 	doneChan <- struct{}{}
 
-	msg := hook.Entries[1].Message
-	want := "Sent request for collation body via a shardp2p broadcast"
-	if msg != want {
-		t.Errorf("incorrect log, expected %s, got %s", want, msg)
+	select {
+	case msg := <-holderRequests:
+		if _, ok := msg.Data.(*pb.CollationBodyRequest); !ok || msg.Peer != node.Peer() {
+			t.Errorf("Expected collation body request from %s, got %T from %s", node.Peer(), msg.Data, msg.Peer)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the peer announcing the period to receive the request")
+	}
+	network.Settle()
+	if len(otherRequests) != 0 {
+		t.Error("Expected peers without the period not to receive the request")
 	}
 
 	exitRoutine <- true
-	hook.Reset()
 }
 
 func TestSimulateAttesterRequests_previousPeriod(t *testing.T) {
@@ -269,7 +282,7 @@ func TestSimulateAttesterRequests_previousPeriod(t *testing.T) {
 	}
 
 	shardID := 0
-	server, err := p2p.NewServer()
+	server, err := p2p.NewServer(&p2p.ServerConfig{})
 	if err != nil {
 		t.Fatalf("Unable to setup p2p server: %v", err)
 	}
@@ -310,7 +323,7 @@ func TestBroadcastTransactions(t *testing.T) {
 	hook := logTest.NewGlobal()
 
 	shardID := 0
-	server, err := p2p.NewServer()
+	server, err := p2p.NewServer(&p2p.ServerConfig{})
 	if err != nil {
 		t.Fatalf("Unable to setup p2p server: %v", err)
 	}
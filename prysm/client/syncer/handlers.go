@@ -81,3 +81,16 @@ func RequestCollationBody(fetcher mainchain.RecordFetcher, shardID *big.Int, per
 		ProposerAddress: record.Proposer.Bytes(),
 	}, nil
 }
+
+// PeersWithPeriod returns the peers whose status announces a latest
+// collation period of the shard at or after the given period. Only they can
+// serve the collation body of the period.
+func PeersWithPeriod(statuses []p2p.PeerStatus, shardID uint64, period uint64) []p2p.Peer {
+	var peers []p2p.Peer
+	for _, status := range statuses {
+		if latest, ok := status.LatestPeriod(shardID); ok && latest >= period {
+			peers = append(peers, status.Peer)
+		}
+	}
+	return peers
+}
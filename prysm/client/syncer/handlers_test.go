@@ -265,3 +265,16 @@ func TestConstructAttesterRequest(t *testing.T) {
 		t.Errorf("Proposer address from attester request incorrect. want: %d, got: %d", period.Uint64(), request.Period)
 	}
 }
+
+func TestPeersWithPeriod(t *testing.T) {
+	behind, ahead, otherShard := p2p.Peer{ID: "behind"}, p2p.Peer{ID: "ahead"}, p2p.Peer{ID: "other"}
+	statuses := []p2p.PeerStatus{
+		{Peer: behind, Status: &pb.Status{Shards: []*pb.ShardStatus{{ShardId: 1, LatestPeriod: 2}}}},
+		{Peer: ahead, Status: &pb.Status{Shards: []*pb.ShardStatus{{ShardId: 1, LatestPeriod: 3}}}},
+		{Peer: otherShard, Status: &pb.Status{Shards: []*pb.ShardStatus{{ShardId: 2, LatestPeriod: 5}}}},
+	}
+	peers := PeersWithPeriod(statuses, 1, 3)
+	if len(peers) != 1 || peers[0] != ahead {
+		t.Errorf("Expected only %s to have period 3 of shard 1, got %v", ahead, peers)
+	}
+}
@@ -21,23 +21,24 @@ import (
 // performing windback sync across nodes, handling reorgs, and synchronizing
 // items such as transactions and in future sharding iterations: state.
 type Syncer struct {
-	config       *params.Config
-	client       *mainchain.SMCClient
-	shardID      int
-	db           *database.DB
-	p2p          *p2p.Server
-	ctx          context.Context
-	cancel       context.CancelFunc
-	msgChan      chan p2p.Message
-	bodyRequests event.Subscription
+	config         *params.Config
+	client         *mainchain.SMCClient
+	shardID        int
+	db             *database.DB
+	p2p            p2p.P2P
+	ctx            context.Context
+	cancel         context.CancelFunc
+	msgChan        chan p2p.Message
+	bodyRequests   event.Subscription
+	directRequests event.Subscription
 }
 
 // NewSyncer creates a struct instance of a syncer service.
 // It will have access to config, a signer, a p2p server,
 // a shardChainDB, and a shardID.
-func NewSyncer(config *params.Config, client *mainchain.SMCClient, p2p *p2p.Server, db *database.DB, shardID int) (*Syncer, error) {
+func NewSyncer(config *params.Config, client *mainchain.SMCClient, p2p p2p.P2P, db *database.DB, shardID int) (*Syncer, error) {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Syncer{config, client, shardID, db, p2p, ctx, cancel, nil, nil}, nil
+	return &Syncer{config, client, shardID, db, p2p, ctx, cancel, nil, nil, nil}, nil
 }
 
 // Start the main loop for handling shard chain data requests.
@@ -45,12 +46,34 @@ func (s *Syncer) Start() {
 	log.Info("Starting sync service")
 
 	shard := types.NewShard(big.NewInt(int64(s.shardID)), s.db.DB())
+	s.p2p.SetStatusFunc(shardStatus(shard))
 
 	s.msgChan = make(chan p2p.Message, 100)
-	s.bodyRequests = s.p2p.Subscribe(pb.CollationBodyRequest{}, s.msgChan)
+	s.bodyRequests = s.p2p.SubscribeShard(uint64(s.shardID), pb.CollationBodyRequest{}, s.msgChan)
+	// Requests are also sent directly to the peers announcing the period.
+	s.directRequests = s.p2p.Subscribe(pb.CollationBodyRequest{}, s.msgChan)
 	go s.HandleCollationBodyRequests(shard, s.ctx.Done())
 }
 
+// shardStatus returns the status announced to peers, holding the latest
+// collation period of the shard.
+func shardStatus(shard *types.Shard) p2p.StatusFunc {
+	return func() (*pb.Status, error) {
+		status := &pb.Status{}
+		period, err := shard.LatestPeriod()
+		if err != nil {
+			return nil, fmt.Errorf("could not get latest period: %v", err)
+		}
+		if period != nil {
+			status.Shards = []*pb.ShardStatus{{
+				ShardId:      shard.ShardID().Uint64(),
+				LatestPeriod: period.Uint64(),
+			}}
+		}
+		return status, nil
+	}
+}
+
 // Stop the main loop.
 func (s *Syncer) Stop() error {
 	// Triggers a cancel call in the service's context which shuts down every goroutine
@@ -59,12 +82,13 @@ func (s *Syncer) Stop() error {
 	defer close(s.msgChan)
 	log.Info("Stopping sync service")
 	s.bodyRequests.Unsubscribe()
+	s.directRequests.Unsubscribe()
 	return nil
 }
 
 // HandleCollationBodyRequests subscribes to messages from the shardp2p
 // network and responds to a specific peer that requested the body using
-// the Send method exposed by the p2p server's API (implementing the p2p.Sender interface).
+// the Reply method exposed by the p2p server's API.
 func (s *Syncer) HandleCollationBodyRequests(collationFetcher types.CollationFetcher, done <-chan struct{}) {
 	for {
 		select {
@@ -86,8 +110,8 @@ func (s *Syncer) HandleCollationBodyRequests(collationFetcher types.CollationFet
 					continue
 				}
 
-				// Reply to that specific peer only.
-				s.p2p.Send(res, req.Peer)
+				// Reply to that specific request only.
+				s.p2p.Reply(req, res)
 				log.WithFields(logrus.Fields{
 					"headerHash": fmt.Sprintf("0x%v", common.Bytes2Hex(res.HeaderHash)),
 				}).Info("Responding to p2p collation request")
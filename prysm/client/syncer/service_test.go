@@ -1,10 +1,12 @@
 package syncer
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	gethTypes "github.com/ethereum/go-ethereum/core/types"
@@ -35,7 +37,7 @@ func TestStop(t *testing.T) {
 		t.Fatalf("unable to setup db: %v", err)
 	}
 	shardID := 0
-	server, err := p2p.NewServer()
+	server, err := p2p.NewServer(&p2p.ServerConfig{})
 	if err != nil {
 		t.Fatalf("Unable to setup p2p server: %v", err)
 	}
@@ -46,7 +48,8 @@ func TestStop(t *testing.T) {
 	}
 
 	syncer.msgChan = make(chan p2p.Message)
-	syncer.bodyRequests = server.Subscribe(pb.CollationBodyRequest{}, syncer.msgChan)
+	syncer.bodyRequests = server.SubscribeShard(uint64(syncer.shardID), pb.CollationBodyRequest{}, syncer.msgChan)
+	syncer.directRequests = server.Subscribe(pb.CollationBodyRequest{}, syncer.msgChan)
 
 	if err := syncer.Stop(); err != nil {
 		t.Fatalf("Unable to stop sync service: %v", err)
@@ -76,7 +79,7 @@ func TestHandleCollationBodyRequests(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unable to setup db: %v", err)
 	}
-	server, err := p2p.NewServer()
+	server, err := p2p.NewServer(&p2p.ServerConfig{})
 	if err != nil {
 		t.Fatalf("Unable to setup p2p server: %v", err)
 	}
@@ -104,7 +107,7 @@ func TestHandleCollationBodyRequests(t *testing.T) {
 	}
 
 	syncer.msgChan = make(chan p2p.Message)
-	syncer.bodyRequests = server.Subscribe(pb.CollationBodyRequest{}, syncer.msgChan)
+	syncer.bodyRequests = server.SubscribeShard(uint64(syncer.shardID), pb.CollationBodyRequest{}, syncer.msgChan)
 
 	doneChan := make(chan struct{})
 	exitRoutine := make(chan bool)
@@ -140,3 +143,115 @@ func TestHandleCollationBodyRequests(t *testing.T) {
 	}
 	hook.Reset()
 }
+
+func TestShardStatus(t *testing.T) {
+	config := &database.DBConfig{Name: "", DataDir: "", InMemory: true}
+	shardChainDB, err := database.NewDB(config)
+	if err != nil {
+		t.Fatalf("unable to setup db: %v", err)
+	}
+	shard := types.NewShard(big.NewInt(3), shardChainDB.DB())
+	status := shardStatus(shard)
+
+	st, err := status()
+	if err != nil {
+		t.Fatalf("Could not get status: %v", err)
+	}
+	if len(st.Shards) != 0 {
+		t.Errorf("Expected no shard status without canonical collations, got %v", st.Shards)
+	}
+
+	emptyHash := common.BytesToHash([]byte{})
+	proposerAddress := common.BytesToAddress([]byte{})
+	header := types.NewCollationHeader(big.NewInt(3), &emptyHash, big.NewInt(8), &proposerAddress, [32]byte{})
+	collation := types.NewCollation(header, []byte{1, 2, 3}, nil)
+	collation.CalculateChunkRoot()
+	if err := shard.SaveCollation(collation); err != nil {
+		t.Fatalf("Could not save collation: %v", err)
+	}
+	if err := shard.SetCanonical(collation.Header()); err != nil {
+		t.Fatalf("Could not set collation as canonical: %v", err)
+	}
+
+	st, err = status()
+	if err != nil {
+		t.Fatalf("Could not get status: %v", err)
+	}
+	if len(st.Shards) != 1 || st.Shards[0].ShardId != 3 || st.Shards[0].LatestPeriod != 8 {
+		t.Errorf("Expected latest period 8 of shard 3, got %v", st.Shards)
+	}
+}
+
+func TestCollationBodyRequestSimulatedNetwork(t *testing.T) {
+	network := p2p.NewSimulatedNetwork(p2p.SimulatedNetworkConfig{Latency: time.Millisecond})
+	defer network.Close()
+	serverNode, requester, otherShard := network.NewNode(), network.NewNode(), network.NewNode()
+
+	config := &database.DBConfig{Name: "", DataDir: "", InMemory: true}
+	shardChainDB, err := database.NewDB(config)
+	if err != nil {
+		t.Fatalf("unable to setup db: %v", err)
+	}
+	body := []byte{1, 2, 3, 4, 5}
+	shardID := big.NewInt(1)
+	chunkRoot := gethTypes.DeriveSha(types.Chunks(body))
+	period := big.NewInt(2)
+	proposerAddress := common.BytesToAddress([]byte{})
+	header := types.NewCollationHeader(shardID, &chunkRoot, period, &proposerAddress, [32]byte{})
+	shard := types.NewShard(shardID, shardChainDB.DB())
+	if err := shard.SaveCollation(types.NewCollation(header, body, nil)); err != nil {
+		t.Fatalf("Could not store collation in shardChainDB: %v", err)
+	}
+
+	syncer, err := NewSyncer(params.DefaultConfig(), &mainchain.SMCClient{}, serverNode, shardChainDB, int(shardID.Int64()))
+	if err != nil {
+		t.Fatalf("Unable to setup syncer service: %v", err)
+	}
+	syncer.Start()
+	defer syncer.Stop()
+
+	// Only the syncer of shard 1 receives the request broadcast to shard 1.
+	otherRequests := make(chan p2p.Message, 1)
+	otherShard.SubscribeShard(2, pb.CollationBodyRequest{}, otherRequests)
+	responses := make(chan p2p.Message, 1)
+	requester.Subscribe(pb.CollationBodyResponse{}, responses)
+	requester.BroadcastShard(shardID.Uint64(), &pb.CollationBodyRequest{
+		ChunkRoot:       chunkRoot.Bytes(),
+		ShardId:         shardID.Uint64(),
+		Period:          period.Uint64(),
+		ProposerAddress: proposerAddress.Bytes(),
+	})
+
+	select {
+	case msg := <-responses:
+		res := msg.Data.(*pb.CollationBodyResponse)
+		if msg.Peer != serverNode.Peer() {
+			t.Errorf("Expected response from %s, got %s", serverNode.Peer(), msg.Peer)
+		}
+		if !bytes.Equal(res.Body, body) {
+			t.Errorf("Expected body %v, got %v", body, res.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No response to collation body request")
+	}
+	network.Settle()
+	if len(otherRequests) != 0 {
+		t.Error("Expected nodes of other shards not to receive the request")
+	}
+
+	// Requests sent to the node directly are answered as well.
+	requester.Send(&pb.CollationBodyRequest{
+		ChunkRoot:       chunkRoot.Bytes(),
+		ShardId:         shardID.Uint64(),
+		Period:          period.Uint64(),
+		ProposerAddress: proposerAddress.Bytes(),
+	}, serverNode.Peer())
+	select {
+	case msg := <-responses:
+		if res := msg.Data.(*pb.CollationBodyResponse); !bytes.Equal(res.Body, body) {
+			t.Errorf("Expected body %v, got %v", body, res.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No response to direct collation body request")
+	}
+}
@@ -11,12 +11,12 @@ var log = logrus.WithField("prefix", "txpool")
 
 // TXPool handles a transaction pool for a sharded system.
 type TXPool struct {
-	p2p              *p2p.Server
+	p2p              p2p.P2P
 	transactionsFeed *event.Feed
 }
 
 // NewTXPool creates a new observer instance.
-func NewTXPool(p2p *p2p.Server) (*TXPool, error) {
+func NewTXPool(p2p p2p.P2P) (*TXPool, error) {
 	return &TXPool{p2p: p2p, transactionsFeed: new(event.Feed)}, nil
 }
 
@@ -128,6 +128,21 @@ func (s *Shard) CanonicalHeaderHash(shardID *big.Int, period *big.Int) (*common.
 	return &collationHash, nil
 }
 
+// LatestPeriod returns the latest period with a canonical collation in the
+// shardDB, or nil if no collation was set as canonical.
+func (s *Shard) LatestPeriod() (*big.Int, error) {
+	key := latestPeriodLookupKey(s.shardID)
+	has, err := s.shardDB.Has(key.Bytes())
+	if err != nil || !has {
+		return nil, err
+	}
+	encoded, err := s.shardDB.Get(key.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(encoded), nil
+}
+
 // CanonicalCollation fetches the collation set as canonical in the shardDB.
 func (s *Shard) CanonicalCollation(shardID *big.Int, period *big.Int) (*Collation, error) {
 	h, err := s.CanonicalHeaderHash(shardID, period)
@@ -243,7 +258,18 @@ func (s *Shard) SetCanonical(header *CollationHeader) error {
 	}
 	// sets the key to be the canonical collation lookup key and val as RLP encoded
 	// collation header.
-	return s.shardDB.Put(key.Bytes(), encoded)
+	if err := s.shardDB.Put(key.Bytes(), encoded); err != nil {
+		return err
+	}
+
+	latest, err := s.LatestPeriod()
+	if err != nil {
+		return fmt.Errorf("cannot get latest period: %v", err)
+	}
+	if latest != nil && latest.Cmp(dbHeader.Period()) >= 0 {
+		return nil
+	}
+	return s.shardDB.Put(latestPeriodLookupKey(s.shardID).Bytes(), dbHeader.Period().Bytes())
 }
 
 // dataAvailabilityLookupKey formats a string that will become a lookup
@@ -253,6 +279,13 @@ func dataAvailabilityLookupKey(chunkRoot *common.Hash) common.Hash {
 	return common.BytesToHash([]byte(key))
 }
 
+// latestPeriodLookupKey formats a string that will become the lookup key of
+// the latest period with a canonical collation of the shard in the shardDB.
+func latestPeriodLookupKey(shardID *big.Int) common.Hash {
+	key := fmt.Sprintf("latest-period-lookup:shardID=%s", shardID)
+	return common.BytesToHash([]byte(key))
+}
+
 // canonicalCollationLookupKey formats a string that will become a lookup key
 // in the shardDB that takes into account the shardID and the period
 // of the shard for ease of use.
@@ -308,6 +308,42 @@ func TestShard_SetCanonical(t *testing.T) {
 	}
 }
 
+func TestShard_LatestPeriod(t *testing.T) {
+	shardID := big.NewInt(1)
+	shardDB := sharedDB.NewKVStore()
+	shard := NewShard(shardID, shardDB)
+
+	latest, err := shard.LatestPeriod()
+	if err != nil {
+		t.Fatalf("failed to get latest period: %v", err)
+	}
+	if latest != nil {
+		t.Errorf("expected no latest period before any canonical collation, got %v", latest)
+	}
+
+	proposerAddress := common.BytesToAddress([]byte{})
+	for _, period := range []int64{2, 5, 3} {
+		emptyHash := common.BytesToHash([]byte{})
+		header := NewCollationHeader(shardID, &emptyHash, big.NewInt(period), &proposerAddress, [32]byte{})
+		collation := &Collation{header: header, body: []byte{byte(period)}}
+		collation.CalculateChunkRoot()
+		if err := shard.SaveCollation(collation); err != nil {
+			t.Fatalf("failed to save collation to shardDB: %v", err)
+		}
+		if err := shard.SetCanonical(collation.Header()); err != nil {
+			t.Fatalf("failed to set collation as canonical: %v", err)
+		}
+	}
+
+	latest, err = shard.LatestPeriod()
+	if err != nil {
+		t.Fatalf("failed to get latest period: %v", err)
+	}
+	if latest == nil || latest.Int64() != 5 {
+		t.Errorf("latest period should be 5, got %v", latest)
+	}
+}
+
 func TestShard_BodyByChunkRoot(t *testing.T) {
 	body := []byte{1, 2, 3, 4, 5}
 	shardID := big.NewInt(1)
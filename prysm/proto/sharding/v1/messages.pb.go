@@ -22,39 +22,51 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 type Topic int32
 
 const (
-	Topic_UNKNOWN                    Topic = 0
-	Topic_COLLATION_BODY_REQUEST     Topic = 1
-	Topic_COLLATION_BODY_RESPONSE    Topic = 2
-	Topic_TRANSACTIONS               Topic = 3
-	Topic_BEACON_BLOCK_HASH_ANNOUNCE Topic = 4
-	Topic_BEACON_BLOCK_REQUEST       Topic = 5
-	Topic_BEACON_BLOCK_RESPONSE      Topic = 6
+	Topic_UNKNOWN                     Topic = 0
+	Topic_COLLATION_BODY_REQUEST      Topic = 1
+	Topic_COLLATION_BODY_RESPONSE     Topic = 2
+	Topic_TRANSACTIONS                Topic = 3
+	Topic_BEACON_BLOCK_HASH_ANNOUNCE  Topic = 4
+	Topic_BEACON_BLOCK_REQUEST        Topic = 5
+	Topic_BEACON_BLOCK_RESPONSE       Topic = 6
+	Topic_CHAIN_HEAD_REQUEST          Topic = 7
+	Topic_CHAIN_HEAD_RESPONSE         Topic = 8
+	Topic_BEACON_BLOCK_RANGE_REQUEST  Topic = 9
+	Topic_BEACON_BLOCK_RANGE_RESPONSE Topic = 10
 )
 
 var Topic_name = map[int32]string{
-	0: "UNKNOWN",
-	1: "COLLATION_BODY_REQUEST",
-	2: "COLLATION_BODY_RESPONSE",
-	3: "TRANSACTIONS",
-	4: "BEACON_BLOCK_HASH_ANNOUNCE",
-	5: "BEACON_BLOCK_REQUEST",
-	6: "BEACON_BLOCK_RESPONSE",
+	0:  "UNKNOWN",
+	1:  "COLLATION_BODY_REQUEST",
+	2:  "COLLATION_BODY_RESPONSE",
+	3:  "TRANSACTIONS",
+	4:  "BEACON_BLOCK_HASH_ANNOUNCE",
+	5:  "BEACON_BLOCK_REQUEST",
+	6:  "BEACON_BLOCK_RESPONSE",
+	7:  "CHAIN_HEAD_REQUEST",
+	8:  "CHAIN_HEAD_RESPONSE",
+	9:  "BEACON_BLOCK_RANGE_REQUEST",
+	10: "BEACON_BLOCK_RANGE_RESPONSE",
 }
 var Topic_value = map[string]int32{
-	"UNKNOWN":                    0,
-	"COLLATION_BODY_REQUEST":     1,
-	"COLLATION_BODY_RESPONSE":    2,
-	"TRANSACTIONS":               3,
-	"BEACON_BLOCK_HASH_ANNOUNCE": 4,
-	"BEACON_BLOCK_REQUEST":       5,
-	"BEACON_BLOCK_RESPONSE":      6,
+	"UNKNOWN":                     0,
+	"COLLATION_BODY_REQUEST":      1,
+	"COLLATION_BODY_RESPONSE":     2,
+	"TRANSACTIONS":                3,
+	"BEACON_BLOCK_HASH_ANNOUNCE":  4,
+	"BEACON_BLOCK_REQUEST":        5,
+	"BEACON_BLOCK_RESPONSE":       6,
+	"CHAIN_HEAD_REQUEST":          7,
+	"CHAIN_HEAD_RESPONSE":         8,
+	"BEACON_BLOCK_RANGE_REQUEST":  9,
+	"BEACON_BLOCK_RANGE_RESPONSE": 10,
 }
 
 func (x Topic) String() string {
 	return proto.EnumName(Topic_name, int32(x))
 }
 func (Topic) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ebbb8b6f046bbe65, []int{0}
+	return fileDescriptor_messages_c846aa9d98863956, []int{0}
 }
 
 type BeaconBlockHashAnnounce struct {
@@ -68,7 +80,7 @@ func (m *BeaconBlockHashAnnounce) Reset()         { *m = BeaconBlockHashAnnounce
 func (m *BeaconBlockHashAnnounce) String() string { return proto.CompactTextString(m) }
 func (*BeaconBlockHashAnnounce) ProtoMessage()    {}
 func (*BeaconBlockHashAnnounce) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ebbb8b6f046bbe65, []int{0}
+	return fileDescriptor_messages_c846aa9d98863956, []int{0}
 }
 func (m *BeaconBlockHashAnnounce) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BeaconBlockHashAnnounce.Unmarshal(m, b)
@@ -106,7 +118,7 @@ func (m *BeaconBlockRequest) Reset()         { *m = BeaconBlockRequest{} }
 func (m *BeaconBlockRequest) String() string { return proto.CompactTextString(m) }
 func (*BeaconBlockRequest) ProtoMessage()    {}
 func (*BeaconBlockRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ebbb8b6f046bbe65, []int{1}
+	return fileDescriptor_messages_c846aa9d98863956, []int{1}
 }
 func (m *BeaconBlockRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BeaconBlockRequest.Unmarshal(m, b)
@@ -138,7 +150,7 @@ type BeaconBlockResponse struct {
 	SlotNumber              uint64               `protobuf:"varint,2,opt,name=slot_number,json=slotNumber,proto3" json:"slot_number,omitempty"`
 	RandaoReveal            []byte               `protobuf:"bytes,3,opt,name=randao_reveal,json=randaoReveal,proto3" json:"randao_reveal,omitempty"`
 	AttestationBitmask      []byte               `protobuf:"bytes,4,opt,name=attestation_bitmask,json=attestationBitmask,proto3" json:"attestation_bitmask,omitempty"`
-	AttestationAggregateSig []uint32             `protobuf:"varint,5,rep,packed,name=attestation_aggregate_sig,json=attestationAggregateSig,proto3" json:"attestation_aggregate_sig,omitempty"`
+	AttestationAggregateSig []byte               `protobuf:"bytes,11,opt,name=attestation_aggregate_sig,json=attestationAggregateSig,proto3" json:"attestation_aggregate_sig,omitempty"`
 	ShardAggregateVotes     []*AggregateVote     `protobuf:"bytes,6,rep,name=shard_aggregate_votes,json=shardAggregateVotes,proto3" json:"shard_aggregate_votes,omitempty"`
 	MainChainRef            []byte               `protobuf:"bytes,7,opt,name=main_chain_ref,json=mainChainRef,proto3" json:"main_chain_ref,omitempty"`
 	ActiveStateHash         []byte               `protobuf:"bytes,8,opt,name=active_state_hash,json=activeStateHash,proto3" json:"active_state_hash,omitempty"`
@@ -153,7 +165,7 @@ func (m *BeaconBlockResponse) Reset()         { *m = BeaconBlockResponse{} }
 func (m *BeaconBlockResponse) String() string { return proto.CompactTextString(m) }
 func (*BeaconBlockResponse) ProtoMessage()    {}
 func (*BeaconBlockResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ebbb8b6f046bbe65, []int{2}
+	return fileDescriptor_messages_c846aa9d98863956, []int{2}
 }
 func (m *BeaconBlockResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BeaconBlockResponse.Unmarshal(m, b)
@@ -201,7 +213,7 @@ func (m *BeaconBlockResponse) GetAttestationBitmask() []byte {
 	return nil
 }
 
-func (m *BeaconBlockResponse) GetAttestationAggregateSig() []uint32 {
+func (m *BeaconBlockResponse) GetAttestationAggregateSig() []byte {
 	if m != nil {
 		return m.AttestationAggregateSig
 	}
@@ -243,11 +255,187 @@ func (m *BeaconBlockResponse) GetTimestamp() *timestamp.Timestamp {
 	return nil
 }
 
+type ChainHeadRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChainHeadRequest) Reset()         { *m = ChainHeadRequest{} }
+func (m *ChainHeadRequest) String() string { return proto.CompactTextString(m) }
+func (*ChainHeadRequest) ProtoMessage()    {}
+func (*ChainHeadRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{3}
+}
+func (m *ChainHeadRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChainHeadRequest.Unmarshal(m, b)
+}
+func (m *ChainHeadRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChainHeadRequest.Marshal(b, m, deterministic)
+}
+func (dst *ChainHeadRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChainHeadRequest.Merge(dst, src)
+}
+func (m *ChainHeadRequest) XXX_Size() int {
+	return xxx_messageInfo_ChainHeadRequest.Size(m)
+}
+func (m *ChainHeadRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChainHeadRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChainHeadRequest proto.InternalMessageInfo
+
+type ChainHeadResponse struct {
+	Slot                 uint64   `protobuf:"varint,1,opt,name=slot,proto3" json:"slot,omitempty"`
+	Hash                 []byte   `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChainHeadResponse) Reset()         { *m = ChainHeadResponse{} }
+func (m *ChainHeadResponse) String() string { return proto.CompactTextString(m) }
+func (*ChainHeadResponse) ProtoMessage()    {}
+func (*ChainHeadResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{4}
+}
+func (m *ChainHeadResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChainHeadResponse.Unmarshal(m, b)
+}
+func (m *ChainHeadResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChainHeadResponse.Marshal(b, m, deterministic)
+}
+func (dst *ChainHeadResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChainHeadResponse.Merge(dst, src)
+}
+func (m *ChainHeadResponse) XXX_Size() int {
+	return xxx_messageInfo_ChainHeadResponse.Size(m)
+}
+func (m *ChainHeadResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChainHeadResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChainHeadResponse proto.InternalMessageInfo
+
+func (m *ChainHeadResponse) GetSlot() uint64 {
+	if m != nil {
+		return m.Slot
+	}
+	return 0
+}
+
+func (m *ChainHeadResponse) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+type BeaconBlockRangeRequest struct {
+	StartSlot            uint64   `protobuf:"varint,1,opt,name=start_slot,json=startSlot,proto3" json:"start_slot,omitempty"`
+	Count                uint64   `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BeaconBlockRangeRequest) Reset()         { *m = BeaconBlockRangeRequest{} }
+func (m *BeaconBlockRangeRequest) String() string { return proto.CompactTextString(m) }
+func (*BeaconBlockRangeRequest) ProtoMessage()    {}
+func (*BeaconBlockRangeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{5}
+}
+func (m *BeaconBlockRangeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BeaconBlockRangeRequest.Unmarshal(m, b)
+}
+func (m *BeaconBlockRangeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BeaconBlockRangeRequest.Marshal(b, m, deterministic)
+}
+func (dst *BeaconBlockRangeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BeaconBlockRangeRequest.Merge(dst, src)
+}
+func (m *BeaconBlockRangeRequest) XXX_Size() int {
+	return xxx_messageInfo_BeaconBlockRangeRequest.Size(m)
+}
+func (m *BeaconBlockRangeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BeaconBlockRangeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BeaconBlockRangeRequest proto.InternalMessageInfo
+
+func (m *BeaconBlockRangeRequest) GetStartSlot() uint64 {
+	if m != nil {
+		return m.StartSlot
+	}
+	return 0
+}
+
+func (m *BeaconBlockRangeRequest) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+type BeaconBlockRangeResponse struct {
+	StartSlot            uint64                 `protobuf:"varint,1,opt,name=start_slot,json=startSlot,proto3" json:"start_slot,omitempty"`
+	Count                uint64                 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Blocks               []*BeaconBlockResponse `protobuf:"bytes,3,rep,name=blocks,proto3" json:"blocks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *BeaconBlockRangeResponse) Reset()         { *m = BeaconBlockRangeResponse{} }
+func (m *BeaconBlockRangeResponse) String() string { return proto.CompactTextString(m) }
+func (*BeaconBlockRangeResponse) ProtoMessage()    {}
+func (*BeaconBlockRangeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{6}
+}
+func (m *BeaconBlockRangeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BeaconBlockRangeResponse.Unmarshal(m, b)
+}
+func (m *BeaconBlockRangeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BeaconBlockRangeResponse.Marshal(b, m, deterministic)
+}
+func (dst *BeaconBlockRangeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BeaconBlockRangeResponse.Merge(dst, src)
+}
+func (m *BeaconBlockRangeResponse) XXX_Size() int {
+	return xxx_messageInfo_BeaconBlockRangeResponse.Size(m)
+}
+func (m *BeaconBlockRangeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BeaconBlockRangeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BeaconBlockRangeResponse proto.InternalMessageInfo
+
+func (m *BeaconBlockRangeResponse) GetStartSlot() uint64 {
+	if m != nil {
+		return m.StartSlot
+	}
+	return 0
+}
+
+func (m *BeaconBlockRangeResponse) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *BeaconBlockRangeResponse) GetBlocks() []*BeaconBlockResponse {
+	if m != nil {
+		return m.Blocks
+	}
+	return nil
+}
+
 type AggregateVote struct {
 	ShardId              uint32   `protobuf:"varint,1,opt,name=shard_id,json=shardId,proto3" json:"shard_id,omitempty"`
 	ShardBlockHash       []byte   `protobuf:"bytes,2,opt,name=shard_block_hash,json=shardBlockHash,proto3" json:"shard_block_hash,omitempty"`
 	SignerBitmask        []byte   `protobuf:"bytes,3,opt,name=signer_bitmask,json=signerBitmask,proto3" json:"signer_bitmask,omitempty"`
-	AggregateSig         []uint32 `protobuf:"varint,4,rep,packed,name=aggregate_sig,json=aggregateSig,proto3" json:"aggregate_sig,omitempty"`
+	AggregateSig         []byte   `protobuf:"bytes,5,opt,name=aggregate_sig,json=aggregateSig,proto3" json:"aggregate_sig,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -257,7 +445,7 @@ func (m *AggregateVote) Reset()         { *m = AggregateVote{} }
 func (m *AggregateVote) String() string { return proto.CompactTextString(m) }
 func (*AggregateVote) ProtoMessage()    {}
 func (*AggregateVote) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ebbb8b6f046bbe65, []int{3}
+	return fileDescriptor_messages_c846aa9d98863956, []int{7}
 }
 func (m *AggregateVote) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_AggregateVote.Unmarshal(m, b)
@@ -298,7 +486,7 @@ func (m *AggregateVote) GetSignerBitmask() []byte {
 	return nil
 }
 
-func (m *AggregateVote) GetAggregateSig() []uint32 {
+func (m *AggregateVote) GetAggregateSig() []byte {
 	if m != nil {
 		return m.AggregateSig
 	}
@@ -320,7 +508,7 @@ func (m *CollationBodyRequest) Reset()         { *m = CollationBodyRequest{} }
 func (m *CollationBodyRequest) String() string { return proto.CompactTextString(m) }
 func (*CollationBodyRequest) ProtoMessage()    {}
 func (*CollationBodyRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ebbb8b6f046bbe65, []int{4}
+	return fileDescriptor_messages_c846aa9d98863956, []int{8}
 }
 func (m *CollationBodyRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CollationBodyRequest.Unmarshal(m, b)
@@ -387,7 +575,7 @@ func (m *CollationBodyResponse) Reset()         { *m = CollationBodyResponse{} }
 func (m *CollationBodyResponse) String() string { return proto.CompactTextString(m) }
 func (*CollationBodyResponse) ProtoMessage()    {}
 func (*CollationBodyResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ebbb8b6f046bbe65, []int{5}
+	return fileDescriptor_messages_c846aa9d98863956, []int{9}
 }
 func (m *CollationBodyResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CollationBodyResponse.Unmarshal(m, b)
@@ -438,7 +626,7 @@ func (m *Transaction) Reset()         { *m = Transaction{} }
 func (m *Transaction) String() string { return proto.CompactTextString(m) }
 func (*Transaction) ProtoMessage()    {}
 func (*Transaction) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ebbb8b6f046bbe65, []int{6}
+	return fileDescriptor_messages_c846aa9d98863956, []int{10}
 }
 func (m *Transaction) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Transaction.Unmarshal(m, b)
@@ -520,7 +708,7 @@ func (m *Signature) Reset()         { *m = Signature{} }
 func (m *Signature) String() string { return proto.CompactTextString(m) }
 func (*Signature) ProtoMessage()    {}
 func (*Signature) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ebbb8b6f046bbe65, []int{7}
+	return fileDescriptor_messages_c846aa9d98863956, []int{11}
 }
 func (m *Signature) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Signature.Unmarshal(m, b)
@@ -561,75 +749,557 @@ func (m *Signature) GetS() uint64 {
 	return 0
 }
 
+type Envelope struct {
+	Topic                Topic    `protobuf:"varint,1,opt,name=topic,proto3,enum=ethereum.messages.v1.Topic" json:"topic,omitempty"`
+	Payload              []byte   `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	RequestId            uint64   `protobuf:"varint,3,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Response             bool     `protobuf:"varint,4,opt,name=response,proto3" json:"response,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Envelope) Reset()         { *m = Envelope{} }
+func (m *Envelope) String() string { return proto.CompactTextString(m) }
+func (*Envelope) ProtoMessage()    {}
+func (*Envelope) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{12}
+}
+func (m *Envelope) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Envelope.Unmarshal(m, b)
+}
+func (m *Envelope) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Envelope.Marshal(b, m, deterministic)
+}
+func (dst *Envelope) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Envelope.Merge(dst, src)
+}
+func (m *Envelope) XXX_Size() int {
+	return xxx_messageInfo_Envelope.Size(m)
+}
+func (m *Envelope) XXX_DiscardUnknown() {
+	xxx_messageInfo_Envelope.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Envelope proto.InternalMessageInfo
+
+func (m *Envelope) GetTopic() Topic {
+	if m != nil {
+		return m.Topic
+	}
+	return Topic_UNKNOWN
+}
+
+func (m *Envelope) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *Envelope) GetRequestId() uint64 {
+	if m != nil {
+		return m.RequestId
+	}
+	return 0
+}
+
+func (m *Envelope) GetResponse() bool {
+	if m != nil {
+		return m.Response
+	}
+	return false
+}
+
+type FindPeersRequest struct {
+	Target               []byte   `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FindPeersRequest) Reset()         { *m = FindPeersRequest{} }
+func (m *FindPeersRequest) String() string { return proto.CompactTextString(m) }
+func (*FindPeersRequest) ProtoMessage()    {}
+func (*FindPeersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{13}
+}
+func (m *FindPeersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FindPeersRequest.Unmarshal(m, b)
+}
+func (m *FindPeersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FindPeersRequest.Marshal(b, m, deterministic)
+}
+func (dst *FindPeersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FindPeersRequest.Merge(dst, src)
+}
+func (m *FindPeersRequest) XXX_Size() int {
+	return xxx_messageInfo_FindPeersRequest.Size(m)
+}
+func (m *FindPeersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FindPeersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FindPeersRequest proto.InternalMessageInfo
+
+func (m *FindPeersRequest) GetTarget() []byte {
+	if m != nil {
+		return m.Target
+	}
+	return nil
+}
+
+type FindPeersResponse struct {
+	Peers                []*PeerAddress `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *FindPeersResponse) Reset()         { *m = FindPeersResponse{} }
+func (m *FindPeersResponse) String() string { return proto.CompactTextString(m) }
+func (*FindPeersResponse) ProtoMessage()    {}
+func (*FindPeersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{14}
+}
+func (m *FindPeersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FindPeersResponse.Unmarshal(m, b)
+}
+func (m *FindPeersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FindPeersResponse.Marshal(b, m, deterministic)
+}
+func (dst *FindPeersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FindPeersResponse.Merge(dst, src)
+}
+func (m *FindPeersResponse) XXX_Size() int {
+	return xxx_messageInfo_FindPeersResponse.Size(m)
+}
+func (m *FindPeersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_FindPeersResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FindPeersResponse proto.InternalMessageInfo
+
+func (m *FindPeersResponse) GetPeers() []*PeerAddress {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
+type PeerAddress struct {
+	Id                   []byte   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Addrs                [][]byte `protobuf:"bytes,2,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PeerAddress) Reset()         { *m = PeerAddress{} }
+func (m *PeerAddress) String() string { return proto.CompactTextString(m) }
+func (*PeerAddress) ProtoMessage()    {}
+func (*PeerAddress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{15}
+}
+func (m *PeerAddress) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PeerAddress.Unmarshal(m, b)
+}
+func (m *PeerAddress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PeerAddress.Marshal(b, m, deterministic)
+}
+func (dst *PeerAddress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PeerAddress.Merge(dst, src)
+}
+func (m *PeerAddress) XXX_Size() int {
+	return xxx_messageInfo_PeerAddress.Size(m)
+}
+func (m *PeerAddress) XXX_DiscardUnknown() {
+	xxx_messageInfo_PeerAddress.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PeerAddress proto.InternalMessageInfo
+
+func (m *PeerAddress) GetId() []byte {
+	if m != nil {
+		return m.Id
+	}
+	return nil
+}
+
+func (m *PeerAddress) GetAddrs() [][]byte {
+	if m != nil {
+		return m.Addrs
+	}
+	return nil
+}
+
+type Handshake struct {
+	NetworkId            uint64   `protobuf:"varint,1,opt,name=network_id,json=networkId,proto3" json:"network_id,omitempty"`
+	HeadSlot             uint64   `protobuf:"varint,2,opt,name=head_slot,json=headSlot,proto3" json:"head_slot,omitempty"`
+	HeadHash             []byte   `protobuf:"bytes,3,opt,name=head_hash,json=headHash,proto3" json:"head_hash,omitempty"`
+	Shards               []uint64 `protobuf:"varint,4,rep,packed,name=shards,proto3" json:"shards,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Handshake) Reset()         { *m = Handshake{} }
+func (m *Handshake) String() string { return proto.CompactTextString(m) }
+func (*Handshake) ProtoMessage()    {}
+func (*Handshake) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{16}
+}
+func (m *Handshake) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Handshake.Unmarshal(m, b)
+}
+func (m *Handshake) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Handshake.Marshal(b, m, deterministic)
+}
+func (dst *Handshake) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Handshake.Merge(dst, src)
+}
+func (m *Handshake) XXX_Size() int {
+	return xxx_messageInfo_Handshake.Size(m)
+}
+func (m *Handshake) XXX_DiscardUnknown() {
+	xxx_messageInfo_Handshake.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Handshake proto.InternalMessageInfo
+
+func (m *Handshake) GetNetworkId() uint64 {
+	if m != nil {
+		return m.NetworkId
+	}
+	return 0
+}
+
+func (m *Handshake) GetHeadSlot() uint64 {
+	if m != nil {
+		return m.HeadSlot
+	}
+	return 0
+}
+
+func (m *Handshake) GetHeadHash() []byte {
+	if m != nil {
+		return m.HeadHash
+	}
+	return nil
+}
+
+func (m *Handshake) GetShards() []uint64 {
+	if m != nil {
+		return m.Shards
+	}
+	return nil
+}
+
+type Status struct {
+	HeadSlot             uint64         `protobuf:"varint,1,opt,name=head_slot,json=headSlot,proto3" json:"head_slot,omitempty"`
+	HeadHash             []byte         `protobuf:"bytes,2,opt,name=head_hash,json=headHash,proto3" json:"head_hash,omitempty"`
+	FinalizedEpoch       uint64         `protobuf:"varint,3,opt,name=finalized_epoch,json=finalizedEpoch,proto3" json:"finalized_epoch,omitempty"`
+	Shards               []*ShardStatus `protobuf:"bytes,4,rep,name=shards,proto3" json:"shards,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *Status) Reset()         { *m = Status{} }
+func (m *Status) String() string { return proto.CompactTextString(m) }
+func (*Status) ProtoMessage()    {}
+func (*Status) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{17}
+}
+func (m *Status) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Status.Unmarshal(m, b)
+}
+func (m *Status) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Status.Marshal(b, m, deterministic)
+}
+func (dst *Status) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Status.Merge(dst, src)
+}
+func (m *Status) XXX_Size() int {
+	return xxx_messageInfo_Status.Size(m)
+}
+func (m *Status) XXX_DiscardUnknown() {
+	xxx_messageInfo_Status.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Status proto.InternalMessageInfo
+
+func (m *Status) GetHeadSlot() uint64 {
+	if m != nil {
+		return m.HeadSlot
+	}
+	return 0
+}
+
+func (m *Status) GetHeadHash() []byte {
+	if m != nil {
+		return m.HeadHash
+	}
+	return nil
+}
+
+func (m *Status) GetFinalizedEpoch() uint64 {
+	if m != nil {
+		return m.FinalizedEpoch
+	}
+	return 0
+}
+
+func (m *Status) GetShards() []*ShardStatus {
+	if m != nil {
+		return m.Shards
+	}
+	return nil
+}
+
+type ShardStatus struct {
+	ShardId              uint64   `protobuf:"varint,1,opt,name=shard_id,json=shardId,proto3" json:"shard_id,omitempty"`
+	LatestPeriod         uint64   `protobuf:"varint,2,opt,name=latest_period,json=latestPeriod,proto3" json:"latest_period,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ShardStatus) Reset()         { *m = ShardStatus{} }
+func (m *ShardStatus) String() string { return proto.CompactTextString(m) }
+func (*ShardStatus) ProtoMessage()    {}
+func (*ShardStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{18}
+}
+func (m *ShardStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ShardStatus.Unmarshal(m, b)
+}
+func (m *ShardStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ShardStatus.Marshal(b, m, deterministic)
+}
+func (dst *ShardStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ShardStatus.Merge(dst, src)
+}
+func (m *ShardStatus) XXX_Size() int {
+	return xxx_messageInfo_ShardStatus.Size(m)
+}
+func (m *ShardStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_ShardStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ShardStatus proto.InternalMessageInfo
+
+func (m *ShardStatus) GetShardId() uint64 {
+	if m != nil {
+		return m.ShardId
+	}
+	return 0
+}
+
+func (m *ShardStatus) GetLatestPeriod() uint64 {
+	if m != nil {
+		return m.LatestPeriod
+	}
+	return 0
+}
+
+type MessageRecord struct {
+	Timestamp            int64    `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Outbound             bool     `protobuf:"varint,2,opt,name=outbound,proto3" json:"outbound,omitempty"`
+	Topic                string   `protobuf:"bytes,3,opt,name=topic,proto3" json:"topic,omitempty"`
+	Peer                 string   `protobuf:"bytes,4,opt,name=peer,proto3" json:"peer,omitempty"`
+	Size                 uint64   `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	Type                 string   `protobuf:"bytes,6,opt,name=type,proto3" json:"type,omitempty"`
+	Data                 []byte   `protobuf:"bytes,7,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MessageRecord) Reset()         { *m = MessageRecord{} }
+func (m *MessageRecord) String() string { return proto.CompactTextString(m) }
+func (*MessageRecord) ProtoMessage()    {}
+func (*MessageRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c846aa9d98863956, []int{19}
+}
+func (m *MessageRecord) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MessageRecord.Unmarshal(m, b)
+}
+func (m *MessageRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MessageRecord.Marshal(b, m, deterministic)
+}
+func (dst *MessageRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MessageRecord.Merge(dst, src)
+}
+func (m *MessageRecord) XXX_Size() int {
+	return xxx_messageInfo_MessageRecord.Size(m)
+}
+func (m *MessageRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_MessageRecord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MessageRecord proto.InternalMessageInfo
+
+func (m *MessageRecord) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *MessageRecord) GetOutbound() bool {
+	if m != nil {
+		return m.Outbound
+	}
+	return false
+}
+
+func (m *MessageRecord) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *MessageRecord) GetPeer() string {
+	if m != nil {
+		return m.Peer
+	}
+	return ""
+}
+
+func (m *MessageRecord) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *MessageRecord) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *MessageRecord) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*BeaconBlockHashAnnounce)(nil), "ethereum.messages.v1.BeaconBlockHashAnnounce")
 	proto.RegisterType((*BeaconBlockRequest)(nil), "ethereum.messages.v1.BeaconBlockRequest")
 	proto.RegisterType((*BeaconBlockResponse)(nil), "ethereum.messages.v1.BeaconBlockResponse")
+	proto.RegisterType((*ChainHeadRequest)(nil), "ethereum.messages.v1.ChainHeadRequest")
+	proto.RegisterType((*ChainHeadResponse)(nil), "ethereum.messages.v1.ChainHeadResponse")
+	proto.RegisterType((*BeaconBlockRangeRequest)(nil), "ethereum.messages.v1.BeaconBlockRangeRequest")
+	proto.RegisterType((*BeaconBlockRangeResponse)(nil), "ethereum.messages.v1.BeaconBlockRangeResponse")
 	proto.RegisterType((*AggregateVote)(nil), "ethereum.messages.v1.AggregateVote")
 	proto.RegisterType((*CollationBodyRequest)(nil), "ethereum.messages.v1.CollationBodyRequest")
 	proto.RegisterType((*CollationBodyResponse)(nil), "ethereum.messages.v1.CollationBodyResponse")
 	proto.RegisterType((*Transaction)(nil), "ethereum.messages.v1.Transaction")
 	proto.RegisterType((*Signature)(nil), "ethereum.messages.v1.Signature")
+	proto.RegisterType((*Envelope)(nil), "ethereum.messages.v1.Envelope")
+	proto.RegisterType((*FindPeersRequest)(nil), "ethereum.messages.v1.FindPeersRequest")
+	proto.RegisterType((*FindPeersResponse)(nil), "ethereum.messages.v1.FindPeersResponse")
+	proto.RegisterType((*PeerAddress)(nil), "ethereum.messages.v1.PeerAddress")
+	proto.RegisterType((*Handshake)(nil), "ethereum.messages.v1.Handshake")
+	proto.RegisterType((*Status)(nil), "ethereum.messages.v1.Status")
+	proto.RegisterType((*ShardStatus)(nil), "ethereum.messages.v1.ShardStatus")
+	proto.RegisterType((*MessageRecord)(nil), "ethereum.messages.v1.MessageRecord")
 	proto.RegisterEnum("ethereum.messages.v1.Topic", Topic_name, Topic_value)
 }
 
 func init() {
-	proto.RegisterFile("proto/sharding/v1/messages.proto", fileDescriptor_messages_ebbb8b6f046bbe65)
-}
-
-var fileDescriptor_messages_ebbb8b6f046bbe65 = []byte{
-	// 839 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x54, 0xed, 0x6e, 0xe2, 0x46,
-	0x14, 0xad, 0x13, 0x20, 0xe1, 0x02, 0x59, 0x3a, 0x49, 0x36, 0x4e, 0xb6, 0x6d, 0x10, 0x69, 0x25,
-	0xba, 0x52, 0x41, 0xbb, 0x55, 0xab, 0xaa, 0x52, 0x7f, 0x00, 0x45, 0xca, 0x6a, 0x91, 0xd9, 0x1a,
-	0xd2, 0x55, 0x7f, 0x59, 0x83, 0x3d, 0x31, 0xa3, 0x98, 0x19, 0x77, 0x66, 0x8c, 0x94, 0x3e, 0x40,
-	0x9f, 0xa3, 0xaf, 0x50, 0xa9, 0xcf, 0xd4, 0xe7, 0xa8, 0x66, 0xc6, 0x06, 0x93, 0xe6, 0x0f, 0xf2,
-	0x3d, 0xe7, 0xdc, 0x99, 0xfb, 0x71, 0x06, 0xe8, 0xa4, 0x82, 0x2b, 0x3e, 0x90, 0x2b, 0x2c, 0x22,
-	0xca, 0xe2, 0xc1, 0xe6, 0xcd, 0x60, 0x4d, 0xa4, 0xc4, 0x31, 0x91, 0x7d, 0x43, 0xa1, 0x33, 0xa2,
-	0x56, 0x44, 0x90, 0x6c, 0xdd, 0xdf, 0x12, 0x9b, 0x37, 0x57, 0xd7, 0x31, 0xe7, 0x71, 0x42, 0x06,
-	0x46, 0xb3, 0xcc, 0xee, 0x07, 0x8a, 0xae, 0x89, 0x54, 0x78, 0x9d, 0xda, 0xb4, 0xee, 0x37, 0x70,
-	0x31, 0x22, 0x38, 0xe4, 0x6c, 0x94, 0xf0, 0xf0, 0xe1, 0x16, 0xcb, 0xd5, 0x90, 0x31, 0x9e, 0xb1,
-	0x90, 0x20, 0x04, 0x95, 0x15, 0x96, 0x2b, 0xd7, 0xe9, 0x38, 0xbd, 0xa6, 0x6f, 0xbe, 0xbb, 0x3d,
-	0x40, 0x25, 0xb9, 0x4f, 0x7e, 0xcf, 0x88, 0x54, 0xcf, 0x2a, 0xff, 0xac, 0xc0, 0xe9, 0x9e, 0x54,
-	0xa6, 0x9c, 0x49, 0x82, 0xae, 0xa1, 0x91, 0x62, 0x41, 0x98, 0x0a, 0x4a, 0x29, 0x60, 0x21, 0x7d,
-	0xbd, 0x16, 0xc8, 0x84, 0xab, 0x80, 0x65, 0xeb, 0x25, 0x11, 0xee, 0x41, 0xc7, 0xe9, 0x55, 0x7c,
-	0xd0, 0x90, 0x67, 0x10, 0x74, 0x03, 0x2d, 0x81, 0x59, 0x84, 0x79, 0x20, 0xc8, 0x86, 0xe0, 0xc4,
-	0x3d, 0x34, 0x67, 0x34, 0x2d, 0xe8, 0x1b, 0x0c, 0x0d, 0xe0, 0x14, 0x2b, 0xa5, 0x5b, 0x55, 0x94,
-	0xb3, 0x60, 0x49, 0xd5, 0x1a, 0xcb, 0x07, 0xb7, 0x62, 0xa4, 0xa8, 0x44, 0x8d, 0x2c, 0x83, 0x7e,
-	0x84, 0xcb, 0x72, 0x02, 0x8e, 0x63, 0x41, 0x62, 0xac, 0x48, 0x20, 0x69, 0xec, 0x56, 0x3b, 0x87,
-	0xbd, 0x96, 0x7f, 0x51, 0x12, 0x0c, 0x0b, 0x7e, 0x4e, 0x63, 0xf4, 0x11, 0xce, 0xcd, 0x66, 0x4a,
-	0x59, 0x1b, 0xae, 0x88, 0x74, 0x6b, 0x9d, 0xc3, 0x5e, 0xe3, 0xed, 0x4d, 0xff, 0xb9, 0xdd, 0xf4,
-	0xb7, 0x47, 0xfc, 0xca, 0x15, 0xf1, 0x4f, 0xcd, 0x09, 0x7b, 0x98, 0x44, 0x5f, 0xc2, 0xc9, 0x1a,
-	0x53, 0x16, 0x84, 0x2b, 0xfd, 0x2b, 0xc8, 0xbd, 0x7b, 0x64, 0x7b, 0xd5, 0xe8, 0x58, 0x83, 0x3e,
-	0xb9, 0x47, 0xaf, 0xe1, 0x53, 0x1c, 0x2a, 0xba, 0x21, 0x81, 0x2e, 0x8e, 0xd8, 0xc1, 0x1e, 0x1b,
-	0xe1, 0x0b, 0x4b, 0xcc, 0x35, 0x6e, 0xa6, 0xfb, 0x3d, 0x5c, 0x84, 0xe2, 0x51, 0x2a, 0x9c, 0x24,
-	0xf4, 0x0f, 0x12, 0x95, 0x33, 0xea, 0x26, 0xe3, 0xbc, 0x4c, 0xef, 0xf2, 0x7e, 0x80, 0xfa, 0xd6,
-	0x3a, 0x2e, 0x74, 0x9c, 0x5e, 0xe3, 0xed, 0x55, 0xdf, 0x9a, 0xab, 0x5f, 0x98, 0xab, 0xbf, 0x28,
-	0x14, 0xfe, 0x4e, 0xdc, 0xfd, 0xcb, 0x81, 0xd6, 0x5e, 0x5b, 0xe8, 0x12, 0x8e, 0xed, 0xb8, 0x68,
-	0x64, 0xf6, 0xdf, 0xf2, 0x8f, 0x4c, 0xfc, 0x2e, 0x42, 0x3d, 0x68, 0x5b, 0x6a, 0xa9, 0x4d, 0x63,
-	0xeb, 0x3a, 0x30, 0x75, 0x9d, 0x18, 0x7c, 0xeb, 0x52, 0xf4, 0x15, 0x9c, 0x48, 0x1a, 0x33, 0x22,
-	0xb6, 0xbb, 0xb5, 0x36, 0x68, 0x59, 0xb4, 0x58, 0xeb, 0x0d, 0xb4, 0xf6, 0x57, 0x59, 0x31, 0xab,
-	0x6c, 0xe2, 0xd2, 0xfe, 0xba, 0x7f, 0x3b, 0x70, 0x36, 0xe6, 0x49, 0x62, 0x0d, 0xc1, 0xa3, 0xc7,
-	0xc2, 0xd8, 0x4f, 0x2b, 0xad, 0xec, 0x2a, 0x7d, 0x09, 0xb5, 0x94, 0x08, 0xca, 0xa3, 0xdc, 0xa1,
-	0x79, 0x84, 0x3e, 0x07, 0x08, 0x57, 0x19, 0x7b, 0x08, 0x04, 0xe7, 0x2a, 0xaf, 0xa9, 0x6e, 0x10,
-	0x9f, 0x73, 0x85, 0xbe, 0x86, 0x76, 0x2a, 0x78, 0xca, 0x25, 0x11, 0x01, 0x8e, 0x22, 0x41, 0xa4,
-	0xcc, 0x4d, 0xf9, 0xa2, 0xc0, 0x87, 0x16, 0x46, 0x9f, 0x41, 0x5d, 0xf7, 0x82, 0x55, 0x26, 0x88,
-	0x5b, 0xb5, 0x07, 0x6d, 0x81, 0xee, 0x14, 0xce, 0x9f, 0x94, 0xbc, 0x7b, 0x60, 0x2b, 0x82, 0x23,
-	0x22, 0xf6, 0x1e, 0x98, 0x85, 0xcc, 0xe4, 0x10, 0x54, 0x96, 0x3c, 0x7a, 0xcc, 0xe7, 0x6a, 0xbe,
-	0xbb, 0xff, 0x3a, 0xd0, 0x58, 0x08, 0xcc, 0xa4, 0xf6, 0x0b, 0x67, 0xe8, 0x0c, 0xaa, 0x8c, 0xb3,
-	0x90, 0xe4, 0x5d, 0xdb, 0x00, 0xbd, 0x82, 0x7a, 0x8c, 0x65, 0x90, 0x0a, 0x1a, 0x92, 0xbc, 0xed,
-	0xe3, 0x18, 0xcb, 0x0f, 0x3a, 0x2e, 0xc8, 0x84, 0xae, 0xa9, 0xed, 0xdb, 0x92, 0x53, 0x1d, 0xeb,
-	0x5e, 0x04, 0x09, 0x69, 0x4a, 0x09, 0x53, 0x79, 0xbf, 0x3b, 0x40, 0xdf, 0xb6, 0xc1, 0x49, 0x66,
-	0xbb, 0xac, 0xf8, 0x36, 0xd0, 0x28, 0x65, 0x69, 0xa6, 0xdc, 0x9a, 0xd1, 0xdb, 0x00, 0xfd, 0x54,
-	0x9e, 0xca, 0x91, 0x31, 0xe2, 0xf5, 0xf3, 0xef, 0x6b, 0x5e, 0xc8, 0xca, 0x63, 0xfb, 0x0e, 0xea,
-	0x5b, 0x1c, 0x35, 0xc1, 0xd9, 0xe4, 0x1d, 0x3a, 0x1b, 0x1d, 0x15, 0x7f, 0x37, 0x8e, 0xd0, 0x91,
-	0xcc, 0xdb, 0x70, 0xe4, 0xeb, 0x7f, 0x1c, 0xa8, 0x2e, 0x78, 0x4a, 0x43, 0xd4, 0x80, 0xa3, 0x3b,
-	0xef, 0xbd, 0x37, 0xfb, 0xe8, 0xb5, 0x3f, 0x41, 0x57, 0xf0, 0x72, 0x3c, 0x9b, 0x4e, 0x87, 0x8b,
-	0x77, 0x33, 0x2f, 0x18, 0xcd, 0x7e, 0xfe, 0x2d, 0xf0, 0x27, 0xbf, 0xdc, 0x4d, 0xe6, 0x8b, 0xb6,
-	0x83, 0x5e, 0xc1, 0xc5, 0xff, 0xb8, 0xf9, 0x87, 0x99, 0x37, 0x9f, 0xb4, 0x0f, 0x50, 0x1b, 0x9a,
-	0x0b, 0x7f, 0xe8, 0xcd, 0x87, 0x63, 0x4d, 0xcf, 0xdb, 0x87, 0xe8, 0x0b, 0xb8, 0x1a, 0x4d, 0x86,
-	0x63, 0xad, 0x9d, 0xce, 0xc6, 0xef, 0x83, 0xdb, 0xe1, 0xfc, 0x36, 0x18, 0x7a, 0xde, 0xec, 0xce,
-	0x1b, 0x4f, 0xda, 0x15, 0xe4, 0xc2, 0xd9, 0x1e, 0x5f, 0x5c, 0x54, 0x45, 0x97, 0x70, 0xfe, 0x84,
-	0xc9, 0xaf, 0xa9, 0x2d, 0x6b, 0xe6, 0x69, 0x7e, 0xfb, 0x5f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xf2,
-	0x0d, 0xab, 0x2f, 0x3f, 0x06, 0x00, 0x00,
+	proto.RegisterFile("proto/sharding/v1/messages.proto", fileDescriptor_messages_c846aa9d98863956)
+}
+
+var fileDescriptor_messages_c846aa9d98863956 = []byte{
+	// 1318 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x56, 0xcb, 0x6e, 0xdb, 0x46,
+	0x17, 0xfe, 0x29, 0x53, 0xb2, 0x78, 0x64, 0x3b, 0xca, 0xf8, 0xc6, 0xd8, 0x7f, 0x6b, 0x97, 0x6e,
+	0x51, 0x27, 0x40, 0x6d, 0x24, 0x41, 0xef, 0xe8, 0x42, 0x56, 0xd4, 0xca, 0x89, 0x23, 0xb9, 0x94,
+	0xd3, 0xa0, 0x2b, 0x62, 0x44, 0x8e, 0x29, 0xc2, 0x12, 0x87, 0x9d, 0x19, 0xa9, 0x70, 0xd0, 0x47,
+	0xe8, 0xaa, 0x0f, 0x91, 0x6d, 0xd1, 0x97, 0xea, 0x73, 0x14, 0x73, 0x21, 0x45, 0x39, 0x6a, 0x16,
+	0xdd, 0x08, 0x73, 0xbe, 0x73, 0x99, 0x73, 0x66, 0xbe, 0x6f, 0x28, 0x38, 0xcc, 0x18, 0x15, 0xf4,
+	0x94, 0x8f, 0x30, 0x8b, 0x92, 0x34, 0x3e, 0x9d, 0x3d, 0x3e, 0x9d, 0x10, 0xce, 0x71, 0x4c, 0xf8,
+	0x89, 0x72, 0xa1, 0x2d, 0x22, 0x46, 0x84, 0x91, 0xe9, 0xe4, 0xa4, 0x70, 0xcc, 0x1e, 0xef, 0x1d,
+	0xc4, 0x94, 0xc6, 0x63, 0x72, 0xaa, 0x62, 0x86, 0xd3, 0xeb, 0x53, 0x91, 0x4c, 0x08, 0x17, 0x78,
+	0x92, 0xe9, 0x34, 0xef, 0x33, 0xd8, 0x3d, 0x23, 0x38, 0xa4, 0xe9, 0xd9, 0x98, 0x86, 0x37, 0x5d,
+	0xcc, 0x47, 0xad, 0x34, 0xa5, 0xd3, 0x34, 0x24, 0x08, 0x81, 0x3d, 0xc2, 0x7c, 0xe4, 0x5a, 0x87,
+	0xd6, 0xf1, 0x9a, 0xaf, 0xd6, 0xde, 0x31, 0xa0, 0x52, 0xb8, 0x4f, 0x7e, 0x99, 0x12, 0x2e, 0x96,
+	0x46, 0xfe, 0x6e, 0xc3, 0xe6, 0x42, 0x28, 0xcf, 0x68, 0xca, 0x09, 0x3a, 0x80, 0x46, 0x86, 0x19,
+	0x49, 0x45, 0x50, 0x4a, 0x01, 0x0d, 0xc9, 0xed, 0x65, 0x00, 0x1f, 0x53, 0x11, 0xa4, 0xd3, 0xc9,
+	0x90, 0x30, 0xb7, 0x72, 0x68, 0x1d, 0xdb, 0x3e, 0x48, 0xa8, 0xa7, 0x10, 0x74, 0x04, 0xeb, 0x0c,
+	0xa7, 0x11, 0xa6, 0x01, 0x23, 0x33, 0x82, 0xc7, 0xee, 0x8a, 0xaa, 0xb1, 0xa6, 0x41, 0x5f, 0x61,
+	0xe8, 0x14, 0x36, 0xb1, 0x10, 0x72, 0x54, 0x91, 0xd0, 0x34, 0x18, 0x26, 0x62, 0x82, 0xf9, 0x8d,
+	0x6b, 0xab, 0x50, 0x54, 0x72, 0x9d, 0x69, 0x0f, 0xfa, 0x06, 0x1e, 0x94, 0x13, 0x70, 0x1c, 0x33,
+	0x12, 0x63, 0x41, 0x02, 0x9e, 0xc4, 0x6e, 0x43, 0xa5, 0xed, 0x96, 0x02, 0x5a, 0xb9, 0x7f, 0x90,
+	0xc4, 0xe8, 0x35, 0x6c, 0xab, 0x9b, 0x29, 0x65, 0xcd, 0xa8, 0x20, 0xdc, 0xad, 0x1d, 0xae, 0x1c,
+	0x37, 0x9e, 0x1c, 0x9d, 0x2c, 0xbb, 0x9b, 0x93, 0xa2, 0xc4, 0x4f, 0x54, 0x10, 0x7f, 0x53, 0x55,
+	0x58, 0xc0, 0x38, 0xfa, 0x18, 0x36, 0x26, 0x38, 0x49, 0x83, 0x70, 0x24, 0x7f, 0x19, 0xb9, 0x76,
+	0x57, 0xf5, 0xac, 0x12, 0x6d, 0x4b, 0xd0, 0x27, 0xd7, 0xe8, 0x11, 0xdc, 0xc7, 0xa1, 0x48, 0x66,
+	0x24, 0x90, 0xcd, 0x11, 0x7d, 0xb0, 0x75, 0x15, 0x78, 0x4f, 0x3b, 0x06, 0x12, 0x57, 0xa7, 0xfb,
+	0x05, 0xec, 0x86, 0xec, 0x96, 0x0b, 0x3c, 0x1e, 0x27, 0x6f, 0x48, 0x54, 0xce, 0x70, 0x54, 0xc6,
+	0x76, 0xd9, 0x3d, 0xcf, 0xfb, 0x0a, 0x9c, 0x82, 0x3a, 0x2e, 0x1c, 0x5a, 0xc7, 0x8d, 0x27, 0x7b,
+	0x27, 0x9a, 0x5c, 0x27, 0x39, 0xb9, 0x4e, 0xae, 0xf2, 0x08, 0x7f, 0x1e, 0xfc, 0xdc, 0xae, 0x57,
+	0x9b, 0x35, 0x0f, 0x41, 0x53, 0xf5, 0xdb, 0x25, 0x38, 0x32, 0xb4, 0xf1, 0xbe, 0x85, 0xfb, 0x25,
+	0xcc, 0xf0, 0x03, 0x81, 0x2d, 0xef, 0x5a, 0x11, 0xc3, 0xf6, 0xd5, 0xba, 0xe0, 0x57, 0xa5, 0xc4,
+	0xaf, 0xde, 0x02, 0x71, 0x7d, 0x9c, 0xc6, 0x24, 0xa7, 0xe3, 0x07, 0x00, 0x5c, 0x60, 0x26, 0x82,
+	0x52, 0x21, 0x47, 0x21, 0x03, 0x59, 0x6d, 0x0b, 0xaa, 0x21, 0x9d, 0xa6, 0xc2, 0x50, 0x4b, 0x1b,
+	0xde, 0x1f, 0x16, 0xb8, 0xef, 0x16, 0x34, 0x4d, 0xfd, 0x97, 0x8a, 0xa8, 0x05, 0xb5, 0xa1, 0x2c,
+	0xc5, 0xdd, 0x15, 0x45, 0x83, 0x87, 0xcb, 0x69, 0xb0, 0x44, 0x24, 0xbe, 0x49, 0xf4, 0xde, 0x5a,
+	0xb0, 0xbe, 0x40, 0x09, 0xf4, 0x00, 0xea, 0x9a, 0x6a, 0x49, 0xa4, 0xfa, 0x58, 0xf7, 0x57, 0x95,
+	0x7d, 0x1e, 0xa1, 0x63, 0x68, 0x6a, 0x97, 0x4a, 0x0e, 0x4a, 0x27, 0xb6, 0xa1, 0xf0, 0x42, 0xe1,
+	0xe8, 0x13, 0xd8, 0xe0, 0x49, 0x9c, 0x12, 0x56, 0xe8, 0x42, 0x4b, 0x68, 0x5d, 0xa3, 0xb9, 0x24,
+	0x8e, 0x60, 0x7d, 0x51, 0x06, 0x55, 0x4d, 0x3e, 0x5c, 0xe2, 0xfe, 0x73, 0xbb, 0x6e, 0x37, 0xab,
+	0xde, 0x5f, 0x16, 0x6c, 0xb5, 0xe9, 0x78, 0xac, 0x25, 0x45, 0xa3, 0xdb, 0xfc, 0x2e, 0xee, 0xf6,
+	0x6b, 0xcf, 0xfb, 0xdd, 0x81, 0x5a, 0x46, 0x58, 0x42, 0x23, 0x73, 0x6c, 0xc6, 0x92, 0x87, 0x1d,
+	0x8e, 0xa6, 0xe9, 0x4d, 0xc0, 0x28, 0x15, 0xa6, 0x33, 0x47, 0x21, 0x3e, 0xa5, 0x02, 0x3d, 0x84,
+	0x66, 0xc6, 0x68, 0x46, 0x39, 0x61, 0x01, 0x8e, 0x22, 0x46, 0x38, 0x37, 0xb2, 0xbe, 0x97, 0xe3,
+	0x2d, 0x0d, 0xa3, 0xff, 0x83, 0x23, 0x27, 0xc2, 0x62, 0xca, 0x88, 0x69, 0x7e, 0x0e, 0x78, 0x17,
+	0xb0, 0x7d, 0xa7, 0xe5, 0xf9, 0x13, 0x35, 0x22, 0x38, 0x22, 0x6c, 0xe1, 0x89, 0xd2, 0x90, 0x3a,
+	0x3f, 0x04, 0xf6, 0x90, 0x46, 0xb7, 0x39, 0x1f, 0xe5, 0xda, 0xfb, 0xdb, 0x82, 0xc6, 0x15, 0xc3,
+	0x29, 0x97, 0x8a, 0xa3, 0xa9, 0xe4, 0x44, 0x4a, 0xd3, 0x90, 0x98, 0xa9, 0xb5, 0x81, 0xf6, 0xc1,
+	0x89, 0x31, 0x0f, 0x32, 0x96, 0x84, 0xc4, 0x8c, 0x5d, 0x8f, 0x31, 0xbf, 0x64, 0xc9, 0xdc, 0x39,
+	0x4e, 0x26, 0x89, 0x9e, 0x5b, 0x3b, 0x2f, 0xa4, 0x2d, 0x67, 0x61, 0x24, 0x4c, 0xb2, 0x84, 0xa4,
+	0xc2, 0xcc, 0x3b, 0x07, 0xe4, 0x6e, 0x33, 0x3c, 0x9e, 0xea, 0x29, 0x6d, 0x5f, 0x1b, 0x12, 0x4d,
+	0xd2, 0x6c, 0x2a, 0xdc, 0x9a, 0x8a, 0xd7, 0x06, 0xfa, 0xae, 0x7c, 0x2a, 0xab, 0x4a, 0xca, 0x07,
+	0xcb, 0xa9, 0x39, 0xc8, 0xc3, 0xca, 0xc7, 0xf6, 0x39, 0x38, 0x05, 0x8e, 0xd6, 0xc0, 0x9a, 0x99,
+	0x09, 0xad, 0x99, 0xb4, 0xf2, 0x07, 0xdb, 0x62, 0xd2, 0xe2, 0x66, 0x0c, 0x8b, 0x4b, 0x7d, 0xd5,
+	0x3b, 0xe9, 0x8c, 0x8c, 0x69, 0x46, 0xd0, 0x63, 0xa8, 0x0a, 0x9a, 0x25, 0xa1, 0x4a, 0xdd, 0x78,
+	0xb2, 0xbf, 0x7c, 0xfb, 0x2b, 0x19, 0xe2, 0xeb, 0x48, 0xe4, 0xc2, 0x6a, 0x86, 0x6f, 0xc7, 0x14,
+	0x47, 0xe6, 0xd8, 0x73, 0x53, 0xf2, 0x85, 0x69, 0xb6, 0x49, 0x92, 0xe9, 0x0d, 0x1d, 0x83, 0x9c,
+	0x47, 0x68, 0x0f, 0xea, 0xcc, 0xdc, 0xac, 0x3a, 0xb7, 0xba, 0x5f, 0xd8, 0xde, 0x23, 0x68, 0x7e,
+	0x9f, 0xa4, 0xd1, 0x25, 0x21, 0x8c, 0xe7, 0x8c, 0xdd, 0x81, 0x9a, 0xc0, 0x2c, 0x26, 0xc2, 0x5c,
+	0xbc, 0xb1, 0xbc, 0x0b, 0xb8, 0x5f, 0x8a, 0x35, 0x54, 0xf9, 0x12, 0xaa, 0x99, 0x04, 0x5c, 0x4b,
+	0x49, 0xfc, 0xa3, 0xe5, 0x83, 0x5c, 0x92, 0x82, 0x93, 0xbe, 0x8e, 0xf7, 0x9e, 0x42, 0xa3, 0x84,
+	0xa2, 0x0d, 0xa8, 0x18, 0x81, 0xac, 0xf9, 0x95, 0x24, 0x92, 0x37, 0x27, 0xb9, 0xcd, 0xdd, 0xca,
+	0xe1, 0x8a, 0xbc, 0x39, 0x65, 0x78, 0xbf, 0x81, 0xd3, 0xc5, 0x69, 0xc4, 0x47, 0xf8, 0x46, 0xbd,
+	0x49, 0x29, 0x11, 0xbf, 0x52, 0x76, 0x33, 0xd7, 0x96, 0x63, 0x90, 0xf3, 0x48, 0x92, 0x49, 0x32,
+	0x56, 0xbf, 0x58, 0x86, 0x69, 0x12, 0x50, 0x0f, 0x56, 0xee, 0x54, 0xfc, 0xd6, 0x0a, 0x53, 0x4e,
+	0xc5, 0xee, 0x1d, 0xa8, 0x29, 0x89, 0x4a, 0x59, 0xad, 0x48, 0x5d, 0x6a, 0x4b, 0x3e, 0x46, 0x35,
+	0xf9, 0x41, 0x98, 0xf2, 0xc5, 0xe2, 0xd6, 0xfb, 0x8a, 0x57, 0xee, 0x14, 0xff, 0x14, 0xee, 0x5d,
+	0x27, 0x29, 0xd6, 0x1f, 0x1f, 0x92, 0xd1, 0x70, 0x64, 0x6e, 0x6c, 0xa3, 0x80, 0x3b, 0x12, 0x45,
+	0x5f, 0x2f, 0x74, 0xf1, 0xaf, 0x47, 0x3b, 0x90, 0x31, 0xba, 0xab, 0xa2, 0xd1, 0x97, 0xd0, 0x28,
+	0xc1, 0xef, 0x7b, 0x82, 0x8e, 0x60, 0x7d, 0x8c, 0x85, 0x64, 0xce, 0xc2, 0x4b, 0xb4, 0xa6, 0xc1,
+	0x4b, 0x85, 0x79, 0x7f, 0x5a, 0xb0, 0xfe, 0x52, 0x6f, 0xe9, 0x93, 0x90, 0xb2, 0x48, 0x6a, 0x71,
+	0xfe, 0x31, 0x94, 0x25, 0x57, 0x4a, 0x1f, 0x3c, 0x49, 0x38, 0x3a, 0x15, 0x43, 0x3a, 0x4d, 0x75,
+	0xbd, 0xba, 0x5f, 0xd8, 0xf2, 0x5e, 0x35, 0xf1, 0xe5, 0xd0, 0x4e, 0xce, 0x6d, 0x04, 0xb6, 0x64,
+	0x85, 0xa2, 0xa7, 0xe3, 0xab, 0xb5, 0xc4, 0x78, 0xf2, 0x26, 0x17, 0xb4, 0x5a, 0x4b, 0x4c, 0xdc,
+	0x66, 0x44, 0xc9, 0xd9, 0xf1, 0xd5, 0x5a, 0x62, 0x11, 0x16, 0xd8, 0xfc, 0x31, 0x50, 0xeb, 0x47,
+	0x6f, 0x2b, 0x50, 0x55, 0xe2, 0x41, 0x0d, 0x58, 0x7d, 0xd5, 0x7b, 0xd1, 0xeb, 0xbf, 0xee, 0x35,
+	0xff, 0x87, 0xf6, 0x60, 0xa7, 0xdd, 0xbf, 0xb8, 0x68, 0x5d, 0x9d, 0xf7, 0x7b, 0xc1, 0x59, 0xff,
+	0xd9, 0xcf, 0x81, 0xdf, 0xf9, 0xf1, 0x55, 0x67, 0x70, 0xd5, 0xb4, 0xd0, 0x3e, 0xec, 0xbe, 0xe3,
+	0x1b, 0x5c, 0xf6, 0x7b, 0x83, 0x4e, 0xb3, 0x82, 0x9a, 0xb0, 0x76, 0xe5, 0xb7, 0x7a, 0x83, 0x56,
+	0x5b, 0xba, 0x07, 0xcd, 0x15, 0xf4, 0x21, 0xec, 0x9d, 0x75, 0x5a, 0x6d, 0x19, 0x7b, 0xd1, 0x6f,
+	0xbf, 0x08, 0xba, 0xad, 0x41, 0x37, 0x68, 0xf5, 0x7a, 0xfd, 0x57, 0xbd, 0x76, 0xa7, 0x69, 0x23,
+	0x17, 0xb6, 0x16, 0xfc, 0xf9, 0x46, 0x55, 0xf4, 0x00, 0xb6, 0xef, 0x78, 0xcc, 0x36, 0x35, 0xb4,
+	0x03, 0xa8, 0xdd, 0x6d, 0x9d, 0xf7, 0x82, 0x6e, 0xa7, 0xf5, 0xac, 0x48, 0x59, 0x45, 0xbb, 0xb0,
+	0xb9, 0x80, 0x9b, 0x84, 0xfa, 0x3b, 0x5d, 0xf8, 0xad, 0xde, 0x0f, 0x9d, 0x22, 0xd1, 0x41, 0x07,
+	0xb0, 0xbf, 0xd4, 0x6f, 0x0a, 0xc0, 0xb0, 0xa6, 0xfe, 0xba, 0x3c, 0xfd, 0x67, 0x00, 0x1e, 0x5a,
+	0x67, 0x5a, 0x5f, 0x0b, 0x00, 0x00,
 }
@@ -35,6 +35,75 @@ var (
 		Usage: "Password file to use for non-interactive password input",
 		Value: "",
 	}
+	// P2PListenFlag defines the multiaddresses the p2p host listens on.
+	P2PListenFlag = cli.StringSliceFlag{
+		Name:  "p2plisten",
+		Usage: "Multiaddress for the p2p host to listen on, such as /ip4/0.0.0.0/tcp/9000. Can be repeated. A random port on 127.0.0.1 is used by default.",
+	}
+	// P2PExternalAddrFlag defines the multiaddress advertised to peers.
+	P2PExternalAddrFlag = cli.StringFlag{
+		Name:  "p2pexternal",
+		Usage: "Multiaddress advertised to peers instead of the listen addresses, for nodes behind a NAT.",
+	}
+	// NodeKeyFlag defines the file holding the p2p identity of the node.
+	NodeKeyFlag = cli.StringFlag{
+		Name:  "nodekey",
+		Usage: "File holding the private key of the p2p identity of the node. Generated on the first start. Defaults to a file in the datadir.",
+	}
+	// StaticPeersFlag defines peers the node stays connected to.
+	StaticPeersFlag = cli.StringSliceFlag{
+		Name:  "staticpeer",
+		Usage: "Multiaddress, ending with /ipfs/<peer ID>, of a peer to stay connected to. Can be repeated.",
+	}
+	// BootstrapNodesFlag defines the nodes contacted on start.
+	BootstrapNodesFlag = cli.StringSliceFlag{
+		Name:  "bootstrapnode",
+		Usage: "Multiaddress, ending with /ipfs/<peer ID>, of a node to contact on start to join the network. Can be repeated.",
+	}
+	// NoMDNSFlag disables the discovery of peers on the local network.
+	NoMDNSFlag = cli.BoolFlag{
+		Name:  "nomdns",
+		Usage: "Disable the discovery of peers on the local network through multicast DNS",
+	}
+	// DHTFlag enables the discovery of peers through the DHT.
+	DHTFlag = cli.BoolFlag{
+		Name:  "dht",
+		Usage: "Discover peers through a Kademlia DHT, joined through the bootstrap nodes",
+	}
+	// TargetPeersFlag defines the number of peers the node stays connected to.
+	TargetPeersFlag = cli.IntFlag{
+		Name:  "targetpeers",
+		Usage: "Number of peers to look for through the DHT, and to keep when trimming connections",
+		Value: 25,
+	}
+	// MaxPeersFlag defines the number of peers above which connections are trimmed.
+	MaxPeersFlag = cli.IntFlag{
+		Name:  "maxpeers",
+		Usage: "Number of connected peers above which connections are trimmed down to the target peer count",
+		Value: 50,
+	}
+	// P2PCompressFlag enables the compression of published p2p messages.
+	P2PCompressFlag = cli.BoolFlag{
+		Name:  "p2pcompress",
+		Usage: "Publish p2p messages snappy compressed. Compressed messages are always accepted",
+	}
+	// P2PRecordFlag defines the file p2p messages are recorded to.
+	P2PRecordFlag = cli.StringFlag{
+		Name:  "p2precord",
+		Usage: "Record the sent and received p2p messages to this file, for replaying them with the p2p replay tool",
+	}
+	// P2PRecordSizeFlag defines the size above which the p2p recording is rotated.
+	P2PRecordSizeFlag = cli.IntFlag{
+		Name:  "p2precordsize",
+		Usage: "Size in MiB above which the p2p recording file is rotated",
+		Value: 64,
+	}
+	// P2PReplayFlag defines the peers allowed to send recorded p2p messages
+	// with the replay tool.
+	P2PReplayFlag = cli.StringSliceFlag{
+		Name:  "p2preplay",
+		Usage: "Peer ID of the p2p replay tool, allowed to send recorded p2p messages to the node. Can be repeated.",
+	}
 	// RPCProviderFlag defines a http endpoint flag to connect to mainchain.
 	RPCProviderFlag = cli.StringFlag{
 		Name:  "rpc",
@@ -0,0 +1,192 @@
+// Package bls implements BLS signatures over the BLS12-381 curve with
+// support for signature and public key aggregation, on top of the blst
+// library.
+//
+// Public keys are points of G1 and signatures are points of G2, both
+// serialized in compressed form (48 and 96 bytes respectively). Messages are
+// hashed to G2 as specified by the proof of possession scheme of the IETF BLS
+// signature draft, like the Ethereum 2.0 specification, so that signatures
+// interoperate with other implementations. Operations on secret keys run in
+// constant time.
+//
+// Aggregate signatures over a common message are vulnerable to rogue public
+// key attacks, so callers must only aggregate keys whose possession has been
+// proven, e.g. at validator registration.
+package bls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+const (
+	// SecretKeyLength is the size in bytes of a serialized secret key.
+	SecretKeyLength = 32
+	// PublicKeyLength is the size in bytes of a serialized public key.
+	PublicKeyLength = 48
+	// SignatureLength is the size in bytes of a serialized signature.
+	SignatureLength = 96
+)
+
+// dst is the domain separation tag of the hash of messages to G2, from the
+// ciphersuite of the proof of possession scheme.
+var dst = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+// SecretKey used in the BLS signature scheme.
+type SecretKey struct {
+	val *blst.SecretKey
+}
+
+// PublicKey used in the BLS signature scheme.
+type PublicKey struct {
+	val *blst.P1Affine
+}
+
+// Signature used in the BLS signature scheme.
+type Signature struct {
+	val *blst.P2Affine
+}
+
+// RandKey creates a new secret key using randomness from r.
+func RandKey(r io.Reader) (*SecretKey, error) {
+	ikm := make([]byte, SecretKeyLength)
+	if _, err := io.ReadFull(r, ikm); err != nil {
+		return nil, fmt.Errorf("could not generate secret key: %v", err)
+	}
+	return &SecretKey{val: blst.KeyGen(ikm)}, nil
+}
+
+// SecretKeyFromBytes deserializes a big-endian secret key.
+func SecretKeyFromBytes(b []byte) (*SecretKey, error) {
+	if len(b) != SecretKeyLength {
+		return nil, fmt.Errorf("secret key must be %d bytes, received %d", SecretKeyLength, len(b))
+	}
+	k := new(blst.SecretKey).Deserialize(b)
+	if k == nil {
+		return nil, errors.New("secret key is not in the range [1, r-1]")
+	}
+	return &SecretKey{val: k}, nil
+}
+
+// Marshal serializes the secret key in big-endian form.
+func (s *SecretKey) Marshal() []byte {
+	return s.val.Serialize()
+}
+
+// PublicKey derives the public key corresponding to the secret key.
+func (s *SecretKey) PublicKey() *PublicKey {
+	return &PublicKey{val: new(blst.P1Affine).From(s.val)}
+}
+
+// Sign a message with the secret key.
+func (s *SecretKey) Sign(msg []byte) *Signature {
+	return &Signature{val: new(blst.P2Affine).Sign(s.val, msg, dst)}
+}
+
+// PublicKeyFromBytes deserializes a compressed public key, checking that it
+// is a valid point of G1.
+func PublicKeyFromBytes(b []byte) (*PublicKey, error) {
+	if len(b) != PublicKeyLength {
+		return nil, fmt.Errorf("public key must be %d bytes, received %d", PublicKeyLength, len(b))
+	}
+	p := new(blst.P1Affine).Uncompress(b)
+	if p == nil {
+		return nil, errors.New("could not decompress public key")
+	}
+	if !p.KeyValidate() {
+		return nil, errors.New("public key is the point at infinity or not in G1")
+	}
+	return &PublicKey{val: p}, nil
+}
+
+// Marshal serializes the public key in compressed form.
+func (p *PublicKey) Marshal() []byte {
+	return p.val.Compress()
+}
+
+// Aggregate returns the sum of two public keys.
+func (p *PublicKey) Aggregate(other *PublicKey) *PublicKey {
+	return AggregatePublicKeys([]*PublicKey{p, other})
+}
+
+// SignatureFromBytes deserializes a compressed signature, checking that it
+// is a valid point of G2.
+func SignatureFromBytes(b []byte) (*Signature, error) {
+	if len(b) != SignatureLength {
+		return nil, fmt.Errorf("signature must be %d bytes, received %d", SignatureLength, len(b))
+	}
+	p := new(blst.P2Affine).Uncompress(b)
+	if p == nil {
+		return nil, errors.New("could not decompress signature")
+	}
+	if !p.SigValidate(false) {
+		return nil, errors.New("signature is not in G2")
+	}
+	return &Signature{val: p}, nil
+}
+
+// Marshal serializes the signature in compressed form.
+func (s *Signature) Marshal() []byte {
+	return s.val.Compress()
+}
+
+// Verify a signature of msg by the holder of pub.
+func (s *Signature) Verify(msg []byte, pub *PublicKey) bool {
+	return s.VerifyAggregate([]*PublicKey{pub}, msg)
+}
+
+// VerifyAggregate checks an aggregate signature of the same msg by every
+// one of pubKeys.
+func (s *Signature) VerifyAggregate(pubKeys []*PublicKey, msg []byte) bool {
+	if len(pubKeys) == 0 {
+		return false
+	}
+	// Keys and signatures are checked to be in their group when
+	// deserialized.
+	return s.val.FastAggregateVerify(false, affinePublicKeys(pubKeys), msg, dst)
+}
+
+// VerifyAggregateDistinct checks an aggregate signature where pubKeys[i]
+// signed msgs[i]. Messages must be distinct.
+func (s *Signature) VerifyAggregateDistinct(pubKeys []*PublicKey, msgs [][]byte) bool {
+	if len(pubKeys) == 0 || len(pubKeys) != len(msgs) {
+		return false
+	}
+	seen := make(map[string]bool, len(msgs))
+	for _, msg := range msgs {
+		if seen[string(msg)] {
+			return false
+		}
+		seen[string(msg)] = true
+	}
+	return s.val.AggregateVerify(false, affinePublicKeys(pubKeys), false, msgs, dst)
+}
+
+// AggregateSignatures combines signatures into a single signature.
+func AggregateSignatures(sigs []*Signature) *Signature {
+	agg := new(blst.P2Aggregate)
+	for _, s := range sigs {
+		agg.Add(s.val, false)
+	}
+	return &Signature{val: agg.ToAffine()}
+}
+
+// AggregatePublicKeys combines public keys into a single public key.
+func AggregatePublicKeys(pubs []*PublicKey) *PublicKey {
+	agg := new(blst.P1Aggregate)
+	for _, p := range pubs {
+		agg.Add(p.val, false)
+	}
+	return &PublicKey{val: agg.ToAffine()}
+}
+
+func affinePublicKeys(pubs []*PublicKey) []*blst.P1Affine {
+	ps := make([]*blst.P1Affine, len(pubs))
+	for i, p := range pubs {
+		ps[i] = p.val
+	}
+	return ps
+}
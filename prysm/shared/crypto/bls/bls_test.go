@@ -0,0 +1,215 @@
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+// Known answer vectors of the sign tests of the Ethereum 2.0 specification,
+// signing 32 byte messages of a repeated byte.
+var signVectors = []struct {
+	secretKey string
+	publicKey string
+	msg       byte
+	signature string
+}{
+	{
+		secretKey: "263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3",
+		publicKey: "a491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+		msg:       0x00,
+		signature: "b6ed936746e01f8ecf281f020953fbf1f01debd5657c4a383940b020b26507f6076334f91e2366c96e9ab279fb5158090352ea1c5b0c9274504f4f0e7053af24802e51e4568d164fe986834f41e55c8e850ce1f98458c0cfc9ab380b55285a55",
+	},
+	{
+		secretKey: "263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3",
+		publicKey: "a491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+		msg:       0x56,
+		signature: "882730e5d03f6b42c3abc26d3372625034e1d871b65a8a6b900a56dae22da98abbe1b68f85e49fe7652a55ec3d0591c20767677e33e5cbb1207315c41a9ac03be39c2e7668edc043d6cb1d9fd93033caa8a1c5b0e84bedaeb6c64972503a43eb",
+	},
+	{
+		secretKey: "263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3",
+		publicKey: "a491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+		msg:       0xab,
+		signature: "91347bccf740d859038fcdcaf233eeceb2a436bcaaee9b2aa3bfb70efe29dfb2677562ccbea1c8e061fb9971b0753c240622fab78489ce96768259fc01360346da5b9f579e5da0d941e4c6ba18a0e64906082375394f337fa1af2b7127b0d121",
+	},
+	{
+		secretKey: "47b8192d77bf871b62e87859d653922725724a5c031afeabc60bcef5ff665138",
+		publicKey: "b301803f8b5ac4a1133581fc676dfedc60d891dd5fa99028805e5ea5b08d3491af75d0707adab3b70c6a6a580217bf81",
+		msg:       0x00,
+		signature: "b23c46be3a001c63ca711f87a005c200cc550b9429d5f4eb38d74322144f1b63926da3388979e5321012fb1a0526bcd100b5ef5fe72628ce4cd5e904aeaa3279527843fae5ca9ca675f4f51ed8f83bbf7155da9ecc9663100a885d5dc6df96d9",
+	},
+	{
+		secretKey: "47b8192d77bf871b62e87859d653922725724a5c031afeabc60bcef5ff665138",
+		publicKey: "b301803f8b5ac4a1133581fc676dfedc60d891dd5fa99028805e5ea5b08d3491af75d0707adab3b70c6a6a580217bf81",
+		msg:       0x56,
+		signature: "af1390c3c47acdb37131a51216da683c509fce0e954328a59f93aebda7e4ff974ba208d9a4a2a2389f892a9d418d618418dd7f7a6bc7aa0da999a9d3a5b815bc085e14fd001f6a1948768a3f4afefc8b8240dda329f984cb345c6363272ba4fe",
+	},
+	{
+		secretKey: "47b8192d77bf871b62e87859d653922725724a5c031afeabc60bcef5ff665138",
+		publicKey: "b301803f8b5ac4a1133581fc676dfedc60d891dd5fa99028805e5ea5b08d3491af75d0707adab3b70c6a6a580217bf81",
+		msg:       0xab,
+		signature: "9674e2228034527f4c083206032b020310face156d4a4685e2fcaec2f6f3665aa635d90347b6ce124eb879266b1e801d185de36a0a289b85e9039662634f2eea1e02e670bc7ab849d006a70b2f93b84597558a05b879c8d445f387a5d5b653df",
+	},
+	{
+		secretKey: "328388aff0d4a5b7dc9205abd374e7e98f3cd9f3418edb4eafda5fb16473d216",
+		publicKey: "b53d21a4cfd562c469cc81514d4ce5a6b577d8403d32a394dc265dd190b47fa9f829fdd7963afdf972e5e77854051f6f",
+		msg:       0x00,
+		signature: "948a7cb99f76d616c2c564ce9bf4a519f1bea6b0a624a02276443c245854219fabb8d4ce061d255af5330b078d5380681751aa7053da2c98bae898edc218c75f07e24d8802a17cd1f6833b71e58f5eb5b94208b4d0bb3848cecb075ea21be115",
+	},
+	{
+		secretKey: "328388aff0d4a5b7dc9205abd374e7e98f3cd9f3418edb4eafda5fb16473d216",
+		publicKey: "b53d21a4cfd562c469cc81514d4ce5a6b577d8403d32a394dc265dd190b47fa9f829fdd7963afdf972e5e77854051f6f",
+		msg:       0x56,
+		signature: "a4efa926610b8bd1c8330c918b7a5e9bf374e53435ef8b7ec186abf62e1b1f65aeaaeb365677ac1d1172a1f5b44b4e6d022c252c58486c0a759fbdc7de15a756acc4d343064035667a594b4c2a6f0b0b421975977f297dba63ee2f63ffe47bb6",
+	},
+	{
+		secretKey: "328388aff0d4a5b7dc9205abd374e7e98f3cd9f3418edb4eafda5fb16473d216",
+		publicKey: "b53d21a4cfd562c469cc81514d4ce5a6b577d8403d32a394dc265dd190b47fa9f829fdd7963afdf972e5e77854051f6f",
+		msg:       0xab,
+		signature: "ae82747ddeefe4fd64cf9cedb9b04ae3e8a43420cd255e3c7cd06a8d88b7c7f8638543719981c5d16fa3527c468c25f0026704a6951bde891360c7e8d12ddee0559004ccdbe6046b55bae1b257ee97f7cdb955773d7cf29adf3ccbb9975e4eb9",
+	},
+}
+
+func TestSignVectors(t *testing.T) {
+	for _, v := range signVectors {
+		b, _ := hex.DecodeString(v.secretKey)
+		sk, err := SecretKeyFromBytes(b)
+		if err != nil {
+			t.Fatalf("Could not deserialize secret key %s: %v", v.secretKey, err)
+		}
+		if pub := hex.EncodeToString(sk.PublicKey().Marshal()); pub != v.publicKey {
+			t.Errorf("Expected public key %s of %s, got %s", v.publicKey, v.secretKey, pub)
+		}
+		msg := bytes.Repeat([]byte{v.msg}, 32)
+		sig := sk.Sign(msg)
+		if got := hex.EncodeToString(sig.Marshal()); got != v.signature {
+			t.Errorf("Expected signature %s of %x by %s, got %s", v.signature, msg, v.secretKey, got)
+		}
+
+		b, _ = hex.DecodeString(v.signature)
+		s, err := SignatureFromBytes(b)
+		if err != nil {
+			t.Fatalf("Could not deserialize signature %s: %v", v.signature, err)
+		}
+		if !s.Verify(msg, sk.PublicKey()) {
+			t.Errorf("Signature %s did not verify", v.signature)
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	sk, err := RandKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Could not generate key: %v", err)
+	}
+	pub := sk.PublicKey()
+	sig := sk.Sign([]byte("hello"))
+	if !sig.Verify([]byte("hello"), pub) {
+		t.Error("Signature did not verify")
+	}
+	if sig.Verify([]byte("goodbye"), pub) {
+		t.Error("Signature verified for the wrong message")
+	}
+}
+
+func TestVerifyAggregate(t *testing.T) {
+	msg := []byte("attestation")
+	var pubs []*PublicKey
+	var sigs []*Signature
+	for i := 0; i < 3; i++ {
+		sk, err := RandKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("Could not generate key: %v", err)
+		}
+		pubs = append(pubs, sk.PublicKey())
+		sigs = append(sigs, sk.Sign(msg))
+	}
+	agg := AggregateSignatures(sigs)
+	if !agg.VerifyAggregate(pubs, msg) {
+		t.Error("Aggregate signature did not verify")
+	}
+	if agg.VerifyAggregate(pubs[:2], msg) {
+		t.Error("Aggregate signature verified with a missing signer")
+	}
+}
+
+func TestVerifyAggregateDistinct(t *testing.T) {
+	msgs := [][]byte{[]byte("a"), []byte("b")}
+	var pubs []*PublicKey
+	var sigs []*Signature
+	for _, msg := range msgs {
+		sk, err := RandKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("Could not generate key: %v", err)
+		}
+		pubs = append(pubs, sk.PublicKey())
+		sigs = append(sigs, sk.Sign(msg))
+	}
+	agg := AggregateSignatures(sigs)
+	if !agg.VerifyAggregateDistinct(pubs, msgs) {
+		t.Error("Aggregate signature did not verify")
+	}
+	if agg.VerifyAggregateDistinct(pubs, [][]byte{[]byte("a"), []byte("a")}) {
+		t.Error("Aggregate signature verified over duplicate messages")
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	sk, err := RandKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Could not generate key: %v", err)
+	}
+	sk2, err := SecretKeyFromBytes(sk.Marshal())
+	if err != nil {
+		t.Fatalf("Could not deserialize secret key: %v", err)
+	}
+	if !bytes.Equal(sk2.Marshal(), sk.Marshal()) {
+		t.Error("Secret key did not round trip")
+	}
+
+	pub := sk.PublicKey()
+	pub2, err := PublicKeyFromBytes(pub.Marshal())
+	if err != nil {
+		t.Fatalf("Could not deserialize public key: %v", err)
+	}
+	if !pub2.val.Equals(pub.val) {
+		t.Error("Public key did not round trip")
+	}
+
+	sig := sk.Sign([]byte("hello"))
+	sig2, err := SignatureFromBytes(sig.Marshal())
+	if err != nil {
+		t.Fatalf("Could not deserialize signature: %v", err)
+	}
+	if !bytes.Equal(sig2.Marshal(), sig.Marshal()) {
+		t.Error("Signature did not round trip")
+	}
+
+	if _, err := PublicKeyFromBytes(make([]byte, PublicKeyLength)); err == nil {
+		t.Error("Expected uncompressed encoding to be rejected")
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	sk, err := RandKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Could not generate key: %v", err)
+	}
+	signer := NewSigner(sk)
+	sig, err := signer.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatalf("Could not sign: %v", err)
+	}
+	v := &Verifier{}
+	ok, err := v.Verify(signer.PublicKey(), []byte("hello"), sig)
+	if err != nil {
+		t.Fatalf("Could not verify: %v", err)
+	}
+	if !ok {
+		t.Error("Signature did not verify")
+	}
+	if _, err := v.Verify([]byte{1, 2, 3}, []byte("hello"), sig); err == nil {
+		t.Error("Expected malformed public key to be rejected")
+	}
+}
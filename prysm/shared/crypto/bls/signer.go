@@ -0,0 +1,66 @@
+package bls
+
+import (
+	"fmt"
+)
+
+// Signer signs messages with a BLS secret key. It satisfies the
+// crypto.Signer interface.
+type Signer struct {
+	key *SecretKey
+}
+
+// NewSigner wraps a secret key into a Signer.
+func NewSigner(key *SecretKey) *Signer {
+	return &Signer{key: key}
+}
+
+// Sign returns the serialized signature of msg.
+func (s *Signer) Sign(msg []byte) ([]byte, error) {
+	return s.key.Sign(msg).Marshal(), nil
+}
+
+// PublicKey returns the serialized public key of the signer.
+func (s *Signer) PublicKey() []byte {
+	return s.key.PublicKey().Marshal()
+}
+
+// Verifier checks serialized BLS signatures. It satisfies the
+// crypto.Verifier and crypto.Aggregator interfaces.
+type Verifier struct{}
+
+// Verify checks that sig is a signature of msg by pubKey.
+func (v *Verifier) Verify(pubKey []byte, msg []byte, sig []byte) (bool, error) {
+	return v.VerifyAggregate([][]byte{pubKey}, msg, sig)
+}
+
+// VerifyAggregate checks that sig is an aggregate signature of msg by every
+// one of pubKeys.
+func (v *Verifier) VerifyAggregate(pubKeys [][]byte, msg []byte, sig []byte) (bool, error) {
+	pubs := make([]*PublicKey, len(pubKeys))
+	for i, b := range pubKeys {
+		pub, err := PublicKeyFromBytes(b)
+		if err != nil {
+			return false, fmt.Errorf("could not deserialize public key %d: %v", i, err)
+		}
+		pubs[i] = pub
+	}
+	s, err := SignatureFromBytes(sig)
+	if err != nil {
+		return false, fmt.Errorf("could not deserialize signature: %v", err)
+	}
+	return s.VerifyAggregate(pubs, msg), nil
+}
+
+// AggregateSignatures combines serialized signatures into a single one.
+func (v *Verifier) AggregateSignatures(sigs [][]byte) ([]byte, error) {
+	ss := make([]*Signature, len(sigs))
+	for i, b := range sigs {
+		s, err := SignatureFromBytes(b)
+		if err != nil {
+			return nil, fmt.Errorf("could not deserialize signature %d: %v", i, err)
+		}
+		ss[i] = s
+	}
+	return AggregateSignatures(ss).Marshal(), nil
+}
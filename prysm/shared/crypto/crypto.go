@@ -0,0 +1,27 @@
+// Package crypto defines the signing abstractions used by beacon chain
+// validators. Implementations live in subpackages, such as bls.
+package crypto
+
+// Signer produces signatures over arbitrary messages with a secret key it
+// holds. Public keys and signatures are exchanged in their serialized form
+// so they can be stored in protobuf messages and the beacon chain state.
+type Signer interface {
+	// Sign returns the serialized signature of msg.
+	Sign(msg []byte) ([]byte, error)
+	// PublicKey returns the serialized public key of the signer.
+	PublicKey() []byte
+}
+
+// Verifier checks serialized signatures against serialized public keys.
+type Verifier interface {
+	// Verify checks that sig is a signature of msg by pubKey.
+	Verify(pubKey []byte, msg []byte, sig []byte) (bool, error)
+	// VerifyAggregate checks that sig is an aggregate signature of msg by
+	// every one of pubKeys.
+	VerifyAggregate(pubKeys [][]byte, msg []byte, sig []byte) (bool, error)
+}
+
+// Aggregator combines serialized signatures into a single signature.
+type Aggregator interface {
+	AggregateSignatures(sigs [][]byte) ([]byte, error)
+}
@@ -0,0 +1,251 @@
+// Package keystore stores validator BLS secret keys in password encrypted
+// files. The file format follows the version 3 Web3 Secret Storage layout
+// used by go-ethereum accounts: the key is encrypted with AES-128-CTR using
+// a scrypt derived key, and a keccak256 MAC guards against wrong passwords.
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/prysmaticlabs/prysm/shared/crypto/bls"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	version = 3
+
+	keyHeaderKDF = "scrypt"
+	cipherName   = "aes-128-ctr"
+
+	// StandardScryptN is the N parameter of scrypt using 256MB memory and
+	// taking approximately 1s CPU time on a modern processor.
+	StandardScryptN = 1 << 18
+	// StandardScryptP is the P parameter of scrypt using 256MB memory and
+	// taking approximately 1s CPU time on a modern processor.
+	StandardScryptP = 1
+	// LightScryptN is the N parameter of scrypt using 4MB memory and taking
+	// approximately 100ms CPU time on a modern processor.
+	LightScryptN = 1 << 12
+	// LightScryptP is the P parameter of scrypt using 4MB memory and taking
+	// approximately 100ms CPU time on a modern processor.
+	LightScryptP = 6
+
+	scryptR     = 8
+	scryptDKLen = 32
+)
+
+// ErrDecrypt is returned when a key file cannot be decrypted with the
+// given password.
+var ErrDecrypt = errors.New("could not decrypt key with given password")
+
+// Key is a validator's BLS key pair.
+type Key struct {
+	PublicKey *bls.PublicKey
+	SecretKey *bls.SecretKey
+}
+
+// NewKey generates a new random validator key.
+func NewKey(r io.Reader) (*Key, error) {
+	sk, err := bls.RandKey(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{PublicKey: sk.PublicKey(), SecretKey: sk}, nil
+}
+
+type encryptedKeyJSON struct {
+	PublicKey string     `json:"publickey"`
+	Crypto    cryptoJSON `json:"crypto"`
+	ID        string     `json:"id"`
+	Version   int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams cipherParamsJSON       `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type cipherParamsJSON struct {
+	IV string `json:"iv"`
+}
+
+// EncryptKey encrypts a key using the specified scrypt parameters into a
+// JSON blob that can be decrypted later on.
+func EncryptKey(key *Key, password string, scryptN, scryptP int) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("could not read random salt: %v", err)
+	}
+	derivedKey, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	encryptKey := derivedKey[:16]
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, fmt.Errorf("could not read random iv: %v", err)
+	}
+	cipherText, err := aesCTRXOR(encryptKey, key.SecretKey.Marshal(), iv)
+	if err != nil {
+		return nil, err
+	}
+	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
+
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, fmt.Errorf("could not read random id: %v", err)
+	}
+
+	return json.Marshal(encryptedKeyJSON{
+		PublicKey: hex.EncodeToString(key.PublicKey.Marshal()),
+		Crypto: cryptoJSON{
+			Cipher:       cipherName,
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherParamsJSON{IV: hex.EncodeToString(iv)},
+			KDF:          keyHeaderKDF,
+			KDFParams: map[string]interface{}{
+				"n":     scryptN,
+				"r":     scryptR,
+				"p":     scryptP,
+				"dklen": scryptDKLen,
+				"salt":  hex.EncodeToString(salt),
+			},
+			MAC: hex.EncodeToString(mac),
+		},
+		ID:      formatUUID(id),
+		Version: version,
+	})
+}
+
+// DecryptKey decrypts a key from a JSON blob, returning the BLS key pair.
+func DecryptKey(keyJSON []byte, password string) (*Key, error) {
+	k := new(encryptedKeyJSON)
+	if err := json.Unmarshal(keyJSON, k); err != nil {
+		return nil, fmt.Errorf("could not parse key file: %v", err)
+	}
+	if k.Version != version {
+		return nil, fmt.Errorf("unsupported key file version: %d", k.Version)
+	}
+	if k.Crypto.Cipher != cipherName {
+		return nil, fmt.Errorf("cipher not supported: %v", k.Crypto.Cipher)
+	}
+	if k.Crypto.KDF != keyHeaderKDF {
+		return nil, fmt.Errorf("kdf not supported: %v", k.Crypto.KDF)
+	}
+
+	mac, err := hex.DecodeString(k.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(k.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := hex.DecodeString(paramString(k.Crypto.KDFParams, "salt"))
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, err := scrypt.Key(
+		[]byte(password),
+		salt,
+		paramInt(k.Crypto.KDFParams, "n"),
+		paramInt(k.Crypto.KDFParams, "r"),
+		paramInt(k.Crypto.KDFParams, "p"),
+		paramInt(k.Crypto.KDFParams, "dklen"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(derivedKey) < 32 {
+		return nil, errors.New("derived key is too short")
+	}
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, ErrDecrypt
+	}
+
+	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := bls.SecretKeyFromBytes(plainText)
+	if err != nil {
+		return nil, fmt.Errorf("could not deserialize secret key: %v", err)
+	}
+	key := &Key{PublicKey: sk.PublicKey(), SecretKey: sk}
+	if hex.EncodeToString(key.PublicKey.Marshal()) != k.PublicKey {
+		return nil, errors.New("decrypted secret key does not match the stored public key")
+	}
+	return key, nil
+}
+
+// StoreKey encrypts a key with the password and writes it to path,
+// creating any missing parent directories.
+func StoreKey(path string, key *Key, password string, scryptN, scryptP int) error {
+	keyJSON, err := EncryptKey(key, password, scryptN, scryptP)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, keyJSON, 0600)
+}
+
+// GetKey reads the key file at path and decrypts it with the password.
+func GetKey(path string, password string) (*Key, error) {
+	keyJSON, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptKey(keyJSON, password)
+}
+
+func aesCTRXOR(key, in, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	stream := cipher.NewCTR(block, iv)
+	out := make([]byte, len(in))
+	stream.XORKeyStream(out, in)
+	return out, nil
+}
+
+func paramInt(params map[string]interface{}, name string) int {
+	// JSON numbers are decoded as float64.
+	f, _ := params[name].(float64)
+	return int(f)
+}
+
+func paramString(params map[string]interface{}, name string) string {
+	s, _ := params[name].(string)
+	return s
+}
+
+// formatUUID formats 16 random bytes as a version 4 UUID.
+func formatUUID(b []byte) string {
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,69 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptDecryptKey(t *testing.T) {
+	key, err := NewKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Could not generate key: %v", err)
+	}
+	keyJSON, err := EncryptKey(key, "password", LightScryptN, LightScryptP)
+	if err != nil {
+		t.Fatalf("Could not encrypt key: %v", err)
+	}
+	decrypted, err := DecryptKey(keyJSON, "password")
+	if err != nil {
+		t.Fatalf("Could not decrypt key: %v", err)
+	}
+	if !bytes.Equal(decrypted.SecretKey.Marshal(), key.SecretKey.Marshal()) {
+		t.Errorf("Decrypted secret key does not match original")
+	}
+	if !bytes.Equal(decrypted.PublicKey.Marshal(), key.PublicKey.Marshal()) {
+		t.Errorf("Decrypted public key does not match original")
+	}
+}
+
+func TestDecryptKey_WrongPassword(t *testing.T) {
+	key, err := NewKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Could not generate key: %v", err)
+	}
+	keyJSON, err := EncryptKey(key, "password", LightScryptN, LightScryptP)
+	if err != nil {
+		t.Fatalf("Could not encrypt key: %v", err)
+	}
+	if _, err := DecryptKey(keyJSON, "wrong"); err != ErrDecrypt {
+		t.Errorf("Expected %v, received %v", ErrDecrypt, err)
+	}
+}
+
+func TestStoreGetKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := NewKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Could not generate key: %v", err)
+	}
+	path := filepath.Join(dir, "keys", "validator")
+	if err := StoreKey(path, key, "password", LightScryptN, LightScryptP); err != nil {
+		t.Fatalf("Could not store key: %v", err)
+	}
+	loaded, err := GetKey(path, "password")
+	if err != nil {
+		t.Fatalf("Could not load key: %v", err)
+	}
+	if !bytes.Equal(loaded.SecretKey.Marshal(), key.SecretKey.Marshal()) {
+		t.Errorf("Loaded secret key does not match stored key")
+	}
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Runs the same checks against the in-memory and the LevelDB backed database,
+// so that tests using the in-memory database behave like the nodes.
+func TestDatabaseConformance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conformance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dbs := map[string]func(t *testing.T) *DB{
+		"KVStore": func(t *testing.T) *DB {
+			db, err := NewDB(&DBConfig{InMemory: true})
+			if err != nil {
+				t.Fatalf("could not initialize a new DB: %v", err)
+			}
+			return db
+		},
+		"LevelDB": func(t *testing.T) *DB {
+			db, err := NewDB(&DBConfig{DataDir: dir, Name: t.Name()})
+			if err != nil {
+				t.Fatalf("could not initialize a new DB: %v", err)
+			}
+			return db
+		},
+	}
+	tests := map[string]func(t *testing.T, db *DB){
+		"PutGetDelete": testPutGetDelete,
+		"Batch":        testBatch,
+		"BatchReset":   testBatchReset,
+		"Iterator":     testIterator,
+	}
+	for name, newDB := range dbs {
+		for test, run := range tests {
+			newDB, run := newDB, run
+			t.Run(name+"/"+test, func(t *testing.T) {
+				db := newDB(t)
+				defer db.Close()
+				run(t, db)
+			})
+		}
+	}
+}
+
+func testPutGetDelete(t *testing.T, db *DB) {
+	key, val := []byte("ralph merkle"), []byte{1, 2, 3}
+	if err := db.DB().Put(key, val); err != nil {
+		t.Fatalf("could not save value in db: %v", err)
+	}
+	// The stored value must not change with the slice it was saved from.
+	val[0] = 9
+	got, err := db.DB().Get(key)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if !bytes.Equal(got, []byte{1, 2, 3}) {
+		t.Errorf("expected value %v, got %v", []byte{1, 2, 3}, got)
+	}
+
+	// Keys longer than a hash are distinct.
+	long := bytes.Repeat([]byte{1}, 40)
+	if err := db.DB().Put(append([]byte{0}, long...), []byte{1}); err != nil {
+		t.Fatalf("could not save value in db: %v", err)
+	}
+	if has, _ := db.DB().Has(append([]byte{2}, long...)); has {
+		t.Error("db should not have a key sharing the suffix of another key")
+	}
+
+	if err := db.DB().Delete(key); err != nil {
+		t.Fatalf("could not delete key: %v", err)
+	}
+	if has, err := db.DB().Has(key); err != nil || has {
+		t.Errorf("db should not have deleted key, got %v, %v", has, err)
+	}
+	if _, err := db.DB().Get(key); err == nil {
+		t.Error("get of deleted key should have returned an error")
+	}
+}
+
+func testBatch(t *testing.T, db *DB) {
+	if err := db.DB().Put([]byte("a"), []byte{1}); err != nil {
+		t.Fatalf("could not save value in db: %v", err)
+	}
+
+	batch := db.DB().NewBatch()
+	if err := batch.Put([]byte("b"), []byte{2, 2}); err != nil {
+		t.Fatalf("could not add put to batch: %v", err)
+	}
+	if err := batch.Put([]byte("c"), []byte{3, 3, 3}); err != nil {
+		t.Fatalf("could not add put to batch: %v", err)
+	}
+	if err := batch.Delete([]byte("a")); err != nil {
+		t.Fatalf("could not add delete to batch: %v", err)
+	}
+	if size := batch.ValueSize(); size < 5 {
+		t.Errorf("expected a batch value size of at least 5, got %d", size)
+	}
+
+	if has, _ := db.DB().Has([]byte("b")); has {
+		t.Error("batch should not be written before Write")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("could not write batch: %v", err)
+	}
+	for key, want := range map[string][]byte{"b": {2, 2}, "c": {3, 3, 3}} {
+		got, err := db.DB().Get([]byte(key))
+		if err != nil {
+			t.Fatalf("get of %s failed: %v", key, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("expected value %v for %s, got %v", want, key, got)
+		}
+	}
+	if has, _ := db.DB().Has([]byte("a")); has {
+		t.Error("db should not have key deleted by batch")
+	}
+}
+
+func testBatchReset(t *testing.T, db *DB) {
+	batch := db.DB().NewBatch()
+	if err := batch.Put([]byte("a"), []byte{1}); err != nil {
+		t.Fatalf("could not add put to batch: %v", err)
+	}
+	batch.Reset()
+	if size := batch.ValueSize(); size != 0 {
+		t.Errorf("expected an empty batch after Reset, got value size %d", size)
+	}
+	if err := batch.Put([]byte("b"), []byte{2}); err != nil {
+		t.Fatalf("could not add put to batch: %v", err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("could not write batch: %v", err)
+	}
+	if has, _ := db.DB().Has([]byte("a")); has {
+		t.Error("db should not have key put before Reset")
+	}
+	if has, _ := db.DB().Has([]byte("b")); !has {
+		t.Error("db should have key put after Reset")
+	}
+}
+
+func testIterator(t *testing.T, db *DB) {
+	for _, key := range []string{"block/3", "block/1", "state/1", "block/2", "blocks"} {
+		if err := db.DB().Put([]byte(key), []byte(key)); err != nil {
+			t.Fatalf("could not save value in db: %v", err)
+		}
+	}
+
+	it := db.NewIteratorWithPrefix([]byte("block/"))
+	defer it.Release()
+	// Writes after the creation of the iterator are not iterated over.
+	if err := db.DB().Put([]byte("block/0"), []byte("block/0")); err != nil {
+		t.Fatalf("could not save value in db: %v", err)
+	}
+
+	var keys []string
+	for it.Next() {
+		if !bytes.Equal(it.Key(), it.Value()) {
+			t.Errorf("expected value %s for key %s, got %s", it.Key(), it.Key(), it.Value())
+		}
+		keys = append(keys, string(it.Key()))
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if fmt.Sprint(keys) != "[block/1 block/2 block/3]" {
+		t.Errorf("expected keys with the prefix in order, got %v", keys)
+	}
+
+	if !it.Seek([]byte("block/15")) || string(it.Key()) != "block/2" {
+		t.Errorf("expected seek to the next key, got %s", it.Key())
+	}
+	if !it.Last() || string(it.Key()) != "block/3" {
+		t.Errorf("expected the last key with the prefix, got %s", it.Key())
+	}
+}
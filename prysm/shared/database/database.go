@@ -8,13 +8,21 @@ import (
 
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 )
 
 var log = logrus.WithField("prefix", "db")
 
 // DB defines a service for the beacon chain system's persistent storage.
 type DB struct {
-	_db ethdb.Database
+	_db database
+}
+
+// database is an ethdb.Database that can iterate over its keys in order. It
+// is implemented by both KVStore and the LevelDB database.
+type database interface {
+	ethdb.Database
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
 }
 
 // DBConfig specifies configuration options for the db service.
@@ -53,3 +61,10 @@ func (b *DB) Close() {
 func (b *DB) DB() ethdb.Database {
 	return b._db
 }
+
+// NewIteratorWithPrefix returns an iterator over the keys of the database
+// starting with the prefix, in ascending order. The iterator must be
+// released after use.
+func (b *DB) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	return b._db.NewIteratorWithPrefix(prefix)
+}
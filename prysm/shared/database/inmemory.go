@@ -1,29 +1,33 @@
 package database
 
 import (
+	"bytes"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 )
 
-// KVStore is an in-memory mapping of hashes to RLP encoded values.
+// KVStore is an in-memory mapping of keys to RLP encoded values.
 type KVStore struct {
-	kv   map[common.Hash][]byte
+	kv   map[string][]byte
 	lock sync.RWMutex
 }
 
 // NewKVStore creates an in-memory, key-value store.
 func NewKVStore() *KVStore {
-	return &KVStore{kv: make(map[common.Hash][]byte)}
+	return &KVStore{kv: make(map[string][]byte)}
 }
 
 // Get fetches a val from the mappping by key.
 func (s *KVStore) Get(k []byte) ([]byte, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
-	v, ok := s.kv[common.BytesToHash(k)]
+	v, ok := s.kv[string(k)]
 	if !ok {
 		return []byte{}, fmt.Errorf("key not found: %v", k)
 	}
@@ -34,8 +38,8 @@ func (s *KVStore) Get(k []byte) ([]byte, error) {
 func (s *KVStore) Has(k []byte) (bool, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
-	v := s.kv[common.BytesToHash(k)]
-	return v != nil, nil
+	_, ok := s.kv[string(k)]
+	return ok, nil
 }
 
 // Put updates a key's value in the mapping.
@@ -43,7 +47,7 @@ func (s *KVStore) Put(k []byte, v []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	// there is no error in a simple setting of a value in a go map.
-	s.kv[common.BytesToHash(k)] = v
+	s.kv[string(k)] = common.CopyBytes(v)
 	return nil
 }
 
@@ -52,7 +56,7 @@ func (s *KVStore) Delete(k []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	// There is no return value for deleting a simple key in a go map.
-	delete(s.kv, common.BytesToHash(k))
+	delete(s.kv, string(k))
 	return nil
 }
 
@@ -62,9 +66,102 @@ func (s *KVStore) Close() {
 	log.Debug("ShardKV Close() isnt implemented yet")
 }
 
-// NewBatch satisfies ethdb.Database.
+// NewBatch creates a batch of writes, applied to the mapping at once when
+// written.
 func (s *KVStore) NewBatch() ethdb.Batch {
-	//TODO: Implement NewBatch for KVStore
-	log.Debug("ShardKV NewBatch() isnt implemented yet")
+	return &kvBatch{store: s}
+}
+
+// NewIteratorWithPrefix returns an iterator over the keys starting with the
+// prefix, in ascending order. The iterator is a snapshot of the mapping, and
+// is not affected by later writes.
+func (s *KVStore) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	var entries kvEntries
+	for k, v := range s.kv {
+		if strings.HasPrefix(k, string(prefix)) {
+			entries = append(entries, kvEntry{key: []byte(k), value: v})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+	return iterator.NewArrayIterator(entries)
+}
+
+type kvEntry struct {
+	key   []byte
+	value []byte
+}
+
+// kvEntries are the entries of the mapping sorted by key, iterated over by
+// an array iterator.
+type kvEntries []kvEntry
+
+func (e kvEntries) Len() int {
+	return len(e)
+}
+
+func (e kvEntries) Search(key []byte) int {
+	return sort.Search(len(e), func(i int) bool {
+		return bytes.Compare(e[i].key, key) >= 0
+	})
+}
+
+func (e kvEntries) Index(i int) ([]byte, []byte) {
+	return e[i].key, e[i].value
+}
+
+// kvWrite is the update or removal of a key in a batch.
+type kvWrite struct {
+	kvEntry
+	delete bool
+}
+
+// kvBatch is a batch of writes to a KVStore.
+type kvBatch struct {
+	store  *KVStore
+	writes []kvWrite
+	size   int
+}
+
+// Put adds the update of a key's value to the batch.
+func (b *kvBatch) Put(k []byte, v []byte) error {
+	b.writes = append(b.writes, kvWrite{kvEntry: kvEntry{key: common.CopyBytes(k), value: common.CopyBytes(v)}})
+	b.size += len(v)
 	return nil
 }
+
+// Delete adds the removal of a key to the batch.
+func (b *kvBatch) Delete(k []byte) error {
+	b.writes = append(b.writes, kvWrite{kvEntry: kvEntry{key: common.CopyBytes(k)}, delete: true})
+	b.size++
+	return nil
+}
+
+// ValueSize returns the amount of data in the batch.
+func (b *kvBatch) ValueSize() int {
+	return b.size
+}
+
+// Write applies the writes of the batch to the mapping, in the order they
+// were added.
+func (b *kvBatch) Write() error {
+	b.store.lock.Lock()
+	defer b.store.lock.Unlock()
+	for _, w := range b.writes {
+		if w.delete {
+			delete(b.store.kv, string(w.key))
+			continue
+		}
+		b.store.kv[string(w.key)] = w.value
+	}
+	return nil
+}
+
+// Reset empties the batch for reuse.
+func (b *kvBatch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
@@ -0,0 +1,75 @@
+package p2p
+
+import (
+	"errors"
+
+	"github.com/golang/snappy"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// Suffix of the gossipsub topics carrying snappy compressed messages. Every
+// node joins both the plain and the compressed variant of its topics, so
+// messages are decoded according to the topic they arrive on and nodes
+// publishing compressed messages can be mixed with nodes that don't.
+const snappySuffix = "/snappy"
+
+// Maximum size of a gossipsub RPC, as enforced by gossipsub, and of the
+// payload of a gossip message, which leaves room in the RPC for the other
+// fields of the message. Larger messages, such as collation bodies of up to
+// DefaultCollationSizeLimit (1 MiB), are only sent over request streams.
+const (
+	maxGossipRPCSize     = 1 << 20
+	maxGossipPayloadSize = maxGossipRPCSize - 4<<10
+)
+
+// Maximum size of an uncompressed gossip message, unless configured
+// otherwise for its topic.
+const defaultMaxMessageSize = maxGossipPayloadSize
+
+var errMessageTooLarge = errors.New("message exceeds maximum size")
+
+// topicName returns the name of the compressed or plain variant of a topic.
+func topicName(name string, compressed bool) string {
+	if compressed {
+		return name + snappySuffix
+	}
+	return name
+}
+
+// encodePayload returns the payload published for the protobuf encoding of a
+// message.
+func encodePayload(b []byte, compressed bool) []byte {
+	if compressed {
+		return snappy.Encode(nil, b)
+	}
+	return b
+}
+
+// decodePayload returns the protobuf encoding of the message published in a
+// payload. It fails if the message exceeds maxSize, which is checked before
+// anything is decompressed.
+func decodePayload(data []byte, compressed bool, maxSize int) ([]byte, error) {
+	if !compressed {
+		if len(data) > maxSize {
+			return nil, errMessageTooLarge
+		}
+		return data, nil
+	}
+	n, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxSize {
+		return nil, errMessageTooLarge
+	}
+	return snappy.Decode(nil, data)
+}
+
+// maxMessageSize returns the maximum size of an uncompressed message of the
+// topic.
+func (s *Server) maxMessageSize(topic pb.Topic) int {
+	if size, ok := s.maxMessageSizes[topic]; ok {
+		return size
+	}
+	return defaultMaxMessageSize
+}
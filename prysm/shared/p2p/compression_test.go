@@ -0,0 +1,55 @@
+package p2p
+
+import (
+	"bytes"
+	"testing"
+
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+func TestDecodePayload(t *testing.T) {
+	msg := bytes.Repeat([]byte("collation"), 100)
+	for _, compressed := range []bool{false, true} {
+		got, err := decodePayload(encodePayload(msg, compressed), compressed, len(msg))
+		if err != nil {
+			t.Fatalf("Could not decode payload (compressed: %v): %v", compressed, err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Errorf("Decoded payload (compressed: %v) does not match the message", compressed)
+		}
+		if _, err := decodePayload(encodePayload(msg, compressed), compressed, len(msg)-1); err != errMessageTooLarge {
+			t.Errorf("Expected oversized payload (compressed: %v) to be refused, got %v", compressed, err)
+		}
+	}
+
+	// A small compressed payload that decompresses to a large message is
+	// refused without being decompressed.
+	bomb := encodePayload(make([]byte, 2*defaultMaxMessageSize), true)
+	if len(bomb) > defaultMaxMessageSize {
+		t.Fatalf("Expected zeroes to compress, got %d bytes", len(bomb))
+	}
+	if _, err := decodePayload(bomb, true, defaultMaxMessageSize); err != errMessageTooLarge {
+		t.Errorf("Expected compressed oversized payload to be refused, got %v", err)
+	}
+
+	if _, err := decodePayload([]byte{0xff, 0xff}, true, defaultMaxMessageSize); err == nil {
+		t.Error("Expected malformed compressed payload to be refused")
+	}
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	s := &Server{maxMessageSizes: map[pb.Topic]int{pb.Topic_TRANSACTIONS: 10}}
+	tests := []struct {
+		topic pb.Topic
+		want  int
+	}{
+		{pb.Topic_TRANSACTIONS, 10},
+		{pb.Topic_COLLATION_BODY_RESPONSE, defaultMaxMessageSize},
+		{pb.Topic_BEACON_BLOCK_REQUEST, defaultMaxMessageSize},
+	}
+	for _, tt := range tests {
+		if got := s.maxMessageSize(tt.topic); got != tt.want {
+			t.Errorf("maxMessageSize(%v) = %d. wanted %d", tt.topic, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package p2p
+
+import (
+	"sync"
+	"time"
+
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// Maximum number of connected peers, unless configured otherwise. Above it,
+// connections are trimmed down to the target peer count.
+const defaultMaxPeers = 50
+
+// Maximum number of peers whose shards are remembered after they disconnect.
+const maxKnownShards = 1024
+
+// connInfo describes a connected peer.
+type connInfo struct {
+	inbound bool
+	// handshake is nil until the peer completed the handshake.
+	handshake *pb.Handshake
+	// status is the latest status received from the peer, at updated.
+	status  *pb.Status
+	updated time.Time
+}
+
+// connManager keeps track of the connected peers and keeps their number
+// between watermarks. Once the number of peers exceeds the high watermark,
+// connections are closed until it falls to the low watermark. Protected
+// peers are never disconnected.
+type connManager struct {
+	host      host.Host
+	lowWater  int
+	highWater int
+	// rank orders the peers that can be disconnected, the first ones are
+	// disconnected first.
+	rank func(ids []peer.ID)
+	// onConnect is called in a new goroutine for every new connection.
+	onConnect func(conn inet.Conn)
+	// banned reports whether a peer is banned. Connections of banned peers
+	// are closed.
+	banned func(id peer.ID) bool
+
+	lock      sync.Mutex
+	peers     map[peer.ID]*connInfo
+	protected map[peer.ID]bool
+	trimming  bool
+	// shards holds the shards of the peers from their latest handshake, and
+	// is kept after they disconnect to choose which peers to dial.
+	shards map[peer.ID][]uint64
+}
+
+func newConnManager(h host.Host, lowWater, highWater int) *connManager {
+	return &connManager{
+		host:      h,
+		lowWater:  lowWater,
+		highWater: highWater,
+		peers:     make(map[peer.ID]*connInfo),
+		protected: make(map[peer.ID]bool),
+		shards:    make(map[peer.ID][]uint64),
+	}
+}
+
+// protect prevents the connection to the peer from being trimmed.
+func (cm *connManager) protect(id peer.ID) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	cm.protected[id] = true
+}
+
+// counts returns the number of inbound and outbound connected peers.
+func (cm *connManager) counts() (inbound, outbound int) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	for _, info := range cm.peers {
+		if info.inbound {
+			inbound++
+		} else {
+			outbound++
+		}
+	}
+	return inbound, outbound
+}
+
+// info returns a copy of what is known about a connected peer.
+func (cm *connManager) info(id peer.ID) (connInfo, bool) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	info, ok := cm.peers[id]
+	if !ok {
+		return connInfo{}, false
+	}
+	return *info, true
+}
+
+// setHandshake records the handshake of a connected peer.
+func (cm *connManager) setHandshake(id peer.ID, hs *pb.Handshake) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	info, ok := cm.peers[id]
+	if !ok {
+		return
+	}
+	info.handshake = hs
+	if _, known := cm.shards[id]; !known && len(cm.shards) >= maxKnownShards {
+		// Forget any other peer to make room.
+		for other := range cm.shards {
+			delete(cm.shards, other)
+			break
+		}
+	}
+	cm.shards[id] = hs.Shards
+}
+
+// peerShards returns the shards of a peer from its latest handshake.
+func (cm *connManager) peerShards(id peer.ID) []uint64 {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	return cm.shards[id]
+}
+
+// handshaked lists the peers that completed the handshake.
+func (cm *connManager) handshaked() []peer.ID {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	var ids []peer.ID
+	for id, info := range cm.peers {
+		if info.handshake != nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// setStatus records the latest status of a connected peer.
+func (cm *connManager) setStatus(id peer.ID, status *pb.Status) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	if info, ok := cm.peers[id]; ok {
+		info.status = status
+		info.updated = time.Now()
+	}
+}
+
+// statuses lists the latest status of the connected peers that sent one.
+func (cm *connManager) statuses() []PeerStatus {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	var statuses []PeerStatus
+	for id, info := range cm.peers {
+		if info.status != nil {
+			statuses = append(statuses, PeerStatus{
+				Peer:    Peer{ID: id.Pretty()},
+				Status:  info.status,
+				Updated: info.updated,
+			})
+		}
+	}
+	return statuses
+}
+
+// Connected records a new connection, trimming the connections if there are
+// too many. Banned peers are disconnected instead.
+func (cm *connManager) Connected(n inet.Network, conn inet.Conn) {
+	id := conn.RemotePeer()
+	if cm.banned != nil && cm.banned(id) {
+		log.Debugf("Disconnecting banned peer %s", id.Pretty())
+		// Closing the peer from a notification would deadlock the network.
+		go func() {
+			if err := n.ClosePeer(id); err != nil {
+				log.Errorf("Could not disconnect peer %s: %v", id.Pretty(), err)
+			}
+		}()
+		return
+	}
+	cm.lock.Lock()
+	_, known := cm.peers[id]
+	if !known {
+		cm.peers[id] = &connInfo{inbound: conn.Stat().Direction == inet.DirInbound}
+	}
+	trim := len(cm.peers) > cm.highWater
+	cm.lock.Unlock()
+
+	if known {
+		return
+	}
+	log.Debugf("Connected to peer %s", id.Pretty())
+	if cm.onConnect != nil {
+		go cm.onConnect(conn)
+	}
+	if trim {
+		go cm.trim()
+	}
+}
+
+// Disconnected forgets the peer once its last connection is closed.
+func (cm *connManager) Disconnected(n inet.Network, conn inet.Conn) {
+	id := conn.RemotePeer()
+	if n.Connectedness(id) == inet.Connected {
+		return
+	}
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	delete(cm.peers, id)
+}
+
+// Listen is part of the inet.Notifiee interface.
+func (cm *connManager) Listen(inet.Network, ma.Multiaddr) {}
+
+// ListenClose is part of the inet.Notifiee interface.
+func (cm *connManager) ListenClose(inet.Network, ma.Multiaddr) {}
+
+// OpenedStream is part of the inet.Notifiee interface.
+func (cm *connManager) OpenedStream(inet.Network, inet.Stream) {}
+
+// ClosedStream is part of the inet.Notifiee interface.
+func (cm *connManager) ClosedStream(inet.Network, inet.Stream) {}
+
+// trim closes the connections above the low watermark, if the number of
+// peers exceeds the high watermark.
+func (cm *connManager) trim() {
+	cm.lock.Lock()
+	if cm.trimming || len(cm.peers) <= cm.highWater {
+		cm.lock.Unlock()
+		return
+	}
+	cm.trimming = true
+	excess := len(cm.peers) - cm.lowWater
+	var candidates []peer.ID
+	for id := range cm.peers {
+		if !cm.protected[id] {
+			candidates = append(candidates, id)
+		}
+	}
+	cm.lock.Unlock()
+	defer func() {
+		cm.lock.Lock()
+		cm.trimming = false
+		cm.lock.Unlock()
+	}()
+
+	if cm.rank != nil {
+		cm.rank(candidates)
+	}
+	if excess > len(candidates) {
+		excess = len(candidates)
+	}
+	for _, id := range candidates[:excess] {
+		log.Debugf("Trimming connection to peer %s", id.Pretty())
+		if err := cm.host.Network().ClosePeer(id); err != nil {
+			log.Errorf("Could not disconnect peer %s: %v", id.Pretty(), err)
+		}
+	}
+}
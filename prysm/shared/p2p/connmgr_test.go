@@ -0,0 +1,207 @@
+package p2p
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+	pstore "github.com/libp2p/go-libp2p-peerstore"
+	swarmt "github.com/libp2p/go-libp2p-swarm/testing"
+	bhost "github.com/libp2p/go-libp2p/p2p/host/basic"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// waitFor polls the condition until it holds or the context is done.
+func waitFor(ctx context.Context, cond func() bool) bool {
+	for !cond() {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return true
+}
+
+func TestTrimConnections(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s := newTestServer(ctx, t)
+	s.conns.lowWater = 2
+	s.conns.highWater = 2
+
+	var peers []*Server
+	for i := 0; i < 3; i++ {
+		peers = append(peers, newTestServer(ctx, t))
+	}
+	// The protected peer is always kept, so the lowest scored of the other
+	// peers is disconnected.
+	s.conns.protect(peers[0].host.ID())
+	s.ReportPeer(Peer{ID: peers[1].host.ID().Pretty()}, 5)
+	s.ReportPeer(Peer{ID: peers[2].host.ID().Pretty()}, -5)
+	for _, p := range peers {
+		if err := s.host.Connect(ctx, pstore.PeerInfo{ID: p.host.ID(), Addrs: p.host.Addrs()}); err != nil {
+			t.Fatalf("Could not connect hosts: %v", err)
+		}
+	}
+
+	trimmed := waitFor(ctx, func() bool {
+		return !containsPeer(s.host.Network().Peers(), peers[2].host.ID())
+	})
+	if !trimmed {
+		t.Fatal("Expected the lowest scored peer to be disconnected")
+	}
+	connected := s.host.Network().Peers()
+	if !containsPeer(connected, peers[0].host.ID()) || !containsPeer(connected, peers[1].host.ID()) {
+		t.Errorf("Expected the protected peer and the best scored peer to be kept, got %v", connected)
+	}
+	if inbound, outbound := s.conns.counts(); inbound != 0 || outbound != 2 {
+		t.Errorf("Expected 0 inbound and 2 outbound peers, got %d and %d", inbound, outbound)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	a, b := newTestServer(ctx, t), newTestServer(ctx, t)
+	a.networkID, b.networkID = 3, 3
+	b.SetStatusFunc(func() (*pb.Status, error) {
+		return &pb.Status{HeadSlot: 7, HeadHash: []byte{'h'}}, nil
+	})
+	b.joinShardTopic(2, messageType(pb.Transaction{}))
+
+	if err := a.host.Connect(ctx, pstore.PeerInfo{ID: b.host.ID(), Addrs: b.host.Addrs()}); err != nil {
+		t.Fatalf("Could not connect hosts: %v", err)
+	}
+
+	var md PeerMetadata
+	done := waitFor(ctx, func() bool {
+		var err error
+		md, err = a.PeerMetadata(Peer{ID: b.host.ID().Pretty()})
+		if err != nil {
+			t.Fatalf("Could not get peer metadata: %v", err)
+		}
+		return md.Handshake != nil
+	})
+	if !done {
+		t.Fatal("Handshake was not completed")
+	}
+	if md.Inbound {
+		t.Error("Expected the dialed peer to be outbound")
+	}
+	hs := md.Handshake
+	if hs.NetworkId != 3 || hs.HeadSlot != 7 || string(hs.HeadHash) != "h" {
+		t.Errorf("Unexpected handshake %v", hs)
+	}
+	if len(hs.Shards) != 1 || hs.Shards[0] != 2 {
+		t.Errorf("Expected shards [2], got %v", hs.Shards)
+	}
+
+	done = waitFor(ctx, func() bool {
+		md, _ := b.PeerMetadata(Peer{ID: a.host.ID().Pretty()})
+		return md.Handshake != nil && md.Inbound
+	})
+	if !done {
+		t.Error("Handshake of the dialing peer was not recorded")
+	}
+}
+
+func TestHandshakeNetworkMismatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	a, b := newTestServer(ctx, t), newTestServer(ctx, t)
+	a.networkID, b.networkID = 1, 2
+
+	if err := a.host.Connect(ctx, pstore.PeerInfo{ID: b.host.ID(), Addrs: b.host.Addrs()}); err != nil {
+		t.Fatalf("Could not connect hosts: %v", err)
+	}
+
+	disconnected := waitFor(ctx, func() bool {
+		return len(a.host.Network().Peers()) == 0 && len(b.host.Network().Peers()) == 0
+	})
+	if !disconnected {
+		t.Error("Expected peers of different networks to be disconnected")
+	}
+	// Peers of other networks are not dialed again right away.
+	if !a.bannedID(b.host.ID()) || !b.bannedID(a.host.ID()) {
+		t.Error("Expected peers of different networks to be banned")
+	}
+}
+
+func TestOutboundHandshakeFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s := newTestServer(ctx, t)
+	// A bare host does not answer the handshake.
+	h := bhost.New(swarmt.GenSwarm(t, ctx))
+	if err := s.host.Connect(ctx, pstore.PeerInfo{ID: h.ID(), Addrs: h.Addrs()}); err != nil {
+		t.Fatalf("Could not connect hosts: %v", err)
+	}
+	disconnected := waitFor(ctx, func() bool {
+		return len(s.host.Network().Peers()) == 0
+	})
+	if !disconnected {
+		t.Error("Expected dialed peer without handshake to be disconnected")
+	}
+}
+
+func TestBannedPeerDisconnected(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	a, b := newTestServer(ctx, t), newTestServer(ctx, t)
+	a.ReportPeer(Peer{ID: b.host.ID().Pretty()}, -1000)
+
+	// The ban does not prevent the banned peer from dialing.
+	if err := b.host.Connect(ctx, pstore.PeerInfo{ID: a.host.ID(), Addrs: a.host.Addrs()}); err != nil {
+		t.Fatalf("Could not connect hosts: %v", err)
+	}
+	disconnected := waitFor(ctx, func() bool {
+		return len(a.host.Network().Peers()) == 0
+	})
+	if !disconnected {
+		t.Error("Expected banned peer to be disconnected")
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { handshakeTimeout = timeout }(handshakeTimeout)
+	handshakeTimeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s := newTestServer(ctx, t)
+	// A bare host never opens the handshake stream.
+	h := bhost.New(swarmt.GenSwarm(t, ctx))
+	if err := h.Connect(ctx, pstore.PeerInfo{ID: s.host.ID(), Addrs: s.host.Addrs()}); err != nil {
+		t.Fatalf("Could not connect hosts: %v", err)
+	}
+	disconnected := waitFor(ctx, func() bool {
+		return len(s.host.Network().Peers()) == 0
+	})
+	if !disconnected {
+		t.Error("Expected peer without handshake to be disconnected")
+	}
+}
+
+func TestPreferShardPeers(t *testing.T) {
+	s := &Server{
+		mutex:       &sync.Mutex{},
+		shardTopics: map[string]uint64{"shard-1": 1},
+		conns:       newConnManager(nil, 1, 1),
+	}
+	a, b, c := peer.ID("a"), peer.ID("b"), peer.ID("c")
+	for id, shards := range map[peer.ID][]uint64{a: {2}, b: {1, 2}, c: nil} {
+		s.conns.peers[id] = &connInfo{}
+		s.conns.setHandshake(id, &pb.Handshake{Shards: shards})
+	}
+	// Shards are remembered once peers disconnect, to choose whom to dial.
+	s.conns.peers = make(map[peer.ID]*connInfo)
+
+	ids := []peer.ID{a, b, c}
+	s.preferShardPeers(ids)
+	if ids[0] != b || ids[1] != a || ids[2] != c {
+		t.Errorf("Expected peer b sharing shard 1 first in its original order, got %v", ids)
+	}
+}
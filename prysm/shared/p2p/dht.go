@@ -0,0 +1,408 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"sort"
+	"sync"
+	"time"
+
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	pstore "github.com/libp2p/go-libp2p-peerstore"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+	ma "github.com/multiformats/go-multiaddr"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// dhtProtocol is the libp2p protocol of the streams carrying discovery DHT
+// queries. Each stream carries a FindPeersRequest and its FindPeersResponse.
+const dhtProtocol = protocol.ID("/prysm/dht/1.0.0")
+
+const (
+	// Number of peers kept in a bucket of the routing table, and returned by
+	// a query.
+	bucketSize = 16
+	// Number of peers queried concurrently during a lookup.
+	lookupConcurrency = 3
+)
+
+// dht is a Kademlia distributed hash table used to discover peers. Peers are
+// identified by the SHA-256 hash of their ID, and every node answers queries
+// for the peers it knows closest to a key. Looking up a random key walks the
+// network and finds peers that are not reachable through mDNS.
+type dht struct {
+	host    host.Host
+	table   *routingTable
+	timeout time.Duration
+	// banned reports whether a peer is banned. Banned peers are neither
+	// queried nor handed out to other peers.
+	banned func(peer.ID) bool
+}
+
+// newDHT creates the DHT of the host and starts answering queries from
+// peers.
+func newDHT(h host.Host, timeout time.Duration, banned func(peer.ID) bool) *dht {
+	d := &dht{
+		host:    h,
+		table:   newRoutingTable(h.ID()),
+		timeout: timeout,
+		banned:  banned,
+	}
+	h.SetStreamHandler(dhtProtocol, d.handleStream)
+	return d
+}
+
+// handleStream answers a query from a peer with the closest peers to its
+// target. The querying peer is not added to the routing table, as any peer
+// can send queries. Peers are only added once they answer a query of the
+// node.
+func (d *dht) handleStream(stream inet.Stream) {
+	defer stream.Close()
+	remote := stream.Conn().RemotePeer()
+	if d.banned(remote) {
+		log.Debugf("Dropping DHT query from banned peer %s", remote.Pretty())
+		return
+	}
+	if err := stream.SetDeadline(time.Now().Add(d.timeout)); err != nil {
+		log.Errorf("Could not set stream deadline: %v", err)
+		return
+	}
+	req := &pb.FindPeersRequest{}
+	if err := readMessage(stream, req); err != nil {
+		log.Debugf("Could not read DHT query from peer %s: %v", remote.Pretty(), err)
+		return
+	}
+	if len(req.Target) != sha256.Size {
+		log.Debugf("Peer %s sent a DHT query with an invalid target", remote.Pretty())
+		return
+	}
+
+	resp := &pb.FindPeersResponse{}
+	for _, id := range d.table.closest(req.Target, bucketSize) {
+		if id == remote || d.banned(id) {
+			continue
+		}
+		addr := &pb.PeerAddress{Id: []byte(id)}
+		for _, a := range d.host.Peerstore().Addrs(id) {
+			addr.Addrs = append(addr.Addrs, a.Bytes())
+		}
+		resp.Peers = append(resp.Peers, addr)
+	}
+	if err := writeMessage(stream, resp); err != nil {
+		log.Debugf("Could not answer DHT query from peer %s: %v", remote.Pretty(), err)
+	}
+}
+
+// findPeers asks a peer for the peers it knows closest to the target. Only
+// the first bucketSize peers of the answer are kept, so that a peer cannot
+// flood the lookup with candidates.
+func (d *dht) findPeers(ctx context.Context, id peer.ID, target []byte) ([]pstore.PeerInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	stream, err := d.host.NewStream(ctx, id, dhtProtocol)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	if err := stream.SetDeadline(time.Now().Add(d.timeout)); err != nil {
+		return nil, err
+	}
+	if err := writeMessage(stream, &pb.FindPeersRequest{Target: target}); err != nil {
+		return nil, err
+	}
+	resp := &pb.FindPeersResponse{}
+	if err := readMessage(stream, resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Peers) > bucketSize {
+		log.Debugf("Peer %s returned %d peers, keeping %d", id.Pretty(), len(resp.Peers), bucketSize)
+		resp.Peers = resp.Peers[:bucketSize]
+	}
+	peers := make([]pstore.PeerInfo, 0, len(resp.Peers))
+	for _, p := range resp.Peers {
+		pid, err := peer.IDFromBytes(p.Id)
+		if err != nil {
+			log.Debugf("Peer %s returned an invalid peer ID: %v", id.Pretty(), err)
+			continue
+		}
+		pi := pstore.PeerInfo{ID: pid}
+		for _, b := range p.Addrs {
+			addr, err := ma.NewMultiaddrBytes(b)
+			if err != nil {
+				continue
+			}
+			pi.Addrs = append(pi.Addrs, addr)
+		}
+		peers = append(peers, pi)
+	}
+	return peers, nil
+}
+
+type queryResult struct {
+	id    peer.ID
+	peers []pstore.PeerInfo
+	err   error
+}
+
+// add adds a peer that answered a query to the routing table. If its bucket
+// is full, the least recently seen peer of the bucket is queried and the new
+// peer replaces it only if it does not answer, as in Kademlia.
+func (d *dht) add(ctx context.Context, id peer.ID) {
+	oldest, full := d.table.update(id)
+	if !full {
+		return
+	}
+	if _, err := d.findPeers(ctx, oldest, dhtKey(d.host.ID())); err != nil {
+		log.Debugf("Evicting unresponsive peer %s from the routing table: %v", oldest.Pretty(), err)
+		d.table.replace(oldest, id)
+		return
+	}
+	d.table.update(oldest)
+}
+
+// lookup iteratively queries the closest known peers to the target for
+// closer peers, until the closest peers have all been queried. It returns
+// the closest peers that answered. Peers that fail to answer are removed
+// from the routing table, and peers that answer are added to it. Banned
+// peers are never queried.
+func (d *dht) lookup(ctx context.Context, target []byte) []peer.ID {
+	seen := map[peer.ID]bool{d.host.ID(): true}
+	queried := make(map[peer.ID]bool)
+	failed := make(map[peer.ID]bool)
+	var candidates []peer.ID
+	for _, id := range d.table.closest(target, bucketSize) {
+		seen[id] = true
+		if !d.banned(id) {
+			candidates = append(candidates, id)
+		}
+	}
+
+	for ctx.Err() == nil {
+		var batch []peer.ID
+		for _, id := range candidates {
+			if !queried[id] {
+				batch = append(batch, id)
+			}
+			if len(batch) == lookupConcurrency {
+				break
+			}
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		results := make(chan queryResult, len(batch))
+		for _, id := range batch {
+			queried[id] = true
+			go func(id peer.ID) {
+				peers, err := d.findPeers(ctx, id, target)
+				results <- queryResult{id, peers, err}
+			}(id)
+		}
+		for range batch {
+			r := <-results
+			if r.err != nil {
+				log.Debugf("DHT query to peer %s failed: %v", r.id.Pretty(), r.err)
+				failed[r.id] = true
+				d.table.remove(r.id)
+				continue
+			}
+			d.add(ctx, r.id)
+			for _, pi := range r.peers {
+				if seen[pi.ID] {
+					continue
+				}
+				seen[pi.ID] = true
+				if d.banned(pi.ID) {
+					continue
+				}
+				d.host.Peerstore().AddAddrs(pi.ID, pi.Addrs, pstore.TempAddrTTL)
+				candidates = append(candidates, pi.ID)
+			}
+		}
+
+		var alive []peer.ID
+		for _, id := range candidates {
+			if !failed[id] {
+				alive = append(alive, id)
+			}
+		}
+		candidates = sortByDistance(alive, target)
+		if len(candidates) > bucketSize {
+			candidates = candidates[:bucketSize]
+		}
+	}
+
+	var found []peer.ID
+	for _, id := range candidates {
+		if queried[id] && !failed[id] {
+			found = append(found, id)
+		}
+	}
+	return found
+}
+
+// randomKey returns a random key to walk the DHT towards.
+func randomKey() []byte {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		log.Errorf("Could not generate random key: %v", err)
+	}
+	return key
+}
+
+// dhtKey is the key of a peer in the DHT key space.
+func dhtKey(id peer.ID) []byte {
+	h := sha256.Sum256([]byte(id))
+	return h[:]
+}
+
+// routingTable holds the peers of the DHT in buckets by the length of the
+// common prefix of their key with the key of the node. Every bucket holds the
+// most recently seen peers last, and is full at bucketSize peers. As in
+// Kademlia, known peers are preferred to new ones when a bucket is full, and
+// are only replaced once they stop answering.
+type routingTable struct {
+	self    []byte
+	lock    sync.RWMutex
+	buckets [sha256.Size * 8][]peer.ID
+}
+
+func newRoutingTable(self peer.ID) *routingTable {
+	return &routingTable{self: dhtKey(self)}
+}
+
+// update marks the peer as seen, adding it to its bucket if there is room.
+// If the bucket is full, the peer is not added and the least recently seen
+// peer of the bucket is returned along with true.
+func (rt *routingTable) update(id peer.ID) (peer.ID, bool) {
+	i := rt.bucketIndex(id)
+	if i < 0 {
+		return "", false
+	}
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	bucket := rt.buckets[i]
+	for j, p := range bucket {
+		if p == id {
+			rt.buckets[i] = append(append(bucket[:j], bucket[j+1:]...), id)
+			return "", false
+		}
+	}
+	if len(bucket) < bucketSize {
+		rt.buckets[i] = append(bucket, id)
+		return "", false
+	}
+	return bucket[0], true
+}
+
+// replace evicts old from the bucket of the peer and adds the peer in its
+// place, unless old was seen again in the meantime.
+func (rt *routingTable) replace(old peer.ID, id peer.ID) {
+	i := rt.bucketIndex(id)
+	if i < 0 {
+		return
+	}
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	bucket := rt.buckets[i]
+	if len(bucket) == 0 || bucket[0] != old {
+		return
+	}
+	for _, p := range bucket {
+		if p == id {
+			return
+		}
+	}
+	rt.buckets[i] = append(bucket[1:], id)
+}
+
+// remove drops the peer from the routing table.
+func (rt *routingTable) remove(id peer.ID) {
+	i := rt.bucketIndex(id)
+	if i < 0 {
+		return
+	}
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	bucket := rt.buckets[i]
+	for j, p := range bucket {
+		if p == id {
+			rt.buckets[i] = append(bucket[:j], bucket[j+1:]...)
+			return
+		}
+	}
+}
+
+// closest returns up to count peers of the table, closest to the target
+// first.
+func (rt *routingTable) closest(target []byte, count int) []peer.ID {
+	rt.lock.RLock()
+	var peers []peer.ID
+	for _, bucket := range rt.buckets {
+		peers = append(peers, bucket...)
+	}
+	rt.lock.RUnlock()
+	peers = sortByDistance(peers, target)
+	if len(peers) > count {
+		peers = peers[:count]
+	}
+	return peers
+}
+
+// size returns the number of peers in the table.
+func (rt *routingTable) size() int {
+	rt.lock.RLock()
+	defer rt.lock.RUnlock()
+	n := 0
+	for _, bucket := range rt.buckets {
+		n += len(bucket)
+	}
+	return n
+}
+
+// bucketIndex returns the bucket of the peer, or -1 for the node itself.
+func (rt *routingTable) bucketIndex(id peer.ID) int {
+	i := commonPrefixLen(rt.self, dhtKey(id))
+	if i == len(rt.buckets) {
+		return -1
+	}
+	return i
+}
+
+// commonPrefixLen returns the number of leading bits shared by two keys.
+func commonPrefixLen(a, b []byte) int {
+	for i := range a {
+		if x := a[i] ^ b[i]; x != 0 {
+			n := 0
+			for x&0x80 == 0 {
+				x <<= 1
+				n++
+			}
+			return i*8 + n
+		}
+	}
+	return len(a) * 8
+}
+
+// sortByDistance sorts the peers by the XOR distance of their key to the
+// target, closest first.
+func sortByDistance(peers []peer.ID, target []byte) []peer.ID {
+	distances := make(map[peer.ID][]byte, len(peers))
+	for _, id := range peers {
+		key := dhtKey(id)
+		for i := range key {
+			key[i] ^= target[i]
+		}
+		distances[id] = key
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return bytes.Compare(distances[peers[i]], distances[peers[j]]) < 0
+	})
+	return peers
+}
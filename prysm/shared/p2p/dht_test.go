@@ -0,0 +1,198 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	pstore "github.com/libp2p/go-libp2p-peerstore"
+	swarmt "github.com/libp2p/go-libp2p-swarm/testing"
+	bhost "github.com/libp2p/go-libp2p/p2p/host/basic"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+func TestRoutingTable(t *testing.T) {
+	rt := newRoutingTable(peer.ID("self"))
+	rt.update(peer.ID("self"))
+	if rt.size() != 0 {
+		t.Fatal("Expected the node not to be added to its own routing table")
+	}
+
+	var ids []peer.ID
+	for i := 0; i < 100; i++ {
+		id := peer.ID(fmt.Sprintf("peer%d", i))
+		ids = append(ids, id)
+		rt.update(id)
+	}
+	// Half of the peers fall in the first bucket, which only holds
+	// bucketSize of them.
+	if rt.size() >= len(ids) {
+		t.Errorf("Expected full buckets to refuse peers, table holds %d peers", rt.size())
+	}
+
+	target := rt.closest(dhtKey(ids[99]), 1)[0]
+	closest := rt.closest(dhtKey(target), bucketSize)
+	if len(closest) != bucketSize {
+		t.Fatalf("Expected %d closest peers, got %d", bucketSize, len(closest))
+	}
+	if closest[0] != target {
+		t.Errorf("Expected %s to be closest to its own key, got %s", target, closest[0])
+	}
+
+	// A new peer of a full bucket is refused, and the least recently seen
+	// peer of the bucket is returned so that it can be replaced.
+	var first peer.ID
+	for _, id := range ids {
+		if rt.bucketIndex(id) == 0 {
+			first = id
+			break
+		}
+	}
+	extra := peer.ID("extra")
+	for i := 0; rt.bucketIndex(extra) != 0; i++ {
+		extra = peer.ID(fmt.Sprintf("extra%d", i))
+	}
+	oldest, full := rt.update(extra)
+	if !full || oldest != first {
+		t.Fatalf("Expected the full bucket to return its oldest peer %s, got %s", first, oldest)
+	}
+	rt.replace(oldest, extra)
+	bucket := rt.closest(dhtKey(extra), rt.size())
+	if !containsPeer(bucket, extra) || containsPeer(bucket, oldest) {
+		t.Error("Expected the oldest peer to be replaced by the new one")
+	}
+
+	rt.remove(target)
+	for _, id := range rt.closest(dhtKey(target), rt.size()) {
+		if id == target {
+			t.Fatal("Expected removed peer not to be returned")
+		}
+	}
+}
+
+func TestDHTIgnoresQueryingPeers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	a := newDHT(bhost.New(swarmt.GenSwarm(t, ctx)), time.Second, func(peer.ID) bool { return false })
+	b := newDHT(bhost.New(swarmt.GenSwarm(t, ctx)), time.Second, func(peer.ID) bool { return false })
+	b.host.Peerstore().AddAddrs(a.host.ID(), a.host.Addrs(), pstore.PermanentAddrTTL)
+	if _, err := b.findPeers(ctx, a.host.ID(), randomKey()); err != nil {
+		t.Fatalf("Could not query peer: %v", err)
+	}
+	if a.table.size() != 0 {
+		t.Errorf("Expected the querying peer not to be added to the routing table, got %d peers", a.table.size())
+	}
+}
+
+func TestCommonPrefixLen(t *testing.T) {
+	tests := []struct {
+		a, b []byte
+		want int
+	}{
+		{[]byte{0x00, 0x00}, []byte{0x00, 0x00}, 16},
+		{[]byte{0x80, 0x00}, []byte{0x00, 0x00}, 0},
+		{[]byte{0x00, 0x01}, []byte{0x00, 0x00}, 15},
+		{[]byte{0x0f, 0xff}, []byte{0x0e, 0x00}, 7},
+	}
+	for _, tt := range tests {
+		if got := commonPrefixLen(tt.a, tt.b); got != tt.want {
+			t.Errorf("commonPrefixLen(%x, %x) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDHTLookup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Every host only knows the previous one, so the last host has to walk
+	// the whole chain to find the first.
+	var hosts []host.Host
+	var dhts []*dht
+	for i := 0; i < 5; i++ {
+		h := bhost.New(swarmt.GenSwarm(t, ctx))
+		d := newDHT(h, time.Second, func(peer.ID) bool { return false })
+		if i > 0 {
+			prev := hosts[i-1]
+			h.Peerstore().AddAddrs(prev.ID(), prev.Addrs(), pstore.PermanentAddrTTL)
+			d.table.update(prev.ID())
+		}
+		hosts = append(hosts, h)
+		dhts = append(dhts, d)
+	}
+
+	last := dhts[len(dhts)-1]
+	found := last.lookup(ctx, dhtKey(hosts[0].ID()))
+	for _, h := range hosts[:len(hosts)-1] {
+		if !containsPeer(found, h.ID()) {
+			t.Errorf("Expected lookup to find peer %s, found %v", h.ID().Pretty(), found)
+		}
+	}
+	if last.table.size() != len(hosts)-1 {
+		t.Errorf("Expected %d peers in the routing table, got %d", len(hosts)-1, last.table.size())
+	}
+
+	// Peers that do not answer are dropped from the routing table.
+	if err := hosts[0].Close(); err != nil {
+		t.Fatal(err)
+	}
+	last.lookup(ctx, randomKey())
+	if containsPeer(last.table.closest(dhtKey(hosts[0].ID()), bucketSize), hosts[0].ID()) {
+		t.Error("Expected unreachable peer to be removed from the routing table")
+	}
+}
+
+func TestDHTFiltersPeers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// flooder answers every query with more than bucketSize peers, all of
+	// them banned by the querying node.
+	banned := bhost.New(swarmt.GenSwarm(t, ctx))
+	flooder := bhost.New(swarmt.GenSwarm(t, ctx))
+	flooder.SetStreamHandler(dhtProtocol, func(stream inet.Stream) {
+		defer stream.Close()
+		if err := readMessage(stream, &pb.FindPeersRequest{}); err != nil {
+			return
+		}
+		resp := &pb.FindPeersResponse{}
+		for i := 0; i < 2*bucketSize; i++ {
+			resp.Peers = append(resp.Peers, &pb.PeerAddress{Id: []byte(banned.ID())})
+		}
+		writeMessage(stream, resp)
+	})
+
+	h := bhost.New(swarmt.GenSwarm(t, ctx))
+	d := newDHT(h, time.Second, func(id peer.ID) bool { return id == banned.ID() })
+	h.Peerstore().AddAddrs(flooder.ID(), flooder.Addrs(), pstore.PermanentAddrTTL)
+	d.table.update(flooder.ID())
+
+	peers, err := d.findPeers(ctx, flooder.ID(), randomKey())
+	if err != nil {
+		t.Fatalf("Could not find peers: %v", err)
+	}
+	if len(peers) != bucketSize {
+		t.Errorf("Expected %d peers to be kept, got %d", bucketSize, len(peers))
+	}
+	found := d.lookup(ctx, randomKey())
+	if containsPeer(found, banned.ID()) {
+		t.Error("Expected banned peer not to be queried")
+	}
+	if !containsPeer(found, flooder.ID()) {
+		t.Errorf("Expected lookup to query peer %s, found %v", flooder.ID().Pretty(), found)
+	}
+}
+
+func containsPeer(ids []peer.ID, id peer.ID) bool {
+	for _, p := range ids {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
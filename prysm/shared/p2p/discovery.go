@@ -20,17 +20,15 @@ var discoveryInterval = 1 * time.Minute
 // mDNSTag is the name of the mDNS service.
 var mDNSTag = mdns.ServiceTag
 
-// startDiscovery protocols. Currently, this supports discovery via multicast
-// DNS peer discovery.
-//
-// TODO: add other discovery protocols such as DHT, etc.
-func startDiscovery(ctx context.Context, host host.Host, gsub topicPeerLister) error {
+// startDiscovery of peers on the local network via multicast DNS. Peers
+// beyond the local network are discovered through the DHT, see dht.go.
+func startDiscovery(ctx context.Context, host host.Host, gsub topicPeerLister, banned func(peer.ID) bool) error {
 	mdnsService, err := mdns.NewMdnsService(ctx, host, discoveryInterval, mDNSTag)
 	if err != nil {
 		return err
 	}
 
-	mdnsService.RegisterNotifee(&discovery{ctx, host, gsub})
+	mdnsService.RegisterNotifee(&discovery{ctx, host, gsub, banned})
 
 	return nil
 }
@@ -48,10 +46,16 @@ type discovery struct {
 
 	// Required for helper method.
 	gsub topicPeerLister
+	// banned reports whether a peer is banned. Banned peers are ignored.
+	banned func(peer.ID) bool
 }
 
-// HandlePeerFound registers the peer with the host.
+// HandlePeerFound registers the peer with the host, unless it is banned.
 func (d *discovery) HandlePeerFound(pi ps.PeerInfo) {
+	if d.banned(pi.ID) {
+		log.Debugf("Ignoring banned peer %s found through mDNS", pi.ID.Pretty())
+		return
+	}
 	d.host.Peerstore().AddAddrs(pi.ID, pi.Addrs, ps.PermanentAddrTTL)
 	if err := d.host.Connect(d.ctx, pi); err != nil {
 		log.Warnf("Failed to connect to peer: %v", err)
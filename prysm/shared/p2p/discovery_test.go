@@ -24,6 +24,10 @@ func (f *fakeTopicPeerLister) ListPeers(topic string) []peer.ID {
 	return nil
 }
 
+func notBanned(peer.ID) bool {
+	return false
+}
+
 func TestStartDiscovery_HandlePeerFound(t *testing.T) {
 	discoveryInterval = 50 * time.Millisecond // Short interval for testing.
 
@@ -33,13 +37,13 @@ func TestStartDiscovery_HandlePeerFound(t *testing.T) {
 	gsub := &fakeTopicPeerLister{}
 
 	a := bhost.New(swarmt.GenSwarm(t, ctx))
-	err := startDiscovery(ctx, a, gsub)
+	err := startDiscovery(ctx, a, gsub, notBanned)
 	if err != nil {
 		t.Errorf("Error when starting discovery: %v", err)
 	}
 
 	b := bhost.New(swarmt.GenSwarm(t, ctx))
-	err = startDiscovery(ctx, b, gsub)
+	err = startDiscovery(ctx, b, gsub, notBanned)
 	if err != nil {
 		t.Errorf("Error when starting discovery: %v", err)
 	}
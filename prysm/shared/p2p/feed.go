@@ -1,6 +1,7 @@
 package p2p
 
 import (
+	"fmt"
 	"reflect"
 
 	"github.com/ethereum/go-ethereum/event"
@@ -15,7 +16,8 @@ import (
 //
 // Event feeds from p2p will always be of type p2p.Message. The message
 // contains information about the sender, aka the peer, and the message payload
-// itself.
+// itself, which is always a pointer to the message. The msg argument can be a
+// value, a pointer or a reflect.Type, which all return the same feed.
 //
 //   feed, err := ps.Feed(MyMessage{})
 //   ch := make(chan p2p.Message, 100) // Choose a reasonable buffer size!
@@ -23,17 +25,9 @@ import (
 //
 //   // Wait until my message comes from a peer.
 //   msg := <- ch
-//   fmt.Printf("Message received: %v", msg.Data)
+//   fmt.Printf("Message received: %v", msg.Data.(*MyMessage))
 func (s *Server) Feed(msg interface{}) *event.Feed {
-	var t reflect.Type
-
-	// Support passing reflect.Type as the msg.
-	switch msg.(type) {
-	case reflect.Type:
-		t = msg.(reflect.Type)
-	default:
-		t = reflect.TypeOf(msg)
-	}
+	t := messageType(msg)
 
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -43,3 +37,55 @@ func (s *Server) Feed(msg interface{}) *event.Feed {
 
 	return s.feeds[t]
 }
+
+// SubscribeData adds a channel of message pointers, such as a
+// chan *pb.Transaction, to the feed of the message type. Only the received
+// messages are delivered, without their sender. It panics if the channel is
+// not a channel of message pointers, like event.Feed does for channels of the
+// wrong type.
+//
+//   ch := make(chan *pb.Transaction, 100)
+//   sub := ps.SubscribeData(ch)
+//   tx := <-ch
+func (s *Server) SubscribeData(channel interface{}) event.Subscription {
+	return forwardData(channel, func(msgType reflect.Type) *event.Feed {
+		return s.Feed(msgType)
+	})
+}
+
+// forwardData subscribes to the feed of the message type of the channel, and
+// forwards the data of the messages to the channel.
+func forwardData(channel interface{}, feed func(msgType reflect.Type) *event.Feed) event.Subscription {
+	chanVal := reflect.ValueOf(channel)
+	chanType := chanVal.Type()
+	if chanType.Kind() != reflect.Chan || chanType.ChanDir()&reflect.SendDir == 0 || chanType.Elem().Kind() != reflect.Ptr {
+		panic(fmt.Sprintf("p2p: SubscribeData argument has type %s, not a channel of message pointers", chanType))
+	}
+
+	msgs := make(chan Message, chanVal.Cap())
+	sub := feed(chanType.Elem().Elem()).Subscribe(msgs)
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case msg := <-msgs:
+				data := reflect.ValueOf(msg.Data)
+				if !data.IsValid() || data.Type() != chanType.Elem() {
+					log.Errorf("Dropping message of type %T from peer %s, expected %s", msg.Data, msg.Peer.ID, chanType.Elem())
+					continue
+				}
+				sent, _, _ := reflect.Select([]reflect.SelectCase{
+					{Dir: reflect.SelectSend, Chan: chanVal, Send: data},
+					{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(quit)},
+				})
+				if sent != 0 {
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	})
+}
@@ -3,7 +3,7 @@ package p2p
 import "testing"
 
 func TestFeed_ConcurrentWrite(t *testing.T) {
-	s, err := NewServer()
+	s, err := NewServer(&ServerConfig{})
 	if err != nil {
 		t.Fatalf("could not create server %v", err)
 	}
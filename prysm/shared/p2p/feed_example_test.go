@@ -4,7 +4,7 @@ import "fmt"
 
 // Feeds can be use to subscribe to any type of message.
 func ExampleServer_Feed() {
-	s, err := NewServer()
+	s, err := NewServer(&ServerConfig{})
 	if err != nil {
 		panic(err)
 	}
@@ -26,7 +26,8 @@ func ExampleServer_Feed() {
 
 	// Wait until we have a puzzle to solve.
 	msg := <-ch
-	puzzle, ok := msg.Data.(Puzzle)
+	// Messages are always delivered as pointers.
+	puzzle, ok := msg.Data.(*Puzzle)
 
 	if !ok {
 		panic("Received a message that wasn't a puzzle!")
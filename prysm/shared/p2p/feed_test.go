@@ -2,7 +2,12 @@ package p2p
 
 import (
 	"reflect"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/event"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
 )
 
 func TestFeed_ReturnsSameFeed(t *testing.T) {
@@ -17,6 +22,7 @@ func TestFeed_ReturnsSameFeed(t *testing.T) {
 		{a: struct{ c int }{c: 1}, b: struct{ c int }{c: 2}, want: true},
 		{a: struct{ c string }{c: "a"}, b: struct{ c string }{c: "b"}, want: true},
 		{a: reflect.TypeOf(struct{ c int }{c: 1}), b: struct{ c int }{c: 2}, want: true},
+		{a: &struct{ c int }{c: 1}, b: struct{ c int }{c: 2}, want: true},
 		// Inequality tests
 		{a: 1, b: '2', want: false},
 		{a: 'a', b: 1, want: false},
@@ -24,7 +30,7 @@ func TestFeed_ReturnsSameFeed(t *testing.T) {
 		{a: struct{ c string }{c: "a"}, b: struct{ c float64 }{c: 3.4}, want: false},
 	}
 
-	s, _ := NewServer()
+	s, _ := NewServer(&ServerConfig{})
 
 	for _, tt := range tests {
 		feed1 := s.Feed(tt.a)
@@ -35,3 +41,34 @@ func TestFeed_ReturnsSameFeed(t *testing.T) {
 		}
 	}
 }
+
+func TestSubscribeData(t *testing.T) {
+	s := &Server{
+		feeds: make(map[reflect.Type]*event.Feed),
+		mutex: &sync.Mutex{},
+	}
+	ch := make(chan *pb.Transaction, 1)
+	sub := s.SubscribeData(ch)
+	defer sub.Unsubscribe()
+
+	feed := s.Feed(pb.Transaction{})
+	// Messages that break the contract are dropped.
+	feed.Send(Message{Data: pb.Transaction{Nonce: 1}})
+	feed.Send(Message{Data: &pb.Transaction{Nonce: 2}})
+
+	select {
+	case tx := <-ch:
+		if tx.Nonce != 2 {
+			t.Errorf("Received transaction with nonce %d, wanted 2", tx.Nonce)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the transaction")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected channel of values to be refused")
+		}
+	}()
+	s.SubscribeData(make(chan pb.Transaction))
+}
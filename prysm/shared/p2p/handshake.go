@@ -0,0 +1,162 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// handshakeProtocol is the libp2p protocol of the stream on which peers
+// exchange their handshake after connecting. The dialing peer opens the
+// stream and sends its handshake first.
+const handshakeProtocol = protocol.ID("/prysm/handshake/1.0.0")
+
+// Time a peer that dialed the node has to complete the handshake before it is
+// disconnected.
+var handshakeTimeout = 10 * time.Second
+
+// Time a peer of another network is banned for once disconnected, so that
+// discovery does not dial it again right away.
+var wrongNetworkBanDuration = 10 * time.Minute
+
+// manageConnections tracks the connections of the host and keeps their
+// number between the target and maximum peer counts, protecting the static
+// peers and bootstrap nodes. Every new connection starts with a handshake,
+// after which the status of the peer is polled.
+func (s *Server) manageConnections(maxPeers int) {
+	s.conns = newConnManager(s.host, s.targetPeers, maxPeers)
+	s.conns.rank = s.rankPeers
+	s.conns.onConnect = s.handshake
+	s.conns.banned = s.bannedID
+	for _, pi := range s.staticPeers {
+		s.conns.protect(pi.ID)
+	}
+	for _, pi := range s.bootstrapNodes {
+		s.conns.protect(pi.ID)
+	}
+	s.host.Network().Notify(s.conns)
+	s.host.SetStreamHandler(handshakeProtocol, s.handleHandshake)
+	s.host.SetStreamHandler(statusProtocol, s.handleStatus)
+}
+
+// localHandshake returns the handshake of the node.
+func (s *Server) localHandshake() *pb.Handshake {
+	joined := s.joinedShards()
+	shards := make([]uint64, 0, len(joined))
+	for shardID := range joined {
+		shards = append(shards, shardID)
+	}
+	sort.Slice(shards, func(i, j int) bool { return shards[i] < shards[j] })
+
+	status := s.localStatus()
+	return &pb.Handshake{
+		NetworkId: s.networkID,
+		HeadSlot:  status.HeadSlot,
+		HeadHash:  status.HeadHash,
+		Shards:    shards,
+	}
+}
+
+// handshake exchanges handshakes with a peer the node dialed, and
+// disconnects it if the exchange fails. Peers that dialed the node open the
+// handshake stream themselves, and are disconnected if they do not before
+// the handshake timeout.
+func (s *Server) handshake(conn inet.Conn) {
+	if conn.Stat().Direction == inet.DirInbound {
+		s.awaitHandshake(conn.RemotePeer())
+		return
+	}
+	remote := conn.RemotePeer()
+	hs, err := s.exchangeHandshake(remote)
+	if err != nil {
+		log.Debugf("Disconnecting peer %s that did not complete the handshake: %v", remote.Pretty(), err)
+		s.closePeer(remote)
+		return
+	}
+	s.receiveHandshake(conn, hs)
+}
+
+// exchangeHandshake sends the handshake of the node to a peer it dialed and
+// returns the handshake of the peer.
+func (s *Server) exchangeHandshake(remote peer.ID) (*pb.Handshake, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.requests.timeout)
+	defer cancel()
+	stream, err := s.host.NewStream(ctx, remote, handshakeProtocol)
+	if err != nil {
+		return nil, fmt.Errorf("could not open handshake stream: %v", err)
+	}
+	defer stream.Close()
+	if err := stream.SetDeadline(time.Now().Add(s.requests.timeout)); err != nil {
+		return nil, fmt.Errorf("could not set stream deadline: %v", err)
+	}
+	if err := writeMessage(stream, s.localHandshake()); err != nil {
+		return nil, fmt.Errorf("could not send handshake: %v", err)
+	}
+	hs := &pb.Handshake{}
+	if err := readMessage(stream, hs); err != nil {
+		return nil, fmt.Errorf("could not read handshake: %v", err)
+	}
+	return hs, nil
+}
+
+// awaitHandshake disconnects a peer that did not complete the handshake
+// within the handshake timeout.
+func (s *Server) awaitHandshake(id peer.ID) {
+	select {
+	case <-time.After(handshakeTimeout):
+	case <-s.ctx.Done():
+		return
+	}
+	if info, ok := s.conns.info(id); !ok || info.handshake != nil {
+		return
+	}
+	log.Debugf("Disconnecting peer %s that did not complete the handshake", id.Pretty())
+	s.closePeer(id)
+}
+
+// closePeer closes the connections to a peer.
+func (s *Server) closePeer(id peer.ID) {
+	if err := s.host.Network().ClosePeer(id); err != nil {
+		log.Errorf("Could not disconnect peer %s: %v", id.Pretty(), err)
+	}
+}
+
+// handleHandshake answers the handshake of a peer that dialed the node.
+func (s *Server) handleHandshake(stream inet.Stream) {
+	defer stream.Close()
+	remote := stream.Conn().RemotePeer()
+	if err := stream.SetDeadline(time.Now().Add(s.requests.timeout)); err != nil {
+		log.Errorf("Could not set stream deadline: %v", err)
+		return
+	}
+	hs := &pb.Handshake{}
+	if err := readMessage(stream, hs); err != nil {
+		log.Debugf("Could not read handshake of peer %s: %v", remote.Pretty(), err)
+		return
+	}
+	if err := writeMessage(stream, s.localHandshake()); err != nil {
+		log.Debugf("Could not send handshake to peer %s: %v", remote.Pretty(), err)
+		return
+	}
+	s.receiveHandshake(stream.Conn(), hs)
+}
+
+// receiveHandshake disconnects and briefly bans peers of other networks, and
+// records the handshake of the others before asking for their status.
+func (s *Server) receiveHandshake(conn inet.Conn, hs *pb.Handshake) {
+	remote := conn.RemotePeer()
+	if hs.NetworkId != s.networkID {
+		log.Debugf("Disconnecting peer %s of network %d", remote.Pretty(), hs.NetworkId)
+		s.scores.ban(Peer{ID: remote.Pretty()}, wrongNetworkBanDuration)
+		s.closePeer(remote)
+		return
+	}
+	s.conns.setHandshake(remote, hs)
+	go s.requestStatus(remote)
+}
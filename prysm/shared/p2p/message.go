@@ -1,9 +1,43 @@
 package p2p
 
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+)
+
 // Message represents a message received from an external peer.
 type Message struct {
 	// Peer represents the sender of the message.
 	Peer Peer
+	// Addrs are the multiaddresses known for the sender when the message was
+	// received. It is empty if the sender is unknown.
+	Addrs []string
 	// Data can be any type of message found in sharding/p2p/proto package.
+	// It is always a pointer to the message, such as *pb.Transaction, no
+	// matter how the message was sent.
 	Data interface{}
+	// RequestID identifies a request sent by the peer, so that its response
+	// can be paired with it. It is 0 for messages that are not requests.
+	RequestID uint64
+}
+
+// protoMessage returns a message given as a pointer or a value as a protobuf
+// message, which is a pointer. Values are copied.
+func protoMessage(msg interface{}) (proto.Message, error) {
+	if m, ok := msg.(proto.Message); ok {
+		return m, nil
+	}
+	v := reflect.ValueOf(msg)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("message is nil")
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	m, ok := p.Interface().(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("message (type: %T) is not a protobuf message", msg)
+	}
+	return m, nil
 }
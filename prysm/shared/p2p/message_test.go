@@ -0,0 +1,28 @@
+package p2p
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+func TestProtoMessage(t *testing.T) {
+	want := &pb.Transaction{Nonce: 1}
+	for _, msg := range []interface{}{want, *want} {
+		m, err := protoMessage(msg)
+		if err != nil {
+			t.Fatalf("Could not convert %T: %v", msg, err)
+		}
+		if !proto.Equal(m, want) {
+			t.Errorf("protoMessage(%T) = %v. wanted %v", msg, m, want)
+		}
+	}
+
+	if _, err := protoMessage(struct{}{}); err == nil {
+		t.Error("Expected non protobuf message to be refused")
+	}
+	if _, err := protoMessage(nil); err == nil {
+		t.Error("Expected nil message to be refused")
+	}
+}
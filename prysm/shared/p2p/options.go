@@ -1,36 +1,182 @@
 package p2p
 
 import (
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	libp2p "github.com/libp2p/go-libp2p"
 	crypto "github.com/libp2p/go-libp2p-crypto"
+	pstore "github.com/libp2p/go-libp2p-peerstore"
 	ma "github.com/multiformats/go-multiaddr"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
 )
 
 var port int32 = 9000
 var portRange int32 = 100
 
+// Number of peers the node looks for with the DHT enabled, and keeps when
+// trimming connections, unless configured otherwise.
+const defaultTargetPeers = 25
+
+// ServerConfig defines the configuration of the p2p server.
+type ServerConfig struct {
+	// ListenAddrs are the multiaddresses the host listens on, such as
+	// /ip4/0.0.0.0/tcp/9000. A random port of the loopback interface is used
+	// if none is given.
+	ListenAddrs []string
+	// ExternalAddr is the multiaddress advertised to peers instead of the
+	// listen addresses, for nodes behind a NAT.
+	ExternalAddr string
+	// KeyFile is the path of the file holding the private key of the node
+	// identity. The key is generated and saved on the first start. A new
+	// identity is used on every start if no file is given.
+	KeyFile string
+	// StaticPeers are the multiaddresses, ending with /ipfs/<peer ID>, of
+	// peers the node stays connected to.
+	StaticPeers []string
+	// BootstrapNodes are the multiaddresses, ending with /ipfs/<peer ID>, of
+	// the nodes contacted on start to join the network.
+	BootstrapNodes []string
+	// NoMDNS disables the discovery of peers on the local network through
+	// multicast DNS.
+	NoMDNS bool
+	// EnableDHT enables the discovery of peers through a Kademlia DHT,
+	// joined through the bootstrap nodes.
+	EnableDHT bool
+	// TargetPeers is the number of peers the node looks for through the DHT,
+	// and keeps when trimming connections. It defaults to
+	// defaultTargetPeers.
+	TargetPeers int
+	// MaxPeers is the number of connected peers above which connections are
+	// trimmed down to TargetPeers. Static peers and bootstrap nodes are
+	// never disconnected. It defaults to defaultMaxPeers.
+	MaxPeers int
+	// NetworkID identifies the network of the node. Peers announcing another
+	// network ID in their handshake are disconnected.
+	NetworkID uint64
+	// EnableCompression publishes messages snappy compressed, on the
+	// compressed variant of their topics. Messages are received from both
+	// variants regardless.
+	EnableCompression bool
+	// MaxMessageSizes overrides the maximum size of the uncompressed
+	// messages of topics. Larger messages are dropped before they are
+	// decoded. Published messages must fit in a gossipsub RPC of 1 MiB
+	// regardless, once compressed if compression is enabled.
+	MaxMessageSizes map[pb.Topic]int
+	// RecordFile is the path of the file the messages sent and received by
+	// the node are recorded to, for replaying them later. Nothing is
+	// recorded if no file is given.
+	RecordFile string
+	// RecordFileSize is the size above which the recording file is rotated.
+	// It defaults to defaultRecordFileSize.
+	RecordFileSize int64
+	// RecordFiles is the number of rotated recording files kept. It defaults
+	// to defaultRecordFiles.
+	RecordFiles int
+	// ReplayPeers are the IDs of the peers allowed to send recorded messages
+	// with the replay tool, which the node delivers to its own feeds. Replay
+	// streams from other peers are rejected.
+	ReplayPeers []string
+}
+
 // buildOptions for the libp2p host.
-// TODO: Expand on these options and provide the option configuration via flags.
-// Currently, this is a random port and a (seemingly) consistent private key
-// identity.
-func buildOptions() []libp2p.Option {
-	rand.Seed(int64(time.Now().Nanosecond()))
-	priv, _, err := crypto.GenerateKeyPair(crypto.Secp256k1, 512)
+func buildOptions(config *ServerConfig) ([]libp2p.Option, error) {
+	priv, err := loadPrivateKey(config.KeyFile)
 	if err != nil {
-		log.Errorf("Failed to generate crypto key pair: %v", err)
+		return nil, err
 	}
 
-	listen, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", port+(rand.Int31n(portRange))))
-	if err != nil {
-		log.Errorf("Failed to p2p listen: %v", err)
+	var listen []ma.Multiaddr
+	for _, addr := range config.ListenAddrs {
+		m, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid listen address %q: %v", addr, err)
+		}
+		listen = append(listen, m)
+	}
+	if len(listen) == 0 {
+		rand.Seed(int64(time.Now().Nanosecond()))
+		m, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", port+(rand.Int31n(portRange))))
+		if err != nil {
+			return nil, err
+		}
+		listen = append(listen, m)
 	}
 
-	return []libp2p.Option{
-		libp2p.ListenAddrs(listen),
+	opts := []libp2p.Option{
+		libp2p.ListenAddrs(listen...),
 		libp2p.Identity(priv),
 	}
+	if config.ExternalAddr != "" {
+		external, err := ma.NewMultiaddr(config.ExternalAddr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid external address %q: %v", config.ExternalAddr, err)
+		}
+		opts = append(opts, libp2p.AddrsFactory(func([]ma.Multiaddr) []ma.Multiaddr {
+			return []ma.Multiaddr{external}
+		}))
+	}
+	return opts, nil
+}
+
+// loadPrivateKey reads the hex encoded private key of the node identity from
+// the key file, generating and saving a new key if the file does not exist.
+// A new key is returned if no file is given.
+func loadPrivateKey(keyFile string) (crypto.PrivKey, error) {
+	if keyFile != "" {
+		enc, err := ioutil.ReadFile(keyFile)
+		if err == nil {
+			b, err := hex.DecodeString(strings.TrimSpace(string(enc)))
+			if err != nil {
+				return nil, fmt.Errorf("could not decode node key %s: %v", keyFile, err)
+			}
+			return crypto.UnmarshalPrivateKey(b)
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("could not read node key %s: %v", keyFile, err)
+		}
+	}
+
+	priv, _, err := crypto.GenerateKeyPair(crypto.Secp256k1, 512)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate crypto key pair: %v", err)
+	}
+	if keyFile == "" {
+		return priv, nil
+	}
+	b, err := crypto.MarshalPrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(keyFile, []byte(hex.EncodeToString(b)), 0600); err != nil {
+		return nil, fmt.Errorf("could not save node key %s: %v", keyFile, err)
+	}
+	log.Infof("Generated new node key in %s", keyFile)
+	return priv, nil
+}
+
+// parsePeers parses multiaddresses ending with the peer ID.
+func parsePeers(addrs []string) ([]pstore.PeerInfo, error) {
+	var peers []pstore.PeerInfo
+	for _, addr := range addrs {
+		m, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer address %q: %v", addr, err)
+		}
+		pi, err := pstore.InfoFromP2pAddr(m)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer address %q: %v", addr, err)
+		}
+		peers = append(peers, *pi)
+	}
+	return peers, nil
 }
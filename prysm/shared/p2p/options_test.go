@@ -1,9 +1,69 @@
 package p2p
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestBuildOptions(t *testing.T) {
-	opts := buildOptions()
+	if _, err := buildOptions(&ServerConfig{}); err != nil {
+		t.Fatalf("Could not build default options: %v", err)
+	}
 
-	_ = opts
+	config := &ServerConfig{
+		ListenAddrs:  []string{"/ip4/0.0.0.0/tcp/9000"},
+		ExternalAddr: "/ip4/1.2.3.4/tcp/9000",
+	}
+	if _, err := buildOptions(config); err != nil {
+		t.Fatalf("Could not build options: %v", err)
+	}
+
+	if _, err := buildOptions(&ServerConfig{ListenAddrs: []string{"127.0.0.1:9000"}}); err == nil {
+		t.Error("Expected invalid listen address to be refused")
+	}
+}
+
+func TestPersistentIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "node", "nodekey")
+
+	generated, err := loadPrivateKey(keyFile)
+	if err != nil {
+		t.Fatalf("Could not generate node key: %v", err)
+	}
+	loaded, err := loadPrivateKey(keyFile)
+	if err != nil {
+		t.Fatalf("Could not load node key: %v", err)
+	}
+	if !generated.Equals(loaded) {
+		t.Error("Expected the saved node key to be loaded")
+	}
+
+	other, err := loadPrivateKey("")
+	if err != nil {
+		t.Fatalf("Could not generate node key: %v", err)
+	}
+	if other.Equals(loaded) {
+		t.Error("Expected a new node key without key file")
+	}
+}
+
+func TestParsePeers(t *testing.T) {
+	peers, err := parsePeers([]string{"/ip4/127.0.0.1/tcp/9000/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ"})
+	if err != nil {
+		t.Fatalf("Could not parse peer address: %v", err)
+	}
+	if len(peers) != 1 || peers[0].ID.Pretty() != "QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ" || len(peers[0].Addrs) != 1 {
+		t.Errorf("Unexpected peers %v", peers)
+	}
+
+	if _, err := parsePeers([]string{"/ip4/127.0.0.1/tcp/9000"}); err == nil {
+		t.Error("Expected address without peer ID to be refused")
+	}
 }
@@ -1,6 +1,38 @@
 package p2p
 
-// Peer TODO - Design and implement.
-// See design doc: https://docs.google.com/document/d/1cthKuGPreOSQH96Ujt7sArcT-IRICk6b-QcdD0EnLsI/edit
-// https://github.com/prysmaticlabs/prysm/issues/175
-type Peer struct{}
+import (
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// Peer is a remote node of the p2p network, identified by its libp2p peer
+// ID. Peers are comparable, so they can be used as map keys. The addresses
+// and other metadata of a peer change over time and are looked up with
+// Server.PeerMetadata.
+type Peer struct {
+	// ID is the base58 encoded libp2p identity of the peer.
+	ID string
+}
+
+// String returns the ID of the peer.
+func (p Peer) String() string {
+	return p.ID
+}
+
+// PeerMetadata describes what the node knows about a peer.
+type PeerMetadata struct {
+	Peer Peer
+	// Addrs are the known multiaddresses of the peer.
+	Addrs []string
+	// Protocols are the protocols the peer is known to support.
+	Protocols []string
+	// Connected reports whether the node is connected to the peer.
+	Connected bool
+	// Inbound reports whether the peer dialed the node, if connected.
+	Inbound bool
+	// Handshake is the handshake the peer sent when connecting, or nil if it
+	// did not complete it.
+	Handshake *pb.Handshake
+	// Status is the latest status received from the peer, or nil if none
+	// was received.
+	Status *pb.Status
+}
@@ -0,0 +1,187 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	pstore "github.com/libp2p/go-libp2p-peerstore"
+)
+
+// Interval between two attempts to reconnect to the static peers.
+var staticPeerInterval = 30 * time.Second
+
+// Interval between two random walks of the DHT, when the node has fewer peers
+// than its target.
+var randomWalkInterval = 30 * time.Second
+
+// PeerMetadata returns what the node knows about a peer, such as the sender
+// of a message.
+func (s *Server) PeerMetadata(p Peer) (PeerMetadata, error) {
+	id, err := peer.IDB58Decode(p.ID)
+	if err != nil {
+		return PeerMetadata{}, fmt.Errorf("could not decode peer ID %q: %v", p.ID, err)
+	}
+	md := PeerMetadata{
+		Peer:      p,
+		Addrs:     s.addrs(id),
+		Connected: s.host.Network().Connectedness(id) == inet.Connected,
+	}
+	if md.Protocols, err = s.host.Peerstore().GetProtocols(id); err != nil {
+		return PeerMetadata{}, err
+	}
+	if info, ok := s.conns.info(id); ok {
+		md.Inbound = info.inbound
+		md.Handshake = info.handshake
+		md.Status = info.status
+	}
+	return md, nil
+}
+
+// addrs returns the known multiaddresses of a peer.
+func (s *Server) addrs(id peer.ID) []string {
+	var addrs []string
+	for _, addr := range s.host.Peerstore().Addrs(id) {
+		addrs = append(addrs, addr.String())
+	}
+	return addrs
+}
+
+// peerAddrs returns the known multiaddresses of a peer, or nil if the peer
+// is unknown.
+func (s *Server) peerAddrs(p Peer) []string {
+	id, err := peer.IDB58Decode(p.ID)
+	if err != nil || s.host == nil {
+		return nil
+	}
+	return s.addrs(id)
+}
+
+// ConnectedPeers lists the peers the node is connected to.
+func (s *Server) ConnectedPeers() []Peer {
+	ids := s.host.Network().Peers()
+	peers := make([]Peer, len(ids))
+	for i, id := range ids {
+		peers[i] = Peer{ID: id.Pretty()}
+	}
+	return peers
+}
+
+// connectPeers connects to the bootstrap nodes once, and to the static peers
+// whenever they are disconnected, until the server is stopped.
+func (s *Server) connectPeers() {
+	for _, pi := range s.staticPeers {
+		s.host.Peerstore().AddAddrs(pi.ID, pi.Addrs, pstore.PermanentAddrTTL)
+	}
+	for _, pi := range s.bootstrapNodes {
+		s.connect(pi)
+	}
+	if len(s.staticPeers) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(staticPeerInterval)
+	defer ticker.Stop()
+	for {
+		for _, pi := range s.staticPeers {
+			if s.host.Network().Connectedness(pi.ID) != inet.Connected {
+				s.connect(pi)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// discoverPeers joins the DHT through the bootstrap nodes, then keeps the
+// number of connected peers at the target until the server is stopped. It
+// walks the DHT towards random keys to find new peers while the node has too
+// few. Connections above the maximum are trimmed by the connection manager.
+func (s *Server) discoverPeers() {
+	for _, pi := range s.bootstrapNodes {
+		s.host.Peerstore().AddAddrs(pi.ID, pi.Addrs, pstore.PermanentAddrTTL)
+		s.dht.table.update(pi.ID)
+	}
+	// Looking up the own key fills the buckets close to the node.
+	s.dht.lookup(s.ctx, dhtKey(s.host.ID()))
+
+	ticker := time.NewTicker(randomWalkInterval)
+	defer ticker.Stop()
+	for {
+		if len(s.host.Network().Peers()) < s.targetPeers {
+			s.randomWalk()
+		}
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// randomWalk looks up a random key of the DHT and connects to the peers found
+// until the target peer count is reached. Peers known to share a shard with
+// the node are dialed first.
+func (s *Server) randomWalk() {
+	found := s.dht.lookup(s.ctx, randomKey())
+	log.Debugf("DHT random walk found %d peers", len(found))
+	s.preferShardPeers(found)
+	for _, id := range found {
+		if len(s.host.Network().Peers()) >= s.targetPeers {
+			return
+		}
+		if s.host.Network().Connectedness(id) == inet.Connected || s.scores.banned(Peer{ID: id.Pretty()}) {
+			continue
+		}
+		s.connect(s.host.Peerstore().PeerInfo(id))
+	}
+}
+
+// preferShardPeers moves the peers that announced any of the shards of the
+// node in their handshake first, keeping the order of the peers otherwise.
+func (s *Server) preferShardPeers(ids []peer.ID) {
+	joined := s.joinedShards()
+	shared := make(map[peer.ID]bool)
+	for _, id := range ids {
+		shared[id] = s.sharesShard(id, joined)
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return shared[ids[i]] && !shared[ids[j]]
+	})
+}
+
+// rankPeers orders peers by how little the node needs them, for the
+// connection manager to disconnect the first ones. Peers that share none of
+// the shards of the node, either by their shard topics or their handshake,
+// come first, and the lowest scored peers first among them.
+func (s *Server) rankPeers(ids []peer.ID) {
+	joined := s.joinedShards()
+	shardPeers := s.shardPeers()
+	for _, id := range ids {
+		if !shardPeers[id] && s.sharesShard(id, joined) {
+			shardPeers[id] = true
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := ids[i], ids[j]
+		if shardPeers[a] != shardPeers[b] {
+			return !shardPeers[a]
+		}
+		return s.scores.score(Peer{ID: a.Pretty()}) < s.scores.score(Peer{ID: b.Pretty()})
+	})
+}
+
+func (s *Server) connect(pi pstore.PeerInfo) {
+	ctx, cancel := context.WithTimeout(s.ctx, requestTimeout)
+	defer cancel()
+	if err := s.host.Connect(ctx, pi); err != nil {
+		log.Warnf("Failed to connect to peer %s: %v", pi.ID.Pretty(), err)
+		return
+	}
+}
@@ -0,0 +1,230 @@
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// Size above which a recording file is rotated, and number of rotated files
+// kept, unless configured otherwise.
+const (
+	defaultRecordFileSize = 64 << 20
+	defaultRecordFiles    = 4
+)
+
+// recorder writes the messages sent and received by the server to a
+// recording file. Once the file exceeds its maximum size, it is renamed with
+// the suffix .1, previous recordings are shifted to .2, .3 and so on, and
+// the oldest recording is removed.
+type recorder struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newRecorder(path string, maxSize int64, maxFiles int) (*recorder, error) {
+	if maxSize <= 0 {
+		maxSize = defaultRecordFileSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = defaultRecordFiles
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	r := &recorder{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the recording file, appending to an existing recording.
+func (r *recorder) open() error {
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open recording %s: %v", r.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// record writes a message sent to or received from a peer. The peer is
+// empty for messages broadcast by the node.
+func (r *recorder) record(outbound bool, topic string, peer Peer, size int, msg interface{}) {
+	m, err := protoMessage(msg)
+	if err != nil {
+		log.Errorf("Could not record message: %v", err)
+		return
+	}
+	data, err := proto.Marshal(m)
+	if err != nil {
+		log.Errorf("Could not record message: %v", err)
+		return
+	}
+	rec := &pb.MessageRecord{
+		Timestamp: time.Now().UnixNano(),
+		Outbound:  outbound,
+		Topic:     topic,
+		Peer:      peer.ID,
+		Size:      uint64(size),
+		Type:      proto.MessageName(m),
+		Data:      data,
+	}
+	var buf bytes.Buffer
+	if err := writeMessage(&buf, rec); err != nil {
+		log.Errorf("Could not record message: %v", err)
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.file == nil {
+		return
+	}
+	if r.size > 0 && r.size+int64(buf.Len()) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			log.Errorf("Could not rotate recording: %v", err)
+		}
+		if r.file == nil {
+			return
+		}
+	}
+	n, err := r.file.Write(buf.Bytes())
+	r.size += int64(n)
+	if err != nil {
+		log.Errorf("Could not record message: %v", err)
+	}
+}
+
+// rotate moves the recording file to the suffix .1 and starts a new one. If
+// the recording cannot be moved, the recording file is reopened to go on
+// recording to it.
+func (r *recorder) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err == nil {
+		err = r.shift()
+	}
+	if openErr := r.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// shift moves the recording file and the rotated files to the next suffix,
+// overwriting the oldest rotated file.
+func (r *recorder) shift() error {
+	for i := r.maxFiles - 1; i > 0; i-- {
+		from := fmt.Sprintf("%s.%d", r.path, i)
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", r.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(r.path, r.path+".1")
+}
+
+func (r *recorder) close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// RecordReader reads the records of a recording written by the message
+// recorder of the server.
+type RecordReader struct {
+	r *bufio.Reader
+}
+
+// NewRecordReader creates a reader of the records of a recording.
+func NewRecordReader(r io.Reader) *RecordReader {
+	return &RecordReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next record of the recording, or io.EOF at its end.
+func (rr *RecordReader) Next() (*pb.MessageRecord, error) {
+	if _, err := rr.r.Peek(1); err != nil {
+		return nil, err
+	}
+	rec := &pb.MessageRecord{}
+	if err := readMessage(rr.r, rec); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return rec, nil
+}
+
+// RecordedMessage decodes the message of a record.
+func RecordedMessage(rec *pb.MessageRecord) (proto.Message, error) {
+	t := proto.MessageType(rec.Type)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("unknown message type %q", rec.Type)
+	}
+	msg, ok := reflect.New(t.Elem()).Interface().(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a protobuf message", rec.Type)
+	}
+	if err := proto.Unmarshal(rec.Data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// ReplayRecording reads the records of a recording and hands them to the
+// replay function, waiting between records for the time that separated them
+// when they were recorded, divided by speed. Records follow each other
+// without waiting if speed is zero. It returns the number of records
+// replayed, stopping at the end of the recording, on the first error of the
+// replay function or once ctx is done.
+func ReplayRecording(ctx context.Context, rr *RecordReader, speed float64, replay func(*pb.MessageRecord) error) (int, error) {
+	var last int64
+	for n := 0; ; n++ {
+		rec, err := rr.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if speed > 0 && last != 0 && rec.Timestamp > last {
+			wait := time.Duration(float64(rec.Timestamp-last) / speed)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return n, ctx.Err()
+			}
+		}
+		last = rec.Timestamp
+		if err := replay(rec); err != nil {
+			return n, err
+		}
+	}
+}
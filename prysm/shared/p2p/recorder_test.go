@@ -0,0 +1,199 @@
+package p2p
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// readRecording reads every record of a recording file.
+func readRecording(t *testing.T, path string) []*pb.MessageRecord {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Could not open recording: %v", err)
+	}
+	defer f.Close()
+	var recs []*pb.MessageRecord
+	rr := NewRecordReader(f)
+	for {
+		rec, err := rr.Next()
+		if err == io.EOF {
+			return recs
+		}
+		if err != nil {
+			t.Fatalf("Could not read record: %v", err)
+		}
+		recs = append(recs, rec)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "p2p", "messages.rec")
+
+	r, err := newRecorder(path, 0, 0)
+	if err != nil {
+		t.Fatalf("Could not create recorder: %v", err)
+	}
+	peer := Peer{ID: "peer"}
+	r.record(false, "TRANSACTIONS/snappy", peer, 10, &pb.Transaction{Nonce: 1})
+	r.record(true, "/prysm/request/1.0.0", peer, 20, pb.CollationBodyRequest{ShardId: 2})
+	if err := r.close(); err != nil {
+		t.Fatalf("Could not close recorder: %v", err)
+	}
+
+	recs := readRecording(t, path)
+	if len(recs) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(recs))
+	}
+	if recs[0].Outbound || recs[0].Topic != "TRANSACTIONS/snappy" || recs[0].Peer != peer.ID || recs[0].Size != 10 {
+		t.Errorf("Unexpected record of received message: %v", recs[0])
+	}
+	if !recs[1].Outbound || recs[1].Topic != "/prysm/request/1.0.0" || recs[1].Size != 20 {
+		t.Errorf("Unexpected record of sent message: %v", recs[1])
+	}
+	if recs[0].Timestamp == 0 || recs[1].Timestamp < recs[0].Timestamp {
+		t.Errorf("Expected increasing timestamps, got %d and %d", recs[0].Timestamp, recs[1].Timestamp)
+	}
+
+	msg, err := RecordedMessage(recs[1])
+	if err != nil {
+		t.Fatalf("Could not decode recorded message: %v", err)
+	}
+	if !proto.Equal(msg, &pb.CollationBodyRequest{ShardId: 2}) {
+		t.Errorf("Expected the recorded request, got %v", msg)
+	}
+	if _, err := RecordedMessage(&pb.MessageRecord{Type: "unknown.Message"}); err == nil {
+		t.Error("Expected an error decoding a message of unknown type")
+	}
+}
+
+func TestRecorderRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "messages.rec")
+
+	// Every file holds a single record.
+	r, err := newRecorder(path, 1, 2)
+	if err != nil {
+		t.Fatalf("Could not create recorder: %v", err)
+	}
+	for i := 1; i <= 4; i++ {
+		r.record(false, "TRANSACTIONS", Peer{}, 0, &pb.Transaction{Nonce: uint64(i)})
+	}
+	if err := r.close(); err != nil {
+		t.Fatalf("Could not close recorder: %v", err)
+	}
+
+	// The oldest record was removed with the rotation.
+	for file, nonce := range map[string]uint64{path: 4, path + ".1": 3, path + ".2": 2} {
+		recs := readRecording(t, file)
+		if len(recs) != 1 {
+			t.Fatalf("Expected 1 record in %s, got %d", file, len(recs))
+		}
+		msg, err := RecordedMessage(recs[0])
+		if err != nil {
+			t.Fatalf("Could not decode recorded message: %v", err)
+		}
+		if tx := msg.(*pb.Transaction); tx.Nonce != nonce {
+			t.Errorf("Expected nonce %d in %s, got %d", nonce, file, tx.Nonce)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected no more than 2 rotated files, got %v", err)
+	}
+}
+
+func TestRecorderRotationFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "messages.rec")
+	// The recording cannot be moved over a non-empty directory.
+	if err := os.MkdirAll(filepath.Join(path+".1", "dir"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := newRecorder(path, 1, 1)
+	if err != nil {
+		t.Fatalf("Could not create recorder: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		r.record(false, "TRANSACTIONS", Peer{}, 0, &pb.Transaction{Nonce: uint64(i)})
+	}
+	if err := r.close(); err != nil {
+		t.Fatalf("Could not close recorder: %v", err)
+	}
+	if recs := readRecording(t, path); len(recs) != 3 {
+		t.Errorf("Expected recording to go on in the same file, got %d records", len(recs))
+	}
+}
+
+func TestReplayRecording(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "messages.rec")
+
+	r, err := newRecorder(path, 0, 0)
+	if err != nil {
+		t.Fatalf("Could not create recorder: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		r.record(false, "TRANSACTIONS", Peer{ID: "peer"}, 0, &pb.Transaction{Nonce: uint64(i)})
+	}
+	r.close()
+
+	// Replay the recording into the feed of a simulated node.
+	network := NewSimulatedNetwork(SimulatedNetworkConfig{})
+	defer network.Close()
+	node := network.NewNode()
+	ch := make(chan Message, 10)
+	node.Subscribe(pb.Transaction{}, ch)
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n, err := ReplayRecording(context.Background(), NewRecordReader(f), 1, func(rec *pb.MessageRecord) error {
+		msg, err := RecordedMessage(rec)
+		if err != nil {
+			return err
+		}
+		node.Feed(msg).Send(Message{Peer: Peer{ID: rec.Peer}, Data: msg})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Could not replay recording: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 records replayed, got %d", n)
+	}
+	msgs := received(ch)
+	if len(msgs) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(msgs))
+	}
+	for i, msg := range msgs {
+		if tx := msg.Data.(*pb.Transaction); tx.Nonce != uint64(i) || msg.Peer.ID != "peer" {
+			t.Errorf("Unexpected message %d: %v from %s", i, tx, msg.Peer.ID)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// replayProtocol is the libp2p protocol of the streams carrying recorded
+// messages to replay. Each stream carries a single MessageRecord. Nodes only
+// accept them from the peers allowed to replay.
+const replayProtocol = protocol.ID("/prysm/replay/1.0.0")
+
+// Replay sends a recorded message to a node that allows the server to
+// replay, to reproduce the traffic a node received. The node delivers the
+// message to its own feeds, so that replayed gossip is not relayed to the
+// network.
+func (s *Server) Replay(rec *pb.MessageRecord, to Peer) error {
+	id, err := peer.IDB58Decode(to.ID)
+	if err != nil {
+		return fmt.Errorf("could not decode peer ID %q: %v", to.ID, err)
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, s.requests.timeout)
+	defer cancel()
+	stream, err := s.host.NewStream(ctx, id, replayProtocol)
+	if err != nil {
+		return fmt.Errorf("could not open replay stream to peer %s: %v", to.ID, err)
+	}
+	defer stream.Close()
+	if err := stream.SetWriteDeadline(time.Now().Add(s.requests.timeout)); err != nil {
+		return err
+	}
+	return writeMessage(stream, rec)
+}
+
+// handleReplay reads a recorded message sent by the replay tool and injects
+// it, if the remote peer is allowed to replay.
+func (s *Server) handleReplay(stream inet.Stream) {
+	defer stream.Close()
+	remote := stream.Conn().RemotePeer()
+	p := Peer{ID: remote.Pretty()}
+	if !s.replayPeers[remote] {
+		log.Warnf("Rejecting replay stream from peer %s, which is not allowed to replay", p.ID)
+		return
+	}
+	if s.scores.banned(p) {
+		log.Debugf("Dropping replay stream from banned peer %s", p.ID)
+		return
+	}
+	if err := stream.SetReadDeadline(time.Now().Add(s.requests.timeout)); err != nil {
+		log.Errorf("Could not set stream deadline: %v", err)
+		return
+	}
+	rec := &pb.MessageRecord{}
+	if err := readMessage(stream, rec); err != nil {
+		log.Debugf("Could not read replayed message from peer %s: %v", p.ID, err)
+		return
+	}
+	if err := s.Inject(rec, p); err != nil {
+		log.Debugf("Could not inject replayed message from peer %s: %v", p.ID, err)
+	}
+}
+
+// Inject delivers a recorded message to the subscribers of the feed it was
+// received on, as sent by the given peer. Messages of shard topics go to the
+// feed of their shard, if the node joined its topic. Injected messages are
+// neither published nor relayed to other peers.
+func (s *Server) Inject(rec *pb.MessageRecord, from Peer) error {
+	msg, err := RecordedMessage(rec)
+	if err != nil {
+		return err
+	}
+	m := Message{Peer: from, Addrs: s.peerAddrs(from), Data: msg}
+	if result := s.validators.validate(m); result != ValidationAccept {
+		return fmt.Errorf("recorded %T is invalid", msg)
+	}
+
+	feed := s.Feed(msg)
+	if rec.Topic != string(requestProtocol) {
+		s.mutex.Lock()
+		shardID, ok := s.shardTopics[strings.TrimSuffix(rec.Topic, snappySuffix)]
+		s.mutex.Unlock()
+		if ok {
+			feed = s.ShardFeed(shardID, msg)
+		}
+	}
+	feed.Send(m)
+	return nil
+}
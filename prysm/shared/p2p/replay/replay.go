@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+	"github.com/prysmaticlabs/prysm/shared/cmd"
+	"github.com/prysmaticlabs/prysm/shared/p2p"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	prefixed "github.com/x-cray/logrus-prefixed-formatter"
+)
+
+func main() {
+	var target string
+	var keyFile string
+	var networkID uint64
+	var speed float64
+	var outbound bool
+	var connectTimeout time.Duration
+
+	customFormatter := new(prefixed.TextFormatter)
+	customFormatter.TimestampFormat = "2006-01-02 15:04:05"
+	customFormatter.FullTimestamp = true
+	logrus.SetFormatter(customFormatter)
+	log := logrus.WithField("prefix", "main")
+
+	app := cli.NewApp()
+	app.Name = "replay"
+	app.Usage = "this is a util to replay recorded p2p messages to a node, which delivers them to itself without relaying them"
+	app.ArgsUsage = "<recording files...>"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:        "target",
+			Usage:       "Multiaddress, ending with /ipfs/<peer ID>, of the node to replay the messages to. It must run with --p2preplay <peer ID of the utility>",
+			Destination: &target,
+		},
+		cli.StringFlag{
+			Name:        "nodekey",
+			Usage:       "File holding the private key of the p2p identity of the utility, generated on the first run, so that its peer ID stays the same across runs",
+			Destination: &keyFile,
+		},
+		cli.Uint64Flag{
+			Name:        "networkid",
+			Value:       cmd.NetworkIDFlag.Value,
+			Usage:       "Network ID of the target node",
+			Destination: &networkID,
+		},
+		cli.Float64Flag{
+			Name:        "speed",
+			Value:       1,
+			Usage:       "Replay speed relative to the recording. 0 replays the messages without waiting",
+			Destination: &speed,
+		},
+		cli.BoolFlag{
+			Name:        "outbound",
+			Usage:       "Also replay the messages the recording node sent, not only those it received",
+			Destination: &outbound,
+		},
+		cli.DurationFlag{
+			Name:        "timeout",
+			Value:       30 * time.Second,
+			Usage:       "Time to wait for the handshake with the target node",
+			Destination: &connectTimeout,
+		},
+	}
+
+	app.Action = func(c *cli.Context) {
+		if target == "" || c.NArg() == 0 {
+			log.Fatal("A target node and at least one recording file are required")
+		}
+		i := strings.LastIndex(target, "/ipfs/")
+		if i < 0 {
+			log.Fatalf("Target %q does not end with /ipfs/<peer ID>", target)
+		}
+		to := p2p.Peer{ID: target[i+len("/ipfs/"):]}
+
+		server, err := p2p.NewServer(&p2p.ServerConfig{
+			KeyFile:     keyFile,
+			StaticPeers: []string{target},
+			NoMDNS:      true,
+			NetworkID:   networkID,
+		})
+		if err != nil {
+			log.Fatalf("Could not create p2p server: %v", err)
+		}
+		server.Start()
+		defer server.Stop()
+
+		if err := waitForHandshake(server, to, connectTimeout); err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("Connected to %s", to.ID)
+
+		ctx := context.Background()
+		replayed := 0
+		for _, path := range c.Args() {
+			f, err := os.Open(path)
+			if err != nil {
+				log.Fatalf("Could not open recording: %v", err)
+			}
+			_, err = p2p.ReplayRecording(ctx, p2p.NewRecordReader(f), speed, func(rec *pb.MessageRecord) error {
+				if rec.Outbound && !outbound {
+					return nil
+				}
+				replayed++
+				log.WithFields(logrus.Fields{
+					"topic": rec.Topic,
+					"type":  rec.Type,
+				}).Debug("Replaying message")
+				return server.Replay(rec, to)
+			})
+			f.Close()
+			if err != nil {
+				log.Fatalf("Could not replay %s: %v", path, err)
+			}
+			log.Infof("Replayed %s", path)
+		}
+		log.Infof("Replayed %d messages", replayed)
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// waitForHandshake waits until the target node completed the handshake, so
+// that the replayed messages reach it.
+func waitForHandshake(server *p2p.Server, to p2p.Peer, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		md, err := server.PeerMetadata(to)
+		if err != nil {
+			return err
+		}
+		if md.Handshake != nil {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("no handshake with %s after %v", to.ID, timeout)
+}
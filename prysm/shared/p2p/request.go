@@ -0,0 +1,185 @@
+package p2p
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// Maximum time to wait for the response to a request, and for a message to
+// be written to or read from a peer stream.
+var requestTimeout = 10 * time.Second
+
+// Maximum size of a message sent over a peer stream.
+const maxEnvelopeSize = 4 << 20
+
+// Mapping of request message types to the type of their responses.
+var responseTypeMapping = map[reflect.Type]reflect.Type{
+	reflect.TypeOf(pb.BeaconBlockRequest{}):      reflect.TypeOf(pb.BeaconBlockResponse{}),
+	reflect.TypeOf(pb.ChainHeadRequest{}):        reflect.TypeOf(pb.ChainHeadResponse{}),
+	reflect.TypeOf(pb.BeaconBlockRangeRequest{}): reflect.TypeOf(pb.BeaconBlockRangeResponse{}),
+	reflect.TypeOf(pb.CollationBodyRequest{}):    reflect.TypeOf(pb.CollationBodyResponse{}),
+}
+
+var errEnvelopeTooLarge = errors.New("envelope exceeds maximum size")
+
+// newEnvelope wraps a message for a peer stream.
+func newEnvelope(msg interface{}, requestID uint64, response bool) (*pb.Envelope, error) {
+	topic := topic(msg)
+	if topic == pb.Topic_UNKNOWN {
+		return nil, fmt.Errorf("topic is unknown for message type %T", msg)
+	}
+	m, err := protoMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Envelope{
+		Topic:     topic,
+		Payload:   payload,
+		RequestId: requestID,
+		Response:  response,
+	}, nil
+}
+
+// openEnvelope decodes the message wrapped in an envelope.
+func openEnvelope(env *pb.Envelope) (msg proto.Message, err error) {
+	msgType, ok := topicTypeMapping[env.Topic]
+	if !ok {
+		return nil, fmt.Errorf("unknown topic %v", env.Topic)
+	}
+	// Decoding data from peers must not crash the server.
+	defer func() {
+		if r := recover(); r != nil {
+			msg, err = nil, fmt.Errorf("could not decode %s message: %v", msgType, r)
+		}
+	}()
+	msg = reflect.New(msgType).Interface().(proto.Message)
+	if err := proto.Unmarshal(env.Payload, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// writeEnvelope writes a length prefixed envelope.
+func writeEnvelope(w io.Writer, env *pb.Envelope) error {
+	return writeMessage(w, env)
+}
+
+// readEnvelope reads a length prefixed envelope.
+func readEnvelope(r io.Reader) (*pb.Envelope, error) {
+	env := &pb.Envelope{}
+	if err := readMessage(r, env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// writeMessage writes a length prefixed protobuf message.
+func writeMessage(w io.Writer, msg proto.Message) error {
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(b) > maxEnvelopeSize {
+		return errEnvelopeTooLarge
+	}
+	prefix := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(prefix, uint64(len(b)))
+	if _, err := w.Write(append(prefix[:n], b...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readMessage reads a length prefixed protobuf message into msg.
+func readMessage(r io.Reader, msg proto.Message) error {
+	br := bufio.NewReader(r)
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return err
+	}
+	if size > maxEnvelopeSize {
+		return errEnvelopeTooLarge
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, msg)
+}
+
+type pendingRequest struct {
+	peer     Peer
+	respType reflect.Type
+	response chan proto.Message
+}
+
+// requestTracker pairs the requests sent by this node with their responses,
+// tracking the requests until they are answered.
+type requestTracker struct {
+	lock    sync.Mutex
+	nextID  uint64
+	pending map[uint64]pendingRequest
+	timeout time.Duration
+}
+
+func newRequestTracker(timeout time.Duration) *requestTracker {
+	return &requestTracker{
+		pending: make(map[uint64]pendingRequest),
+		timeout: timeout,
+	}
+}
+
+// start registers a new outgoing request to the peer and returns its ID and
+// the channel its response is delivered to.
+func (rt *requestTracker) start(peer Peer, msg interface{}) (uint64, <-chan proto.Message, error) {
+	respType, ok := responseTypeMapping[messageType(msg)]
+	if !ok {
+		return 0, nil, fmt.Errorf("%T is not a request message", msg)
+	}
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	rt.nextID++
+	ch := make(chan proto.Message, 1)
+	rt.pending[rt.nextID] = pendingRequest{peer: peer, respType: respType, response: ch}
+	return rt.nextID, ch, nil
+}
+
+// done forgets an outgoing request, once answered or timed out.
+func (rt *requestTracker) done(id uint64) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	delete(rt.pending, id)
+}
+
+// deliver hands a response from a peer to the request it answers. It returns
+// false if no request to that peer is waiting for a response of that type.
+func (rt *requestTracker) deliver(peer Peer, id uint64, msg proto.Message) bool {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	req, ok := rt.pending[id]
+	if !ok || req.peer != peer || req.respType != messageType(msg) {
+		return false
+	}
+	delete(rt.pending, id)
+	req.response <- msg
+	return true
+}
+
+// answers reports whether msg is of the response type of the request.
+func answers(req interface{}, msg interface{}) bool {
+	respType, ok := responseTypeMapping[messageType(req)]
+	return ok && respType == messageType(msg)
+}
@@ -0,0 +1,85 @@
+package p2p
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	msg := &pb.BeaconBlockRequest{Hash: []byte{'a'}}
+	env, err := newEnvelope(msg, 7, false)
+	if err != nil {
+		t.Fatalf("Could not wrap message: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := writeEnvelope(&buf, env); err != nil {
+		t.Fatalf("Could not write envelope: %v", err)
+	}
+	read, err := readEnvelope(&buf)
+	if err != nil {
+		t.Fatalf("Could not read envelope: %v", err)
+	}
+	if !proto.Equal(read, env) {
+		t.Errorf("Expected envelope %v, got %v", env, read)
+	}
+	opened, err := openEnvelope(read)
+	if err != nil {
+		t.Fatalf("Could not open envelope: %v", err)
+	}
+	if !proto.Equal(opened, msg) {
+		t.Errorf("Expected message %v, got %v", msg, opened)
+	}
+
+	if _, err := newEnvelope(&pb.Envelope{}, 0, false); err == nil {
+		t.Error("Expected a message without topic to be refused")
+	}
+	tooLarge := bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0x0f})
+	if _, err := readEnvelope(tooLarge); err != errEnvelopeTooLarge {
+		t.Errorf("Expected %v, got %v", errEnvelopeTooLarge, err)
+	}
+}
+
+func TestRequestTrackerResponses(t *testing.T) {
+	rt := newRequestTracker(time.Second)
+	a, b := Peer{ID: "a"}, Peer{ID: "b"}
+
+	if _, _, err := rt.start(a, &pb.BeaconBlockResponse{}); err == nil {
+		t.Error("Expected a response message to be refused as a request")
+	}
+	id, response, err := rt.start(a, &pb.BeaconBlockRequest{})
+	if err != nil {
+		t.Fatalf("Could not start request: %v", err)
+	}
+	if rt.deliver(b, id, &pb.BeaconBlockResponse{}) {
+		t.Error("Response from another peer should not be delivered")
+	}
+	if rt.deliver(a, id, &pb.ChainHeadResponse{}) {
+		t.Error("Response of another type should not be delivered")
+	}
+	resp := &pb.BeaconBlockResponse{SlotNumber: 1}
+	if !rt.deliver(a, id, resp) {
+		t.Fatal("Expected response to be delivered")
+	}
+	if got := <-response; got != resp {
+		t.Errorf("Expected response %v, got %v", resp, got)
+	}
+	if rt.deliver(a, id, resp) {
+		t.Error("Request should only be answered once")
+	}
+}
+
+func TestAnswers(t *testing.T) {
+	if !answers(&pb.BeaconBlockRequest{}, pb.BeaconBlockResponse{}) {
+		t.Error("Expected a block response to answer a block request")
+	}
+	if answers(&pb.BeaconBlockRequest{}, &pb.ChainHeadResponse{}) {
+		t.Error("Expected a chain head response not to answer a block request")
+	}
+	if answers(&pb.BeaconBlockHashAnnounce{}, &pb.BeaconBlockResponse{}) {
+		t.Error("Expected no response to answer a message that is not a request")
+	}
+}
@@ -0,0 +1,162 @@
+package p2p
+
+import (
+	"sync"
+	"time"
+)
+
+// ScoringConfig defines how peer scores are kept and when peers are banned.
+type ScoringConfig struct {
+	// BanThreshold is the score at or below which a peer is banned.
+	BanThreshold int
+	// BanDuration is how long a banned peer is refused before its score is
+	// reset.
+	BanDuration time.Duration
+	// MaxScore caps the score a peer can build up with good behaviour, so a
+	// long history of valid messages doesn't cover for misbehaviour.
+	MaxScore int
+}
+
+// DefaultScoringConfig provides the default peer scoring configuration.
+func DefaultScoringConfig() ScoringConfig {
+	return ScoringConfig{
+		BanThreshold: -100,
+		BanDuration:  time.Hour,
+		MaxScore:     100,
+	}
+}
+
+// PeerInfo describes a known peer and its standing.
+type PeerInfo struct {
+	Peer  Peer
+	Score int
+	// BannedUntil is the time the ban on the peer expires. It is zero if the
+	// peer is not banned.
+	BannedUntil time.Time
+}
+
+// Banned reports whether the peer is banned at the given time.
+func (pi PeerInfo) Banned(now time.Time) bool {
+	return now.Before(pi.BannedUntil)
+}
+
+// peerScores keeps the score of every peer that has been reported and bans
+// peers whose score falls to the ban threshold.
+type peerScores struct {
+	lock  sync.Mutex
+	cfg   ScoringConfig
+	peers map[Peer]*PeerInfo
+	onBan func(peer Peer)
+	now   func() time.Time
+}
+
+func newPeerScores(cfg ScoringConfig, onBan func(peer Peer)) *peerScores {
+	return &peerScores{
+		cfg:   cfg,
+		peers: make(map[Peer]*PeerInfo),
+		onBan: onBan,
+		now:   time.Now,
+	}
+}
+
+// adjust adds delta to the score of the peer, banning it if the score falls
+// to the ban threshold. Reports about banned peers are ignored. Peers are
+// only tracked while their score is not zero or they are banned.
+func (ps *peerScores) adjust(peer Peer, delta int) {
+	ps.lock.Lock()
+	now := ps.now()
+	info := ps.info(peer, now)
+	if info == nil {
+		info = &PeerInfo{Peer: peer}
+		ps.peers[peer] = info
+	}
+	if info.Banned(now) {
+		ps.lock.Unlock()
+		return
+	}
+	info.Score += delta
+	if info.Score > ps.cfg.MaxScore {
+		info.Score = ps.cfg.MaxScore
+	}
+	banned := info.Score <= ps.cfg.BanThreshold
+	if banned {
+		info.BannedUntil = now.Add(ps.cfg.BanDuration)
+		log.Warnf("Banning peer %s with score %d until %v", peer.ID, info.Score, info.BannedUntil)
+	} else if info.Score == 0 {
+		delete(ps.peers, peer)
+	}
+	ps.lock.Unlock()
+
+	if banned && ps.onBan != nil {
+		ps.onBan(peer)
+	}
+}
+
+// ban bans the peer for the given duration without changing its score,
+// unless it is already banned for longer. The ban is not reported to onBan,
+// the caller disconnects the peer itself.
+func (ps *peerScores) ban(peer Peer, d time.Duration) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	now := ps.now()
+	info := ps.info(peer, now)
+	if info == nil {
+		info = &PeerInfo{Peer: peer}
+		ps.peers[peer] = info
+	}
+	if until := now.Add(d); until.After(info.BannedUntil) {
+		info.BannedUntil = until
+		log.Debugf("Banning peer %s until %v", peer.ID, until)
+	}
+}
+
+// banned reports whether messages from the peer should be refused. Unknown
+// peers are not banned and are not added to the scores.
+func (ps *peerScores) banned(peer Peer) bool {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	now := ps.now()
+	info := ps.info(peer, now)
+	return info != nil && info.Banned(now)
+}
+
+// score returns the current score of the peer.
+func (ps *peerScores) score(peer Peer) int {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	info := ps.info(peer, ps.now())
+	if info == nil {
+		return 0
+	}
+	return info.Score
+}
+
+// list returns the standing of every known peer, in no particular order.
+func (ps *peerScores) list() []PeerInfo {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	now := ps.now()
+	infos := make([]PeerInfo, 0, len(ps.peers))
+	for peer := range ps.peers {
+		if info := ps.info(peer, now); info != nil {
+			infos = append(infos, *info)
+		}
+	}
+	return infos
+}
+
+// info returns the entry of the peer, or nil if the peer is unknown. The
+// entry of a peer whose ban has expired is dropped, which resets its score.
+// The lock must be held.
+func (ps *peerScores) info(peer Peer, now time.Time) *PeerInfo {
+	info, ok := ps.peers[peer]
+	if !ok {
+		return nil
+	}
+	if !info.BannedUntil.IsZero() && !info.Banned(now) {
+		log.Infof("Ban on peer %s expired", peer.ID)
+		delete(ps.peers, peer)
+		return nil
+	}
+	return info
+}
@@ -0,0 +1,118 @@
+package p2p
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPeerScoresBan(t *testing.T) {
+	var banned []Peer
+	ps := newPeerScores(ScoringConfig{BanThreshold: -10, BanDuration: time.Minute, MaxScore: 5}, func(p Peer) {
+		banned = append(banned, p)
+	})
+	now := time.Now()
+	ps.now = func() time.Time { return now }
+
+	good, bad := Peer{ID: "good"}, Peer{ID: "bad"}
+	for i := 0; i < 10; i++ {
+		ps.adjust(good, 1)
+	}
+	ps.adjust(bad, -5)
+	if ps.banned(bad) {
+		t.Fatal("Peer should not be banned above the threshold")
+	}
+	ps.adjust(bad, -5)
+	if !ps.banned(bad) {
+		t.Fatal("Peer should be banned at the threshold")
+	}
+	if ps.banned(good) {
+		t.Error("Well behaved peer should not be banned")
+	}
+	if len(banned) != 1 || banned[0] != bad {
+		t.Errorf("Expected ban callback for %v, got %v", bad, banned)
+	}
+
+	// Reports about banned peers are ignored.
+	ps.adjust(bad, -5)
+	if len(banned) != 1 {
+		t.Errorf("Banned peer should not be banned again, got %d callbacks", len(banned))
+	}
+
+	infos := make(map[Peer]PeerInfo)
+	for _, info := range ps.list() {
+		infos[info.Peer] = info
+	}
+	if len(infos) != 2 {
+		t.Fatalf("Expected 2 peers to be listed, got %d", len(infos))
+	}
+	if infos[good].Score != 5 {
+		t.Errorf("Expected score to be capped at 5, got %d", infos[good].Score)
+	}
+	if !infos[bad].Banned(now) || infos[bad].Score != -10 {
+		t.Errorf("Expected banned peer with score -10, got %+v", infos[bad])
+	}
+
+	// The ban expires after the ban duration.
+	now = now.Add(time.Minute)
+	if ps.banned(bad) {
+		t.Fatal("Ban should have expired")
+	}
+	ps.adjust(bad, -5)
+	if ps.banned(bad) {
+		t.Error("Score should be reset once the ban expires")
+	}
+	if len(ps.list()) != 2 {
+		t.Errorf("Expected the peers to be listed once the ban expired, got %d", len(ps.list()))
+	}
+}
+
+func TestPeerScoresOnlyTracksScoredPeers(t *testing.T) {
+	ps := newPeerScores(DefaultScoringConfig(), nil)
+
+	// Looking up peers does not add them.
+	for i := 0; i < 10; i++ {
+		if ps.banned(Peer{ID: fmt.Sprintf("peer-%d", i)}) {
+			t.Fatal("Unknown peer should not be banned")
+		}
+	}
+	if len(ps.peers) != 0 {
+		t.Errorf("Expected no peers to be tracked, got %d", len(ps.peers))
+	}
+
+	// Peers whose score is back to zero are dropped.
+	p := Peer{ID: "peer"}
+	ps.adjust(p, 5)
+	if len(ps.peers) != 1 {
+		t.Fatalf("Expected the scored peer to be tracked, got %d peers", len(ps.peers))
+	}
+	ps.adjust(p, -5)
+	if len(ps.peers) != 0 {
+		t.Errorf("Expected the peer with a zero score to be dropped, got %d peers", len(ps.peers))
+	}
+}
+
+func TestPeerScoresTemporaryBan(t *testing.T) {
+	ps := newPeerScores(DefaultScoringConfig(), func(Peer) {
+		t.Error("Temporary bans should not be reported")
+	})
+	now := time.Now()
+	ps.now = func() time.Time { return now }
+
+	p := Peer{ID: "peer"}
+	ps.adjust(p, 5)
+	ps.ban(p, time.Minute)
+	if !ps.banned(p) {
+		t.Fatal("Expected peer to be banned")
+	}
+	// A shorter ban does not shorten the current one.
+	ps.ban(p, time.Second)
+	now = now.Add(30 * time.Second)
+	if !ps.banned(p) {
+		t.Fatal("Expected peer to remain banned")
+	}
+	now = now.Add(time.Minute)
+	if ps.banned(p) || ps.score(p) != 0 {
+		t.Errorf("Expected the ban to expire and the score to be reset, got score %d", ps.score(p))
+	}
+}
@@ -0,0 +1,152 @@
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	fpb "github.com/libp2p/go-floodsub/pb"
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+)
+
+// Number of gossip messages remembered by the gossip cache.
+const gossipCacheSize = 4096
+
+// gossipCache remembers the peers gossip messages were received from, and
+// the messages once they are decoded by their topic validator, so that they
+// are not decoded again when delivered. Gossipsub only exposes the origin of
+// a message, which is set by its author and can be forged by any peer, so
+// the peer that delivered a message is taken from the connection it arrived
+// on instead. Only the first delivery of a message is remembered, as
+// gossipsub drops the copies that follow.
+type gossipCache struct {
+	lock     sync.Mutex
+	messages map[string]Message
+	order    []string // message IDs in the order they were added.
+	next     int      // index of the oldest message ID in order.
+}
+
+func newGossipCache(size int) *gossipCache {
+	return &gossipCache{
+		messages: make(map[string]Message),
+		order:    make([]string, 0, size),
+	}
+}
+
+// addSender remembers the sender of a message, unless it is already known.
+func (gc *gossipCache) addSender(id string, sender Peer) {
+	gc.lock.Lock()
+	defer gc.lock.Unlock()
+	if _, ok := gc.messages[id]; ok {
+		return
+	}
+	gc.insert(id, Message{Peer: sender})
+}
+
+// addDecoded remembers a decoded message along with its sender.
+func (gc *gossipCache) addDecoded(id string, msg Message) {
+	gc.lock.Lock()
+	defer gc.lock.Unlock()
+	if _, ok := gc.messages[id]; ok {
+		gc.messages[id] = msg
+		return
+	}
+	gc.insert(id, msg)
+}
+
+// insert adds a message, evicting the oldest one if the cache is full.
+func (gc *gossipCache) insert(id string, msg Message) {
+	if len(gc.order) < cap(gc.order) {
+		gc.order = append(gc.order, id)
+	} else {
+		delete(gc.messages, gc.order[gc.next])
+		gc.order[gc.next] = id
+		gc.next = (gc.next + 1) % len(gc.order)
+	}
+	gc.messages[id] = msg
+}
+
+func (gc *gossipCache) get(id string) (Message, bool) {
+	gc.lock.Lock()
+	defer gc.lock.Unlock()
+	msg, ok := gc.messages[id]
+	return msg, ok
+}
+
+// gossipMessageID is the ID gossipsub identifies a message by.
+func gossipMessageID(msg *fpb.Message) string {
+	return string(msg.GetFrom()) + string(msg.GetSeqno())
+}
+
+// senderHost wraps the host given to gossipsub, so that the gossipsub RPCs
+// received from peers are read through a senderStream first.
+type senderHost struct {
+	host.Host
+	cache *gossipCache
+}
+
+func (h *senderHost) SetStreamHandler(pid protocol.ID, handler inet.StreamHandler) {
+	h.Host.SetStreamHandler(pid, func(stream inet.Stream) {
+		handler(&senderStream{
+			Stream: stream,
+			r:      bufio.NewReader(stream),
+			sender: Peer{ID: stream.Conn().RemotePeer().Pretty()},
+			cache:  h.cache,
+		})
+	})
+}
+
+// senderStream is a stream of gossipsub RPCs from a peer. It reads the RPCs
+// one at a time and remembers the peer as the sender of their messages
+// before gossipsub reads them.
+type senderStream struct {
+	inet.Stream
+	r      *bufio.Reader
+	buf    bytes.Buffer
+	sender Peer
+	cache  *gossipCache
+}
+
+func (s *senderStream) Read(p []byte) (int, error) {
+	if s.buf.Len() == 0 {
+		if err := s.readRPC(); err != nil {
+			return 0, err
+		}
+	}
+	return s.buf.Read(p)
+}
+
+// readRPC reads the next length-prefixed RPC of the stream into the buffer.
+func (s *senderStream) readRPC() error {
+	size, err := binary.ReadUvarint(s.r)
+	if err != nil {
+		return err
+	}
+	if size > maxGossipRPCSize {
+		return fmt.Errorf("gossip RPC of %d bytes exceeds maximum size", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(s.r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	// Malformed RPCs are left for gossipsub to reject.
+	rpc := &fpb.RPC{}
+	if err := proto.Unmarshal(data, rpc); err == nil {
+		for _, msg := range rpc.GetPublish() {
+			s.cache.addSender(gossipMessageID(msg), s.sender)
+		}
+	}
+	var prefix [binary.MaxVarintLen64]byte
+	s.buf.Write(prefix[:binary.PutUvarint(prefix[:], size)])
+	s.buf.Write(data)
+	return nil
+}
@@ -11,6 +11,7 @@ package p2p
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"sync"
 
@@ -21,6 +22,8 @@ import (
 	floodsub "github.com/libp2p/go-floodsub"
 	libp2p "github.com/libp2p/go-libp2p"
 	host "github.com/libp2p/go-libp2p-host"
+	peer "github.com/libp2p/go-libp2p-peer"
+	pstore "github.com/libp2p/go-libp2p-peerstore"
 	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
 )
 
@@ -30,48 +33,163 @@ type Sender interface {
 	Send(msg interface{}, peer Peer)
 }
 
+// P2P is the interface of the p2p network used by the services of a node.
+// It is implemented by Server, and by SimulatedNode for multi-node tests.
+type P2P interface {
+	Sender
+	Reply(req Message, msg interface{})
+	Feed(msg interface{}) *event.Feed
+	Subscribe(msg interface{}, channel interface{}) event.Subscription
+	SubscribeShard(shardID uint64, msg interface{}, channel interface{}) event.Subscription
+	Broadcast(msg interface{})
+	BroadcastShard(shardID uint64, msg interface{})
+	ReportPeer(peer Peer, delta int)
+	SetStatusFunc(f StatusFunc)
+	PeerStatuses() []PeerStatus
+}
+
 // Server is a placeholder for a p2p service. To be designed.
 type Server struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 	mutex  *sync.Mutex
 	feeds  map[reflect.Type]*event.Feed
-	host   host.Host
-	gsub   *floodsub.PubSub
+	// Feeds of shard topics, and shard IDs of the joined shard topics.
+	shardFeeds  map[shardFeedKey]*event.Feed
+	shardTopics map[string]uint64
+	host        host.Host
+	gsub        *floodsub.PubSub
+	// gossip remembers the peers gossip messages were received from and
+	// the decoded messages.
+	gossip     *gossipCache
+	scores     *peerScores
+	validators *messageValidators
+	requests   *requestTracker
+	// Peers the server connects to on start and stays connected to.
+	bootstrapNodes []pstore.PeerInfo
+	staticPeers    []pstore.PeerInfo
+	noMDNS         bool
+	// compress enables the snappy compression of published messages.
+	compress        bool
+	maxMessageSizes map[pb.Topic]int
+	// dht discovers peers beyond the local network. It is nil unless
+	// enabled.
+	dht         *dht
+	targetPeers int
+	conns       *connManager
+	// networkID must match the network ID of peers, which are disconnected
+	// otherwise.
+	networkID uint64
+	status    StatusFunc
+	// recorder records the messages sent and received by the server. It is
+	// nil unless enabled.
+	recorder *recorder
+	// replayPeers are the peers allowed to replay recorded messages.
+	replayPeers map[peer.ID]bool
 }
 
 // NewServer creates a new p2p server instance.
-func NewServer() (*Server, error) {
+func NewServer(config *ServerConfig) (*Server, error) {
+	opts, err := buildOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	bootstrapNodes, err := parsePeers(config.BootstrapNodes)
+	if err != nil {
+		return nil, err
+	}
+	staticPeers, err := parsePeers(config.StaticPeers)
+	if err != nil {
+		return nil, err
+	}
+	replayPeers := make(map[peer.ID]bool)
+	for _, p := range config.ReplayPeers {
+		id, err := peer.IDB58Decode(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replay peer ID %q: %v", p, err)
+		}
+		replayPeers[id] = true
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
-	opts := buildOptions()
 	host, err := libp2p.New(ctx, opts...)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
 
-	gsub, err := floodsub.NewGossipSub(ctx, host)
+	gossip := newGossipCache(gossipCacheSize)
+	gsub, err := floodsub.NewGossipSub(ctx, &senderHost{Host: host, cache: gossip})
 	if err != nil {
 		cancel()
 		return nil, err
 	}
 
-	return &Server{
-		ctx:    ctx,
-		cancel: cancel,
-		feeds:  make(map[reflect.Type]*event.Feed),
-		host:   host,
-		gsub:   gsub,
-		mutex:  &sync.Mutex{},
-	}, nil
+	s := &Server{
+		ctx:        ctx,
+		cancel:     cancel,
+		feeds:      make(map[reflect.Type]*event.Feed),
+		host:       host,
+		gsub:       gsub,
+		gossip:     gossip,
+		mutex:      &sync.Mutex{},
+		validators: newMessageValidators(),
+		requests:   newRequestTracker(requestTimeout),
+
+		bootstrapNodes: bootstrapNodes,
+		staticPeers:    staticPeers,
+		noMDNS:         config.NoMDNS,
+		targetPeers:    config.TargetPeers,
+		networkID:      config.NetworkID,
+		replayPeers:    replayPeers,
+
+		compress:        config.EnableCompression,
+		maxMessageSizes: config.MaxMessageSizes,
+	}
+	if s.targetPeers <= 0 {
+		s.targetPeers = defaultTargetPeers
+	}
+	maxPeers := config.MaxPeers
+	if maxPeers <= 0 {
+		maxPeers = defaultMaxPeers
+	}
+	if maxPeers < s.targetPeers {
+		maxPeers = s.targetPeers
+	}
+	s.scores = newPeerScores(DefaultScoringConfig(), s.disconnect)
+	host.SetStreamHandler(requestProtocol, s.handleStream)
+	s.manageConnections(maxPeers)
+	if config.EnableDHT {
+		s.dht = newDHT(host, requestTimeout, s.bannedID)
+	}
+	if config.RecordFile != "" {
+		s.recorder, err = newRecorder(config.RecordFile, config.RecordFileSize, config.RecordFiles)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		log.Infof("Recording messages to %s", config.RecordFile)
+	}
+	if len(replayPeers) > 0 {
+		host.SetStreamHandler(replayProtocol, s.handleReplay)
+		log.Warnf("Accepting replayed messages from peers %v", config.ReplayPeers)
+	}
+	return s, nil
 }
 
 // Start the main routine for an p2p server.
 func (s *Server) Start() {
-	log.Info("Starting service")
-	if err := startDiscovery(s.ctx, s.host, s.gsub); err != nil {
-		log.Errorf("Could not start p2p discovery! %v", err)
-		return
+	log.WithField("peer", s.host.ID().Pretty()).Info("Starting service")
+	if !s.noMDNS {
+		if err := startDiscovery(s.ctx, s.host, s.gsub, s.bannedID); err != nil {
+			log.Errorf("Could not start p2p discovery! %v", err)
+			return
+		}
+	}
+	go s.connectPeers()
+	go s.pollStatuses()
+	if s.dht != nil {
+		go s.discoverPeers()
 	}
 
 	// Subscribe to all topics.
@@ -79,7 +197,7 @@ func (s *Server) Start() {
 		log.WithFields(logrus.Fields{
 			"topic": topic,
 		}).Debug("Subscribing to topic")
-		go s.subscribeToTopic(topic, msgType)
+		s.joinTopic(topic.String(), msgType, s.Feed(msgType))
 	}
 }
 
@@ -88,6 +206,9 @@ func (s *Server) Stop() error {
 	log.Info("Stopping service")
 
 	s.cancel()
+	if s.recorder != nil {
+		return s.recorder.close()
+	}
 	return nil
 }
 
@@ -96,35 +217,106 @@ func (s *Server) Subscribe(msg interface{}, channel interface{}) event.Subscript
 	return s.Feed(msg).Subscribe(channel)
 }
 
-// Send a message to a specific peer.
+// RegisterValidator sets the validator of the messages of msg's type. The
+// msg can be a value, a pointer or a reflect.Type. Received messages of that
+// type are only delivered to subscribers and relayed to other peers if the
+// validator accepts them, and the senders of rejected messages are
+// penalized. A nil validator removes the registered one.
+func (s *Server) RegisterValidator(msg interface{}, validator Validator) {
+	s.validators.register(msg, validator)
+}
+
+// ReportPeer adjusts the score of a peer by delta. Services report
+// negative deltas for invalid or unwanted data and positive deltas for
+// useful data. Peers whose score falls to the ban threshold are
+// disconnected and their messages are dropped until the ban expires.
+func (s *Server) ReportPeer(peer Peer, delta int) {
+	s.scores.adjust(peer, delta)
+}
+
+// Peers lists the peers known to the server with their score and ban
+// status.
+func (s *Server) Peers() []PeerInfo {
+	return s.scores.list()
+}
+
+// disconnect closes the connection to a banned peer.
+func (s *Server) disconnect(p Peer) {
+	id, err := peer.IDB58Decode(p.ID)
+	if err != nil {
+		log.Errorf("Could not decode peer ID %q: %v", p.ID, err)
+		return
+	}
+	if err := s.host.Network().ClosePeer(id); err != nil {
+		log.Errorf("Could not disconnect peer %s: %v", p.ID, err)
+	}
+}
+
+// bannedID reports whether the peer with the given ID is banned.
+func (s *Server) bannedID(id peer.ID) bool {
+	return s.scores.banned(Peer{ID: id.Pretty()})
+}
+
+// Send a message to a specific peer over a direct stream. The message is
+// sent in the background and does not answer any request, see Reply.
 func (s *Server) Send(msg interface{}, peer Peer) {
-	// TODO
-	// https://github.com/prysmaticlabs/prysm/issues/175
+	s.sendAsync(msg, peer, 0, false)
+}
 
-	// TODO: Support passing value and pointer type messages.
+// Reply answers a request received from a peer with its response. Requests
+// without an ID, which the peer does not wait on, are answered with Send.
+func (s *Server) Reply(req Message, msg interface{}) {
+	if req.RequestID != 0 && !answers(req.Data, msg) {
+		log.Errorf("Could not reply to %T: %T is not its response", req.Data, msg)
+		return
+	}
+	s.sendAsync(msg, req.Peer, req.RequestID, req.RequestID != 0)
+}
 
-	// TODO: Remove debug log after send is implemented.
-	_ = peer
-	log.Debug("Broadcasting to everyone rather than sending a single peer")
-	s.Broadcast(msg)
+// sendAsync writes a message to a peer stream in the background, so that
+// callers are not held up by dialing the peer.
+func (s *Server) sendAsync(msg interface{}, peer Peer, requestID uint64, response bool) {
+	if s.scores.banned(peer) {
+		log.Debugf("Not sending to banned peer %s", peer.ID)
+		return
+	}
+	env, err := newEnvelope(msg, requestID, response)
+	if err != nil {
+		log.Errorf("Could not send message: %v", err)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(s.ctx, s.requests.timeout)
+		defer cancel()
+		if err := s.sendEnvelope(ctx, peer, env); err != nil {
+			log.Errorf("Failed to send %T to peer %s: %v", msg, peer.ID, err)
+			return
+		}
+		s.record(true, string(requestProtocol), peer, proto.Size(env), msg)
+	}()
 }
 
 // Broadcast a message to the world.
 func (s *Server) Broadcast(msg interface{}) {
 	// TODO https://github.com/prysmaticlabs/prysm/issues/176
 	topic := topic(msg)
-	log.WithFields(logrus.Fields{
-		"topic": topic,
-	}).Debugf("Broadcasting msg %T", msg)
-
 	if topic == pb.Topic_UNKNOWN {
 		log.Warnf("Topic is unknown for message type %T. %v", msg, msg)
 	}
+	s.publish(topic.String(), msg)
+}
 
-	// TODO: Next assertion may fail if your msg is not a pointer to a msg.
-	m, ok := msg.(proto.Message)
-	if !ok {
-		log.Errorf("Message to broadcast (type: %T) is not a protobuf message: %v", msg, msg)
+// publish a message on the gossipsub topic with the given name, or on its
+// compressed variant if compression is enabled.
+func (s *Server) publish(name string, msg interface{}) {
+	name = topicName(name, s.compress)
+	log.WithFields(logrus.Fields{
+		"topic": name,
+	}).Debugf("Broadcasting msg %T", msg)
+
+	m, err := protoMessage(msg)
+	if err != nil {
+		log.Errorf("Could not broadcast message: %v", err)
 		return
 	}
 
@@ -133,19 +325,51 @@ func (s *Server) Broadcast(msg interface{}) {
 		log.Errorf("Failed to marshal data for broadcast: %v", err)
 		return
 	}
-	if err := s.gsub.Publish(topic.String(), b); err != nil {
+	if max := s.maxMessageSize(topic(msg)); len(b) > max {
+		log.Errorf("Message to broadcast (type: %T) exceeds maximum size of %d bytes", msg, max)
+		return
+	}
+	data := encodePayload(b, s.compress)
+	if len(data) > maxGossipPayloadSize {
+		log.Errorf("Message to broadcast (type: %T) exceeds maximum gossip payload size of %d bytes", msg, maxGossipPayloadSize)
+		return
+	}
+	if err := s.gsub.Publish(name, data); err != nil {
 		log.Errorf("Failed to publish to gossipsub topic: %v", err)
+		return
+	}
+	s.record(true, name, Peer{}, len(data), m)
+}
+
+// record writes a message sent or received by the server to the recording,
+// if enabled.
+func (s *Server) record(outbound bool, topic string, peer Peer, size int, msg interface{}) {
+	if s.recorder != nil {
+		s.recorder.record(outbound, topic, peer, size, msg)
+	}
+}
+
+// joinTopic subscribes to the gossipsub topic with the given name and to its
+// compressed variant, delivering their messages of msgType to the feed.
+func (s *Server) joinTopic(name string, msgType reflect.Type, feed *event.Feed) {
+	for _, compressed := range []bool{false, true} {
+		name := topicName(name, compressed)
+		if err := s.gsub.RegisterTopicValidator(name, s.topicValidator(msgType, compressed)); err != nil {
+			log.Errorf("Could not register validator for topic %s: %v", name, err)
+		}
+		go s.subscribeToGossip(name, msgType, compressed, feed)
 	}
 }
 
-func (s *Server) subscribeToTopic(topic pb.Topic, msgType reflect.Type) {
-	sub, err := s.gsub.Subscribe(topic.String())
+// subscribeToGossip joins the gossipsub topic with the given name and
+// delivers its messages of msgType to the feed until the server is stopped.
+func (s *Server) subscribeToGossip(name string, msgType reflect.Type, compressed bool, feed *event.Feed) {
+	sub, err := s.gsub.Subscribe(name)
 	if err != nil {
 		log.Errorf("Failed to subscribe to topic: %v", err)
 		return
 	}
 	defer sub.Cancel()
-	feed := s.Feed(msgType)
 
 	for {
 		msg, err := sub.Next(s.ctx)
@@ -158,22 +382,106 @@ func (s *Server) subscribeToTopic(topic pb.Topic, msgType reflect.Type) {
 			return
 		}
 
-		// TODO: reflect.Value.Interface() can panic so we should capture that
-		// panic so the server doesn't crash.
-		d, ok := reflect.New(msgType).Interface().(proto.Message)
-		if !ok {
-			log.Error("Received message is not a protobuf message")
-			continue
+		// The message was decoded when validated, unless it has been evicted
+		// from the cache since.
+		m, ok := s.gossip.get(gossipMessageID(msg.Message))
+		if !ok || m.Data == nil {
+			if m, err = s.decodeMessage(msgType, compressed, msg); err != nil {
+				log.Errorf("Failed to decode data: %v", err)
+				continue
+			}
 		}
-		err = proto.Unmarshal(msg.Data, d)
-		if err != nil {
-			log.Errorf("Failed to decode data: %v", err)
+		if s.scores.banned(m.Peer) {
+			log.Debugf("Dropping message from banned peer %s", m.Peer.ID)
 			continue
 		}
+		s.record(false, name, m.Peer, len(msg.Data), m.Data)
 
-		i := feed.Send(Message{Data: d})
+		i := feed.Send(m)
 		log.WithFields(logrus.Fields{
 			"numSubs": i,
 		}).Debug("Send a request to subs")
 	}
 }
+
+// topicValidator returns the gossipsub validator of a topic carrying messages
+// of msgType, compressed or not. It runs before a message is delivered or
+// relayed, dropping oversized and malformed messages, messages from banned
+// peers and messages rejected by the registered validator. The peer that
+// delivered a dropped message is penalized, not its claimed origin. Accepted
+// messages are cached decoded for their delivery.
+func (s *Server) topicValidator(msgType reflect.Type, compressed bool) floodsub.Validator {
+	return func(ctx context.Context, msg *floodsub.Message) bool {
+		m, err := s.decodeMessage(msgType, compressed, msg)
+		if err != nil {
+			log.Debugf("Rejecting malformed %s message: %v", msgType.Name(), err)
+			s.penalizeSender(m.Peer)
+			return false
+		}
+		if s.scores.banned(m.Peer) {
+			return false
+		}
+		result := s.validators.validate(m)
+		if result == ValidationReject {
+			s.penalizeSender(m.Peer)
+		}
+		if result != ValidationAccept {
+			log.WithFields(logrus.Fields{
+				"peer":   m.Peer.ID,
+				"result": result,
+			}).Debugf("Dropping invalid %s message", msgType.Name())
+			return false
+		}
+		s.gossip.addDecoded(gossipMessageID(msg.Message), m)
+		return true
+	}
+}
+
+// penalizeSender penalizes the peer that delivered an invalid gossip
+// message, unless it is unknown.
+func (s *Server) penalizeSender(p Peer) {
+	if p.ID != "" {
+		s.ReportPeer(p, invalidMessagePenalty)
+	}
+}
+
+// gossipSender returns the peer a gossip message was received from. It is
+// the local node for its own messages, and empty if unknown.
+func (s *Server) gossipSender(msg *floodsub.Message) Peer {
+	if m, ok := s.gossip.get(gossipMessageID(msg.Message)); ok {
+		return m.Peer
+	}
+	if s.host != nil && peer.ID(msg.GetFrom()) == s.host.ID() {
+		return Peer{ID: s.host.ID().Pretty()}
+	}
+	return Peer{}
+}
+
+// decodeMessage unmarshals the data of a gossip message into a new message of
+// msgType, once its size is checked against the maximum size of its topic.
+// The peer the message was received from is set even if the data is
+// malformed.
+func (s *Server) decodeMessage(msgType reflect.Type, compressed bool, msg *floodsub.Message) (m Message, err error) {
+	m = Message{Peer: s.gossipSender(msg)}
+	m.Addrs = s.peerAddrs(m.Peer)
+	// Decoding data from peers must not crash the server.
+	defer func() {
+		if r := recover(); r != nil {
+			m.Data = nil
+			err = fmt.Errorf("could not decode %s message: %v", msgType, r)
+		}
+	}()
+	data, err := decodePayload(msg.Data, compressed, s.maxMessageSize(typeTopicMapping[msgType]))
+	if err != nil {
+		return m, err
+	}
+	d, ok := reflect.New(msgType).Interface().(proto.Message)
+	if !ok {
+		return m, fmt.Errorf("%s is not a protobuf message", msgType)
+	}
+	if err := proto.Unmarshal(data, d); err != nil {
+		return m, err
+	}
+	m.Data = d
+	return m, nil
+}
@@ -1,9 +1,11 @@
 package p2p
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
 	"reflect"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -13,6 +15,9 @@ import (
 	"github.com/prysmaticlabs/prysm/shared"
 
 	floodsub "github.com/libp2p/go-floodsub"
+	fpb "github.com/libp2p/go-floodsub/pb"
+	peer "github.com/libp2p/go-libp2p-peer"
+	pstore "github.com/libp2p/go-libp2p-peerstore"
 	swarmt "github.com/libp2p/go-libp2p-swarm/testing"
 	bhost "github.com/libp2p/go-libp2p/p2p/host/basic"
 	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
@@ -23,6 +28,9 @@ import (
 // Ensure that server implements service.
 var _ = shared.Service(&Server{})
 
+// Ensure that server implements the p2p interface.
+var _ = P2P(&Server{})
+
 func init() {
 	logrus.SetLevel(logrus.DebugLevel)
 	logrus.SetOutput(ioutil.Discard)
@@ -31,7 +39,7 @@ func init() {
 func TestLifecycle(t *testing.T) {
 	hook := logTest.NewGlobal()
 
-	s, err := NewServer()
+	s, err := NewServer(&ServerConfig{})
 	if err != nil {
 		t.Fatalf("Could not start a new server: %v", err)
 	}
@@ -57,7 +65,7 @@ func TestLifecycle(t *testing.T) {
 }
 
 func TestBroadcast(t *testing.T) {
-	s, err := NewServer()
+	s, err := NewServer(&ServerConfig{})
 	if err != nil {
 		t.Fatalf("Could not start a new server: %v", err)
 	}
@@ -68,6 +76,37 @@ func TestBroadcast(t *testing.T) {
 	// TODO: test that topic was published
 }
 
+func TestBroadcastOversizedMessage(t *testing.T) {
+	hook := logTest.NewGlobal()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s := newTestServer(ctx, t)
+	gsub, err := floodsub.NewGossipSub(ctx, s.host)
+	if err != nil {
+		t.Fatalf("Failed to create gossipsub: %v", err)
+	}
+	s.gsub = gsub
+	s.maxMessageSizes = map[pb.Topic]int{pb.Topic_COLLATION_BODY_RESPONSE: 2 << 20}
+	sub, err := gsub.Subscribe(pb.Topic_COLLATION_BODY_RESPONSE.String())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer sub.Cancel()
+
+	// Messages over 1 MiB do not fit in a gossipsub RPC, even if the topic
+	// allows them.
+	s.Broadcast(&pb.CollationBodyResponse{Body: make([]byte, (1<<20)+1)})
+	if entry := hook.LastEntry(); entry == nil || entry.Level != logrus.ErrorLevel {
+		t.Errorf("Expected oversized message to be refused, got log %v", entry)
+	}
+	nextCtx, nextCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer nextCancel()
+	if _, err := sub.Next(nextCtx); err == nil {
+		t.Error("Expected oversized message not to be published")
+	}
+	hook.Reset()
+}
+
 func TestSubscribeToTopic(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
 	defer cancel()
@@ -79,11 +118,14 @@ func TestSubscribeToTopic(t *testing.T) {
 	}
 
 	s := Server{
-		ctx:   ctx,
-		gsub:  gsub,
-		host:  h,
-		feeds: make(map[reflect.Type]*event.Feed),
-		mutex: &sync.Mutex{},
+		ctx:        ctx,
+		gsub:       gsub,
+		host:       h,
+		feeds:      make(map[reflect.Type]*event.Feed),
+		gossip:     newGossipCache(gossipCacheSize),
+		mutex:      &sync.Mutex{},
+		scores:     newPeerScores(DefaultScoringConfig(), nil),
+		validators: newMessageValidators(),
 	}
 
 	feed := s.Feed(pb.CollationBodyRequest{})
@@ -105,11 +147,14 @@ func TestSubscribe(t *testing.T) {
 	}
 
 	s := Server{
-		ctx:   ctx,
-		gsub:  gsub,
-		host:  h,
-		feeds: make(map[reflect.Type]*event.Feed),
-		mutex: &sync.Mutex{},
+		ctx:        ctx,
+		gsub:       gsub,
+		host:       h,
+		feeds:      make(map[reflect.Type]*event.Feed),
+		gossip:     newGossipCache(gossipCacheSize),
+		mutex:      &sync.Mutex{},
+		scores:     newPeerScores(DefaultScoringConfig(), nil),
+		validators: newMessageValidators(),
 	}
 
 	ch := make(chan Message)
@@ -122,7 +167,7 @@ func TestSubscribe(t *testing.T) {
 func testSubscribe(ctx context.Context, t *testing.T, s Server, gsub *floodsub.PubSub, ch chan Message) {
 	topic := pb.Topic_COLLATION_BODY_REQUEST
 	msgType := topicTypeMapping[topic]
-	go s.subscribeToTopic(topic, msgType)
+	go s.subscribeToGossip(topic.String(), msgType, false, s.Feed(msgType))
 
 	// Short delay to let goroutine add subscription.
 	time.Sleep(time.Millisecond * 10)
@@ -161,3 +206,315 @@ func testSubscribe(ctx context.Context, t *testing.T, s Server, gsub *floodsub.P
 		t.Error("Context timed out before a message was received!")
 	}
 }
+
+func TestSubscribeShard(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	h := bhost.New(swarmt.GenSwarm(t, ctx))
+
+	gsub, err := floodsub.NewFloodSub(ctx, h)
+	if err != nil {
+		t.Errorf("Failed to create floodsub: %v", err)
+	}
+
+	s := Server{
+		ctx:        ctx,
+		gsub:       gsub,
+		host:       h,
+		feeds:      make(map[reflect.Type]*event.Feed),
+		gossip:     newGossipCache(gossipCacheSize),
+		mutex:      &sync.Mutex{},
+		scores:     newPeerScores(DefaultScoringConfig(), nil),
+		validators: newMessageValidators(),
+	}
+
+	ch := make(chan Message, 2)
+	sub := s.SubscribeShard(3, pb.Transaction{}, ch)
+	defer sub.Unsubscribe()
+	globalCh := make(chan Message, 1)
+	globalSub := s.Subscribe(pb.Transaction{}, globalCh)
+	defer globalSub.Unsubscribe()
+
+	// Short delay to let goroutine add subscription.
+	time.Sleep(time.Millisecond * 10)
+
+	topics := gsub.GetTopics()
+	sort.Strings(topics)
+	want := []string{"TRANSACTIONS/shard/3", "TRANSACTIONS/shard/3/snappy"}
+	if !reflect.DeepEqual(topics, want) {
+		t.Errorf("Unexpected subscribed topics: %v. Wanted %v", topics, want)
+	}
+
+	// Only the transaction broadcast to shard 3 is received.
+	s.BroadcastShard(4, &pb.Transaction{Nonce: 4})
+	s.BroadcastShard(3, &pb.Transaction{Nonce: 3})
+
+	select {
+	case msg := <-ch:
+		if tx := msg.Data.(*pb.Transaction); tx.Nonce != 3 {
+			t.Errorf("Received transaction with nonce %d, wanted 3", tx.Nonce)
+		}
+	case <-ctx.Done():
+		t.Fatal("Context timed out before a message was received!")
+	}
+	select {
+	case msg := <-ch:
+		t.Errorf("Unexpected message: %v", msg.Data)
+	case msg := <-globalCh:
+		t.Errorf("Unexpected message on the global feed: %v", msg.Data)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTopicValidator(t *testing.T) {
+	s := Server{
+		feeds:      make(map[reflect.Type]*event.Feed),
+		gossip:     newGossipCache(gossipCacheSize),
+		mutex:      &sync.Mutex{},
+		scores:     newPeerScores(DefaultScoringConfig(), nil),
+		validators: newMessageValidators(),
+	}
+	s.RegisterValidator(pb.CollationBodyRequest{}, func(msg Message) ValidationResult {
+		if msg.Data.(*pb.CollationBodyRequest).ShardId == 0 {
+			return ValidationReject
+		}
+		return ValidationAccept
+	})
+	validate := s.topicValidator(topicTypeMapping[pb.Topic_COLLATION_BODY_REQUEST], false)
+	origin, sender := peer.ID("origin"), peer.ID("sender")
+	p := Peer{ID: sender.Pretty()}
+
+	// Messages are relayed by the sender, or claim the origin without any
+	// known sender.
+	seqno := 0
+	gossip := func(data []byte, relayed bool) *floodsub.Message {
+		seqno++
+		msg := &fpb.Message{From: []byte(origin), Data: data, Seqno: []byte{byte(seqno)}}
+		if relayed {
+			s.gossip.addSender(gossipMessageID(msg), p)
+		}
+		return &floodsub.Message{Message: msg}
+	}
+	encode := func(msg proto.Message) []byte {
+		b, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatalf("Failed to marshal message: %v", err)
+		}
+		return b
+	}
+
+	valid := gossip(encode(&pb.CollationBodyRequest{ShardId: 1}), true)
+	if !validate(context.Background(), valid) {
+		t.Error("Expected valid message to be accepted")
+	}
+	// The accepted message is delivered without being decoded again.
+	if m, ok := s.gossip.get(gossipMessageID(valid.Message)); !ok || m.Peer != p || !proto.Equal(m.Data.(proto.Message), &pb.CollationBodyRequest{ShardId: 1}) {
+		t.Errorf("Expected the decoded message to be cached, got %v", m)
+	}
+	if validate(context.Background(), gossip(encode(&pb.CollationBodyRequest{}), true)) {
+		t.Error("Expected invalid message to be rejected")
+	}
+	if validate(context.Background(), gossip([]byte{0xff}, true)) {
+		t.Error("Expected malformed message to be rejected")
+	}
+	if validate(context.Background(), gossip(encode(&pb.CollationBodyRequest{}), false)) {
+		t.Error("Expected invalid message without a known sender to be rejected")
+	}
+	// The claimed origin of the messages is not penalized.
+	peers := s.Peers()
+	if len(peers) != 1 || peers[0].Peer != p || peers[0].Score != 2*invalidMessagePenalty {
+		t.Errorf("Expected only %v to be penalized twice, got %+v", p, peers)
+	}
+}
+
+func TestGossipCache(t *testing.T) {
+	gc := newGossipCache(2)
+	a, b, c := Peer{ID: "a"}, Peer{ID: "b"}, Peer{ID: "c"}
+	gc.addSender("1", a)
+	// Only the first sender of a message is remembered.
+	gc.addSender("1", b)
+	gc.addSender("2", b)
+	if m, ok := gc.get("1"); !ok || m.Peer != a {
+		t.Errorf("Expected sender %v of message 1, got %v", a, m.Peer)
+	}
+	// The decoded message is remembered along with its sender.
+	tx := &pb.Transaction{Nonce: 2}
+	gc.addDecoded("2", Message{Peer: b, Data: tx})
+	if m, ok := gc.get("2"); !ok || m.Peer != b || m.Data != tx {
+		t.Errorf("Expected decoded message 2 from %v, got %v", b, m)
+	}
+	// The oldest message is forgotten once the cache is full.
+	gc.addSender("3", c)
+	if _, ok := gc.get("1"); ok {
+		t.Error("Expected the sender of the oldest message to be forgotten")
+	}
+	for id, want := range map[string]Peer{"2": b, "3": c} {
+		if m, ok := gc.get(id); !ok || m.Peer != want {
+			t.Errorf("Expected sender %v of message %s, got %v", want, id, m.Peer)
+		}
+	}
+}
+
+func newTestServer(ctx context.Context, t *testing.T) *Server {
+	h := bhost.New(swarmt.GenSwarm(t, ctx))
+	s := &Server{
+		ctx:        ctx,
+		host:       h,
+		feeds:      make(map[reflect.Type]*event.Feed),
+		gossip:     newGossipCache(gossipCacheSize),
+		mutex:      &sync.Mutex{},
+		scores:     newPeerScores(DefaultScoringConfig(), nil),
+		validators: newMessageValidators(),
+		requests:   newRequestTracker(time.Second),
+
+		targetPeers: defaultTargetPeers,
+	}
+	h.SetStreamHandler(requestProtocol, s.handleStream)
+	s.manageConnections(defaultMaxPeers)
+	return s
+}
+
+func TestRequestResponse(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	a, b := newTestServer(ctx, t), newTestServer(ctx, t)
+	if err := a.host.Connect(ctx, pstore.PeerInfo{ID: b.host.ID(), Addrs: b.host.Addrs()}); err != nil {
+		t.Fatalf("Could not connect hosts: %v", err)
+	}
+
+	// b answers collation body requests through its feed with a body of
+	// Period bytes, except for shard 0.
+	ch := make(chan Message, 1)
+	sub := b.Subscribe(pb.CollationBodyRequest{}, ch)
+	defer sub.Unsubscribe()
+	go func() {
+		for msg := range ch {
+			req := msg.Data.(*pb.CollationBodyRequest)
+			if req.ShardId != 0 {
+				body := bytes.Repeat([]byte{byte(req.ShardId)}, int(req.Period))
+				b.Reply(msg, &pb.CollationBodyResponse{Body: body})
+			}
+		}
+	}()
+
+	// A skipped request must not be answered by the response to a later one.
+	skipCtx, skipCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer skipCancel()
+	if _, err := a.Request(skipCtx, Peer{ID: b.host.ID().Pretty()}, &pb.CollationBodyRequest{ShardId: 0}); err == nil {
+		t.Error("Expected skipped request to time out")
+	}
+	resp, err := a.Request(ctx, Peer{ID: b.host.ID().Pretty()}, &pb.CollationBodyRequest{ShardId: 5, Period: 1})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, ok := resp.(*pb.CollationBodyResponse)
+	if !ok || !bytes.Equal(body.Body, []byte{5}) {
+		t.Errorf("Unexpected response %v", resp)
+	}
+
+	// Collation bodies too large to be gossiped are served over the stream.
+	resp, err = a.Request(ctx, Peer{ID: b.host.ID().Pretty()}, &pb.CollationBodyRequest{ShardId: 6, Period: 1 << 20})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if body, ok := resp.(*pb.CollationBodyResponse); !ok || len(body.Body) != 1<<20 {
+		t.Errorf("Expected a collation body of 1 MiB, got %T", resp)
+	}
+
+	// Nobody answers chain head requests.
+	if _, err := a.Request(ctx, Peer{ID: b.host.ID().Pretty()}, &pb.ChainHeadRequest{}); err == nil {
+		t.Error("Expected request without response to time out")
+	}
+}
+
+// recordOf records a message received on a topic.
+func recordOf(t *testing.T, topic string, msg proto.Message) *pb.MessageRecord {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pb.MessageRecord{Topic: topic, Type: proto.MessageName(msg), Data: data}
+}
+
+func TestReplay(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	a, b, c := newTestServer(ctx, t), newTestServer(ctx, t), newTestServer(ctx, t)
+	a.replayPeers = map[peer.ID]bool{b.host.ID(): true}
+	a.host.SetStreamHandler(replayProtocol, a.handleReplay)
+	a.shardTopics = map[string]uint64{shardTopic(pb.Topic_TRANSACTIONS, 3): 3}
+	for _, s := range []*Server{b, c} {
+		if err := s.host.Connect(ctx, pstore.PeerInfo{ID: a.host.ID(), Addrs: a.host.Addrs()}); err != nil {
+			t.Fatalf("Could not connect hosts: %v", err)
+		}
+	}
+
+	ch, shardCh := make(chan Message, 1), make(chan Message, 1)
+	sub := a.Subscribe(pb.Transaction{}, ch)
+	defer sub.Unsubscribe()
+	shardSub := a.ShardFeed(3, pb.Transaction{}).Subscribe(shardCh)
+	defer shardSub.Unsubscribe()
+
+	to := Peer{ID: a.host.ID().Pretty()}
+	// Peers not allowed to replay are rejected, so that the first
+	// transaction delivered is the one replayed by b.
+	if err := c.Replay(recordOf(t, "TRANSACTIONS", &pb.Transaction{Nonce: 3}), to); err != nil {
+		t.Fatalf("Could not send replayed message: %v", err)
+	}
+	if err := b.Replay(recordOf(t, "TRANSACTIONS/snappy", &pb.Transaction{Nonce: 1}), to); err != nil {
+		t.Fatalf("Could not replay message: %v", err)
+	}
+	if err := b.Replay(recordOf(t, shardTopic(pb.Topic_TRANSACTIONS, 3), &pb.Transaction{Nonce: 2}), to); err != nil {
+		t.Fatalf("Could not replay message: %v", err)
+	}
+	for nonce, ch := range map[uint64]chan Message{1: ch, 2: shardCh} {
+		select {
+		case msg := <-ch:
+			if tx := msg.Data.(*pb.Transaction); tx.Nonce != nonce || msg.Peer.ID != b.host.ID().Pretty() {
+				t.Errorf("Expected transaction %d from the replaying peer, got %v from %s", nonce, tx, msg.Peer.ID)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Replayed transaction %d was not delivered", nonce)
+		}
+	}
+
+	// Nodes that allow no peer to replay refuse replayed messages.
+	if err := a.Replay(recordOf(t, "TRANSACTIONS", &pb.Transaction{}), Peer{ID: b.host.ID().Pretty()}); err == nil {
+		t.Error("Expected replay to a node without replay enabled to fail")
+	}
+}
+
+func TestPeerMetadata(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	a, b := newTestServer(ctx, t), newTestServer(ctx, t)
+	if err := a.host.Connect(ctx, pstore.PeerInfo{ID: b.host.ID(), Addrs: b.host.Addrs()}); err != nil {
+		t.Fatalf("Could not connect hosts: %v", err)
+	}
+
+	peers := a.ConnectedPeers()
+	want := Peer{ID: b.host.ID().Pretty()}
+	if len(peers) != 1 || peers[0] != want {
+		t.Fatalf("Expected connected peers [%v], got %v", want, peers)
+	}
+	md, err := a.PeerMetadata(want)
+	if err != nil {
+		t.Fatalf("Could not get peer metadata: %v", err)
+	}
+	if !md.Connected || md.Peer != want {
+		t.Errorf("Expected connected peer %v, got %+v", want, md)
+	}
+	known := make(map[string]bool)
+	for _, addr := range md.Addrs {
+		known[addr] = true
+	}
+	for _, addr := range b.host.Addrs() {
+		if !known[addr.String()] {
+			t.Errorf("Expected address %v in %v", addr, md.Addrs)
+		}
+	}
+
+	if _, err := a.PeerMetadata(Peer{ID: "not a peer ID"}); err == nil {
+		t.Error("Expected an invalid peer ID to be refused")
+	}
+}
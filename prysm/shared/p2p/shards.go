@@ -0,0 +1,128 @@
+package p2p
+
+import (
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/event"
+	peer "github.com/libp2p/go-libp2p-peer"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+type shardFeedKey struct {
+	shardID uint64
+	msgType reflect.Type
+}
+
+// ShardFeed returns the feed of the messages of msg's type received on the
+// topic of a shard. The msg can be a value, a pointer or a reflect.Type.
+//
+// Unlike the feeds of global topics, shard topics are only joined once
+// subscribed to with SubscribeShard.
+func (s *Server) ShardFeed(shardID uint64, msg interface{}) *event.Feed {
+	key := shardFeedKey{shardID, messageType(msg)}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.shardFeeds == nil {
+		s.shardFeeds = make(map[shardFeedKey]*event.Feed)
+	}
+	if s.shardFeeds[key] == nil {
+		s.shardFeeds[key] = new(event.Feed)
+	}
+	return s.shardFeeds[key]
+}
+
+// SubscribeShard joins the topic of msg's type for a shard, such as
+// TRANSACTIONS/shard/3, along with its compressed variant, and adds the
+// channel to its feed. Only the messages broadcast to that shard with
+// BroadcastShard are delivered.
+func (s *Server) SubscribeShard(shardID uint64, msg interface{}, channel interface{}) event.Subscription {
+	s.joinShardTopic(shardID, messageType(msg))
+	return s.ShardFeed(shardID, msg).Subscribe(channel)
+}
+
+// SubscribeShardData joins the topic of the message type of the channel for
+// a shard, and adds the channel of message pointers to its feed like
+// SubscribeData.
+func (s *Server) SubscribeShardData(shardID uint64, channel interface{}) event.Subscription {
+	return forwardData(channel, func(msgType reflect.Type) *event.Feed {
+		s.joinShardTopic(shardID, msgType)
+		return s.ShardFeed(shardID, msgType)
+	})
+}
+
+// BroadcastShard broadcasts a message to the peers subscribed to the topic of
+// its type for a shard.
+func (s *Server) BroadcastShard(shardID uint64, msg interface{}) {
+	topic := topic(msg)
+	if topic == pb.Topic_UNKNOWN {
+		log.Warnf("Topic is unknown for message type %T. %v", msg, msg)
+	}
+	s.publish(shardTopic(topic, shardID), msg)
+}
+
+// joinShardTopic subscribes to the gossipsub topic of msgType for a shard,
+// unless already subscribed.
+func (s *Server) joinShardTopic(shardID uint64, msgType reflect.Type) {
+	topic, ok := typeTopicMapping[msgType]
+	if !ok {
+		log.Warnf("Topic is unknown for message type %s", msgType)
+		return
+	}
+	name := shardTopic(topic, shardID)
+
+	s.mutex.Lock()
+	if s.shardTopics == nil {
+		s.shardTopics = make(map[string]uint64)
+	}
+	_, joined := s.shardTopics[name]
+	s.shardTopics[name] = shardID
+	s.mutex.Unlock()
+	if joined {
+		return
+	}
+
+	s.joinTopic(name, msgType, s.ShardFeed(shardID, msgType))
+}
+
+// joinedShards returns the IDs of the shards the node joined a topic of.
+func (s *Server) joinedShards() map[uint64]bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	joined := make(map[uint64]bool)
+	for _, shardID := range s.shardTopics {
+		joined[shardID] = true
+	}
+	return joined
+}
+
+// sharesShard reports whether a peer announced in its latest handshake any of
+// the joined shards.
+func (s *Server) sharesShard(id peer.ID, joined map[uint64]bool) bool {
+	for _, shardID := range s.conns.peerShards(id) {
+		if joined[shardID] {
+			return true
+		}
+	}
+	return false
+}
+
+// shardPeers returns the peers subscribed to any of the shard topics the node
+// joined, compressed or not.
+func (s *Server) shardPeers() map[peer.ID]bool {
+	s.mutex.Lock()
+	names := make([]string, 0, len(s.shardTopics))
+	for name := range s.shardTopics {
+		names = append(names, name)
+	}
+	s.mutex.Unlock()
+
+	peers := make(map[peer.ID]bool)
+	for _, name := range names {
+		for _, compressed := range []bool{false, true} {
+			for _, id := range s.gsub.ListPeers(topicName(name, compressed)) {
+				peers[id] = true
+			}
+		}
+	}
+	return peers
+}
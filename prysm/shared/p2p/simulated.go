@@ -0,0 +1,376 @@
+package p2p
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/golang/protobuf/proto"
+)
+
+// Ensure that simulated nodes can stand in for the server.
+var _ = P2P(&SimulatedNode{})
+
+// SimulatedNetworkConfig defines the conditions of a simulated network.
+type SimulatedNetworkConfig struct {
+	// Latency is the time a message takes to reach a node.
+	Latency time.Duration
+	// LossRate is the probability, between 0 and 1, that a message is lost
+	// on its way to a node.
+	LossRate float64
+	// Seed seeds the random source deciding which messages are lost, so that
+	// the same messages are lost when the nodes send the same messages in
+	// the same order.
+	Seed int64
+}
+
+// SimulatedNetwork is an in-memory p2p network, connecting nodes that run in
+// the same process for multi-node tests. Messages are delivered to each node
+// in the order they were sent, after the latency of the network, unless they
+// are lost or the nodes are partitioned from each other.
+type SimulatedNetwork struct {
+	config SimulatedNetworkConfig
+	quit   chan struct{}
+
+	lock  sync.Mutex
+	rand  *rand.Rand
+	nodes []*SimulatedNode
+	// groups maps the nodes to their partition, and is nil if the network
+	// is not partitioned.
+	groups map[*SimulatedNode]int
+	// pending is the number of messages in flight.
+	pending int
+	settled *sync.Cond
+	closed  bool
+}
+
+// NewSimulatedNetwork creates a simulated network without nodes.
+func NewSimulatedNetwork(config SimulatedNetworkConfig) *SimulatedNetwork {
+	n := &SimulatedNetwork{
+		config: config,
+		quit:   make(chan struct{}),
+		rand:   rand.New(rand.NewSource(config.Seed)),
+	}
+	n.settled = sync.NewCond(&n.lock)
+	return n
+}
+
+// NewNode adds a node to the network. Nodes are connected to every other
+// node of the network.
+func (n *SimulatedNetwork) NewNode() *SimulatedNode {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	node := &SimulatedNode{
+		network:    n,
+		peer:       Peer{ID: fmt.Sprintf("simulated-%d", len(n.nodes))},
+		mutex:      &sync.Mutex{},
+		feeds:      make(map[reflect.Type]*event.Feed),
+		shardFeeds: make(map[shardFeedKey]*event.Feed),
+		joined:     make(map[shardFeedKey]bool),
+		scores:     make(map[Peer]int),
+		wake:       make(chan struct{}, 1),
+	}
+	n.nodes = append(n.nodes, node)
+	go node.deliver()
+	return node
+}
+
+// Partition splits the network into groups of nodes that only reach the
+// nodes of their own group. The nodes left out of every group form another
+// group. Messages already in flight are still delivered.
+func (n *SimulatedNetwork) Partition(groups ...[]*SimulatedNode) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.groups = make(map[*SimulatedNode]int)
+	for i, group := range groups {
+		for _, node := range group {
+			n.groups[node] = i + 1
+		}
+	}
+}
+
+// Heal removes the partitions of the network.
+func (n *SimulatedNetwork) Heal() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.groups = nil
+}
+
+// Settle waits until every message in flight is delivered to the
+// subscribers of its feed, or dropped if there are none, or until the network
+// is closed.
+func (n *SimulatedNetwork) Settle() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for n.pending > 0 {
+		n.settled.Wait()
+	}
+}
+
+// Close stops the delivery of messages. The messages in flight are dropped,
+// and messages sent afterwards are lost. Closing the network again has no
+// effect.
+func (n *SimulatedNetwork) Close() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.closed {
+		return
+	}
+	n.closed = true
+	n.pending = 0
+	n.settled.Broadcast()
+	close(n.quit)
+}
+
+// peers returns the nodes a node reaches.
+func (n *SimulatedNetwork) peers(from *SimulatedNode) []*SimulatedNode {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	var peers []*SimulatedNode
+	for _, node := range n.nodes {
+		if node != from && n.groups[node] == n.groups[from] {
+			peers = append(peers, node)
+		}
+	}
+	return peers
+}
+
+// send queues a message for delivery to the feed of a node, unless it is
+// lost.
+func (n *SimulatedNetwork) send(to *SimulatedNode, feed *event.Feed, msg Message) {
+	n.lock.Lock()
+	lost := n.closed || (n.config.LossRate > 0 && n.rand.Float64() < n.config.LossRate)
+	if !lost {
+		n.pending++
+	}
+	n.lock.Unlock()
+	if lost {
+		log.Debugf("Simulated network lost %T from %s to %s", msg.Data, msg.Peer.ID, to.peer.ID)
+		return
+	}
+	to.enqueue(simulatedDelivery{
+		due:  time.Now().Add(n.config.Latency),
+		feed: feed,
+		msg:  msg,
+	})
+}
+
+// delivered marks a message in flight as delivered.
+func (n *SimulatedNetwork) delivered() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.closed {
+		return
+	}
+	n.pending--
+	if n.pending == 0 {
+		n.settled.Broadcast()
+	}
+}
+
+type simulatedDelivery struct {
+	due  time.Time
+	feed *event.Feed
+	msg  Message
+}
+
+// SimulatedNode is a node of a simulated network. It implements P2P like the
+// server, delivering the messages sent by other nodes to its feeds.
+type SimulatedNode struct {
+	network *SimulatedNetwork
+	peer    Peer
+
+	mutex      *sync.Mutex
+	feeds      map[reflect.Type]*event.Feed
+	shardFeeds map[shardFeedKey]*event.Feed
+	// joined holds the shard topics the node subscribed to.
+	joined map[shardFeedKey]bool
+	scores map[Peer]int
+	status StatusFunc
+	inbox  []simulatedDelivery
+	wake   chan struct{}
+}
+
+// Peer returns the peer other nodes see the node as.
+func (sn *SimulatedNode) Peer() Peer {
+	return sn.peer
+}
+
+// Feed returns the feed of the messages of msg's type, like Server.Feed.
+func (sn *SimulatedNode) Feed(msg interface{}) *event.Feed {
+	t := messageType(msg)
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	if sn.feeds[t] == nil {
+		sn.feeds[t] = new(event.Feed)
+	}
+	return sn.feeds[t]
+}
+
+// Subscribe adds the channel to the feed of msg's type.
+func (sn *SimulatedNode) Subscribe(msg interface{}, channel interface{}) event.Subscription {
+	return sn.Feed(msg).Subscribe(channel)
+}
+
+// ShardFeed returns the feed of the messages of msg's type received on the
+// topic of a shard, like Server.ShardFeed.
+func (sn *SimulatedNode) ShardFeed(shardID uint64, msg interface{}) *event.Feed {
+	key := shardFeedKey{shardID, messageType(msg)}
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	if sn.shardFeeds[key] == nil {
+		sn.shardFeeds[key] = new(event.Feed)
+	}
+	return sn.shardFeeds[key]
+}
+
+// SubscribeShard joins the topic of msg's type for a shard and adds the
+// channel to its feed. Only the nodes that joined the topic receive the
+// messages broadcast to it.
+func (sn *SimulatedNode) SubscribeShard(shardID uint64, msg interface{}, channel interface{}) event.Subscription {
+	sn.mutex.Lock()
+	sn.joined[shardFeedKey{shardID, messageType(msg)}] = true
+	sn.mutex.Unlock()
+	return sn.ShardFeed(shardID, msg).Subscribe(channel)
+}
+
+// Send a message to the node of a peer, if it is reachable.
+func (sn *SimulatedNode) Send(msg interface{}, peer Peer) {
+	for _, node := range sn.network.peers(sn) {
+		if node.peer == peer {
+			sn.sendTo(node, node.Feed(msg), msg)
+			return
+		}
+	}
+	log.Debugf("Simulated node %s could not reach peer %s", sn.peer.ID, peer.ID)
+}
+
+// Reply answers a request received from a peer with its response. Simulated
+// nodes do not wait on requests, so the response is sent like any message.
+func (sn *SimulatedNode) Reply(req Message, msg interface{}) {
+	sn.Send(msg, req.Peer)
+}
+
+// Broadcast a message to every reachable node.
+func (sn *SimulatedNode) Broadcast(msg interface{}) {
+	for _, node := range sn.network.peers(sn) {
+		sn.sendTo(node, node.Feed(msg), msg)
+	}
+}
+
+// BroadcastShard broadcasts a message to the reachable nodes that joined the
+// topic of its type for a shard.
+func (sn *SimulatedNode) BroadcastShard(shardID uint64, msg interface{}) {
+	key := shardFeedKey{shardID, messageType(msg)}
+	for _, node := range sn.network.peers(sn) {
+		node.mutex.Lock()
+		joined := node.joined[key]
+		node.mutex.Unlock()
+		if joined {
+			sn.sendTo(node, node.ShardFeed(shardID, msg), msg)
+		}
+	}
+}
+
+// sendTo sends a copy of the message to the feed of a node, so that nodes
+// never share messages.
+func (sn *SimulatedNode) sendTo(node *SimulatedNode, feed *event.Feed, msg interface{}) {
+	m, err := protoMessage(msg)
+	if err != nil {
+		log.Errorf("Could not send message: %v", err)
+		return
+	}
+	sn.network.send(node, feed, Message{Peer: sn.peer, Data: proto.Clone(m)})
+}
+
+// ReportPeer adjusts the score of a peer by delta.
+func (sn *SimulatedNode) ReportPeer(peer Peer, delta int) {
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	sn.scores[peer] += delta
+}
+
+// Score returns the sum of the score adjustments reported for a peer.
+func (sn *SimulatedNode) Score(peer Peer) int {
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	return sn.scores[peer]
+}
+
+// SetStatusFunc sets the function returning the status of the node.
+func (sn *SimulatedNode) SetStatusFunc(f StatusFunc) {
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	sn.status = f
+}
+
+// PeerStatuses lists the current status of the reachable nodes that have a
+// status function, the nodes with the highest head slot first.
+func (sn *SimulatedNode) PeerStatuses() []PeerStatus {
+	var statuses []PeerStatus
+	for _, node := range sn.network.peers(sn) {
+		node.mutex.Lock()
+		status := node.status
+		node.mutex.Unlock()
+		if status == nil {
+			continue
+		}
+		st, err := status()
+		if err != nil {
+			log.Errorf("Could not get status of simulated node %s: %v", node.peer.ID, err)
+			continue
+		}
+		statuses = append(statuses, PeerStatus{Peer: node.peer, Status: st, Updated: time.Now()})
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Status.HeadSlot > statuses[j].Status.HeadSlot
+	})
+	return statuses
+}
+
+// enqueue adds a message to the inbox of the node.
+func (sn *SimulatedNode) enqueue(d simulatedDelivery) {
+	sn.mutex.Lock()
+	sn.inbox = append(sn.inbox, d)
+	sn.mutex.Unlock()
+	select {
+	case sn.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends the messages of the inbox to their feed once they are due,
+// until the network is closed.
+func (sn *SimulatedNode) deliver() {
+	for {
+		sn.mutex.Lock()
+		if len(sn.inbox) == 0 {
+			sn.mutex.Unlock()
+			select {
+			case <-sn.wake:
+				continue
+			case <-sn.network.quit:
+				return
+			}
+		}
+		d := sn.inbox[0]
+		sn.inbox = sn.inbox[1:]
+		sn.mutex.Unlock()
+
+		if wait := time.Until(d.due); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-sn.network.quit:
+				return
+			}
+		}
+		if d.feed.Send(d.msg) == 0 {
+			log.Debugf("Simulated node %s dropped %T without subscribers", sn.peer.ID, d.msg.Data)
+		}
+		sn.network.delivered()
+	}
+}
@@ -0,0 +1,183 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// received drains the messages delivered to a channel.
+func received(ch chan Message) []Message {
+	var msgs []Message
+	for {
+		select {
+		case msg := <-ch:
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestSimulatedBroadcast(t *testing.T) {
+	network := NewSimulatedNetwork(SimulatedNetworkConfig{Latency: time.Millisecond})
+	defer network.Close()
+	a, b, c := network.NewNode(), network.NewNode(), network.NewNode()
+
+	chB, chC := make(chan Message, 10), make(chan Message, 10)
+	b.Subscribe(pb.Transaction{}, chB)
+	c.Subscribe(pb.Transaction{}, chC)
+
+	tx := &pb.Transaction{Nonce: 1}
+	a.Broadcast(tx)
+	a.Broadcast(pb.Transaction{Nonce: 2})
+	network.Settle()
+
+	for _, ch := range []chan Message{chB, chC} {
+		msgs := received(ch)
+		if len(msgs) != 2 {
+			t.Fatalf("Expected 2 messages, got %d", len(msgs))
+		}
+		for i, msg := range msgs {
+			if msg.Peer != a.Peer() {
+				t.Errorf("Expected message from %s, got %s", a.Peer(), msg.Peer)
+			}
+			if data := msg.Data.(*pb.Transaction); data.Nonce != uint64(i+1) {
+				t.Errorf("Expected messages in order, got nonce %d at %d", data.Nonce, i)
+			}
+		}
+		if msgs[0].Data == tx {
+			t.Error("Expected nodes to receive a copy of the message")
+		}
+	}
+}
+
+func TestSimulatedSend(t *testing.T) {
+	network := NewSimulatedNetwork(SimulatedNetworkConfig{})
+	defer network.Close()
+	a, b, c := network.NewNode(), network.NewNode(), network.NewNode()
+
+	chB, chC := make(chan Message, 10), make(chan Message, 10)
+	b.Subscribe(pb.CollationBodyRequest{}, chB)
+	c.Subscribe(pb.CollationBodyRequest{}, chC)
+
+	a.Send(&pb.CollationBodyRequest{ShardId: 1}, b.Peer())
+	network.Settle()
+
+	if msgs := received(chB); len(msgs) != 1 {
+		t.Errorf("Expected the addressed node to receive 1 message, got %d", len(msgs))
+	}
+	if msgs := received(chC); len(msgs) != 0 {
+		t.Errorf("Expected other nodes to receive no message, got %d", len(msgs))
+	}
+}
+
+func TestSimulatedBroadcastShard(t *testing.T) {
+	network := NewSimulatedNetwork(SimulatedNetworkConfig{})
+	defer network.Close()
+	a, b, c := network.NewNode(), network.NewNode(), network.NewNode()
+
+	chB, chC := make(chan Message, 10), make(chan Message, 10)
+	b.SubscribeShard(1, pb.Transaction{}, chB)
+	c.SubscribeShard(2, pb.Transaction{}, chC)
+
+	a.BroadcastShard(1, &pb.Transaction{})
+	network.Settle()
+
+	if msgs := received(chB); len(msgs) != 1 {
+		t.Errorf("Expected the node of shard 1 to receive 1 message, got %d", len(msgs))
+	}
+	if msgs := received(chC); len(msgs) != 0 {
+		t.Errorf("Expected the node of shard 2 to receive no message, got %d", len(msgs))
+	}
+}
+
+func TestSimulatedPartition(t *testing.T) {
+	network := NewSimulatedNetwork(SimulatedNetworkConfig{})
+	defer network.Close()
+	a, b, c := network.NewNode(), network.NewNode(), network.NewNode()
+	chB, chC := make(chan Message, 10), make(chan Message, 10)
+	b.Subscribe(pb.Transaction{}, chB)
+	c.Subscribe(pb.Transaction{}, chC)
+	c.SetStatusFunc(func() (*pb.Status, error) {
+		return &pb.Status{HeadSlot: 3}, nil
+	})
+
+	network.Partition([]*SimulatedNode{a, b})
+	a.Broadcast(&pb.Transaction{})
+	network.Settle()
+
+	if msgs := received(chB); len(msgs) != 1 {
+		t.Errorf("Expected the node of the same partition to receive 1 message, got %d", len(msgs))
+	}
+	if msgs := received(chC); len(msgs) != 0 {
+		t.Errorf("Expected the partitioned node to receive no message, got %d", len(msgs))
+	}
+	if statuses := a.PeerStatuses(); len(statuses) != 0 {
+		t.Errorf("Expected no status of partitioned nodes, got %v", statuses)
+	}
+
+	network.Heal()
+	a.Broadcast(&pb.Transaction{})
+	network.Settle()
+
+	if msgs := received(chC); len(msgs) != 1 {
+		t.Errorf("Expected the healed node to receive 1 message, got %d", len(msgs))
+	}
+	statuses := a.PeerStatuses()
+	if len(statuses) != 1 || statuses[0].Peer != c.Peer() || statuses[0].Status.HeadSlot != 3 {
+		t.Errorf("Expected the status of the healed node, got %v", statuses)
+	}
+}
+
+func TestSimulatedLoss(t *testing.T) {
+	// The same seed loses the same messages.
+	var counts []int
+	for run := 0; run < 2; run++ {
+		network := NewSimulatedNetwork(SimulatedNetworkConfig{LossRate: 0.5, Seed: 7})
+		a, b := network.NewNode(), network.NewNode()
+		ch := make(chan Message, 100)
+		b.Subscribe(pb.Transaction{}, ch)
+		for i := 0; i < 100; i++ {
+			a.Broadcast(&pb.Transaction{Nonce: uint64(i)})
+		}
+		network.Settle()
+		network.Close()
+
+		n := len(received(ch))
+		if n == 0 || n == 100 {
+			t.Errorf("Expected about half of the messages to be lost, got %d delivered", n)
+		}
+		counts = append(counts, n)
+	}
+	if counts[0] != counts[1] {
+		t.Errorf("Expected runs with the same seed to lose the same messages, got %d and %d delivered", counts[0], counts[1])
+	}
+}
+
+func TestSimulatedSettleAfterClose(t *testing.T) {
+	network := NewSimulatedNetwork(SimulatedNetworkConfig{Latency: time.Hour})
+	a, b := network.NewNode(), network.NewNode()
+	b.Subscribe(pb.Transaction{}, make(chan Message, 1))
+
+	a.Broadcast(pb.Transaction{Nonce: 1})
+	settled := make(chan struct{})
+	go func() {
+		network.Settle()
+		close(settled)
+	}()
+	network.Close()
+	select {
+	case <-settled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Settle to return once the network is closed")
+	}
+
+	// Messages sent after Close are lost, and do not hold Settle up.
+	a.Broadcast(pb.Transaction{Nonce: 2})
+	network.Settle()
+
+	// Closing the network again has no effect.
+	network.Close()
+}
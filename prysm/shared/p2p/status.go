@@ -0,0 +1,129 @@
+package p2p
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// statusProtocol is the libp2p protocol of the streams on which a node asks
+// a peer for its status. The peer writes its status as soon as the stream is
+// opened.
+const statusProtocol = protocol.ID("/prysm/status/1.0.0")
+
+// Interval between two polls of the status of the connected peers.
+var statusInterval = 15 * time.Second
+
+// StatusFunc returns the status of the node, announced to peers. The head
+// of the status is also announced in the handshake.
+type StatusFunc func() (*pb.Status, error)
+
+// PeerStatus is the latest status received from a connected peer.
+type PeerStatus struct {
+	Peer   Peer
+	Status *pb.Status
+	// Updated is the time the status was received.
+	Updated time.Time
+}
+
+// LatestPeriod returns the latest collation period of a shard in the
+// status, and whether the peer follows the shard.
+func (ps PeerStatus) LatestPeriod(shardID uint64) (uint64, bool) {
+	for _, shard := range ps.Status.Shards {
+		if shard.ShardId == shardID {
+			return shard.LatestPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// SetStatusFunc sets the function returning the status of the node. Peers
+// receive an empty status until it is set.
+func (s *Server) SetStatusFunc(f StatusFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status = f
+}
+
+// PeerStatuses lists the latest status of the connected peers that sent
+// one, the peers with the highest head slot first.
+func (s *Server) PeerStatuses() []PeerStatus {
+	statuses := s.conns.statuses()
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Status.HeadSlot > statuses[j].Status.HeadSlot
+	})
+	return statuses
+}
+
+// localStatus returns the status of the node.
+func (s *Server) localStatus() *pb.Status {
+	s.mutex.Lock()
+	status := s.status
+	s.mutex.Unlock()
+	if status == nil {
+		return &pb.Status{}
+	}
+	st, err := status()
+	if err != nil {
+		log.Errorf("Could not get node status: %v", err)
+		return &pb.Status{}
+	}
+	return st
+}
+
+// pollStatuses refreshes the status of the connected peers until the server
+// is stopped.
+func (s *Server) pollStatuses() {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+		for _, id := range s.conns.handshaked() {
+			go s.requestStatus(id)
+		}
+	}
+}
+
+// requestStatus asks a peer for its status and records it.
+func (s *Server) requestStatus(id peer.ID) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.requests.timeout)
+	defer cancel()
+	stream, err := s.host.NewStream(ctx, id, statusProtocol)
+	if err != nil {
+		log.Debugf("Could not open status stream to peer %s: %v", id.Pretty(), err)
+		return
+	}
+	defer stream.Close()
+	if err := stream.SetReadDeadline(time.Now().Add(s.requests.timeout)); err != nil {
+		log.Errorf("Could not set stream deadline: %v", err)
+		return
+	}
+	st := &pb.Status{}
+	if err := readMessage(stream, st); err != nil {
+		log.Debugf("Could not read status of peer %s: %v", id.Pretty(), err)
+		return
+	}
+	s.conns.setStatus(id, st)
+}
+
+// handleStatus writes the status of the node to a peer asking for it.
+func (s *Server) handleStatus(stream inet.Stream) {
+	defer stream.Close()
+	remote := stream.Conn().RemotePeer()
+	if err := stream.SetWriteDeadline(time.Now().Add(s.requests.timeout)); err != nil {
+		log.Errorf("Could not set stream deadline: %v", err)
+		return
+	}
+	if err := writeMessage(stream, s.localStatus()); err != nil {
+		log.Debugf("Could not send status to peer %s: %v", remote.Pretty(), err)
+	}
+}
@@ -0,0 +1,95 @@
+package p2p
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pstore "github.com/libp2p/go-libp2p-peerstore"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+func TestPeerStatuses(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s := newTestServer(ctx, t)
+
+	var peers []*Server
+	for i := uint64(1); i <= 2; i++ {
+		p := newTestServer(ctx, t)
+		status := &pb.Status{
+			HeadSlot:       i * 10,
+			FinalizedEpoch: i,
+			Shards:         []*pb.ShardStatus{{ShardId: 4, LatestPeriod: i * 100}},
+		}
+		p.SetStatusFunc(func() (*pb.Status, error) {
+			return status, nil
+		})
+		if err := s.host.Connect(ctx, pstore.PeerInfo{ID: p.host.ID(), Addrs: p.host.Addrs()}); err != nil {
+			t.Fatalf("Could not connect hosts: %v", err)
+		}
+		peers = append(peers, p)
+	}
+
+	var statuses []PeerStatus
+	received := waitFor(ctx, func() bool {
+		statuses = s.PeerStatuses()
+		return len(statuses) == 2
+	})
+	if !received {
+		t.Fatalf("Expected the status of 2 peers, got %d", len(statuses))
+	}
+	// The peer with the highest head comes first.
+	if statuses[0].Peer.ID != peers[1].host.ID().Pretty() {
+		t.Errorf("Expected peer %s first, got %s", peers[1].host.ID().Pretty(), statuses[0].Peer.ID)
+	}
+	if statuses[0].Status.HeadSlot != 20 || statuses[0].Status.FinalizedEpoch != 2 {
+		t.Errorf("Unexpected status %v", statuses[0].Status)
+	}
+	if period, ok := statuses[1].LatestPeriod(4); !ok || period != 100 {
+		t.Errorf("Expected latest period 100 of shard 4, got %d, %v", period, ok)
+	}
+	if _, ok := statuses[1].LatestPeriod(5); ok {
+		t.Error("Expected no latest period of a shard the peer does not follow")
+	}
+}
+
+func TestPeerStatusRefresh(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer func(interval time.Duration) { statusInterval = interval }(statusInterval)
+	statusInterval = 50 * time.Millisecond
+	a, b := newTestServer(ctx, t), newTestServer(ctx, t)
+	go a.pollStatuses()
+
+	var lock sync.Mutex
+	slot := uint64(1)
+	b.SetStatusFunc(func() (*pb.Status, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return &pb.Status{HeadSlot: slot}, nil
+	})
+	if err := a.host.Connect(ctx, pstore.PeerInfo{ID: b.host.ID(), Addrs: b.host.Addrs()}); err != nil {
+		t.Fatalf("Could not connect hosts: %v", err)
+	}
+
+	headSlot := func() uint64 {
+		md, err := a.PeerMetadata(Peer{ID: b.host.ID().Pretty()})
+		if err != nil || md.Status == nil {
+			return 0
+		}
+		return md.Status.HeadSlot
+	}
+	if !waitFor(ctx, func() bool { return headSlot() == 1 }) {
+		t.Fatal("Expected the status of the peer to be received")
+	}
+	lock.Lock()
+	slot = 2
+	lock.Unlock()
+
+	refreshed := waitFor(ctx, func() bool { return headSlot() == 2 })
+	if !refreshed {
+		t.Error("Expected the status of the peer to be refreshed")
+	}
+}
@@ -0,0 +1,120 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+// requestProtocol is the libp2p protocol of the streams carrying messages
+// addressed to a single peer. Each stream carries a single envelope.
+const requestProtocol = protocol.ID("/prysm/request/1.0.0")
+
+// Request sends a request message to a peer and waits for its response,
+// which is a message of the paired response type, such as a
+// BeaconBlockResponse for a BeaconBlockRequest. It fails if the peer does not
+// respond before the context is done or the request times out.
+//
+// The peer receives the request from its feed like any other message and
+// answers it with Reply.
+func (s *Server) Request(ctx context.Context, peer Peer, msg interface{}) (proto.Message, error) {
+	if s.scores.banned(peer) {
+		return nil, fmt.Errorf("peer %s is banned", peer.ID)
+	}
+	id, response, err := s.requests.start(peer, msg)
+	if err != nil {
+		return nil, err
+	}
+	defer s.requests.done(id)
+
+	ctx, cancel := context.WithTimeout(ctx, s.requests.timeout)
+	defer cancel()
+	env, err := newEnvelope(msg, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sendEnvelope(ctx, peer, env); err != nil {
+		return nil, err
+	}
+	s.record(true, string(requestProtocol), peer, proto.Size(env), msg)
+	select {
+	case resp := <-response:
+		return resp, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no response to %T from peer %s: %v", msg, peer.ID, ctx.Err())
+	}
+}
+
+// sendEnvelope opens a stream to the peer and writes the envelope to it.
+func (s *Server) sendEnvelope(ctx context.Context, p Peer, env *pb.Envelope) error {
+	id, err := peer.IDB58Decode(p.ID)
+	if err != nil {
+		return fmt.Errorf("could not decode peer ID %q: %v", p.ID, err)
+	}
+	stream, err := s.host.NewStream(ctx, id, requestProtocol)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	if err := stream.SetWriteDeadline(time.Now().Add(s.requests.timeout)); err != nil {
+		return err
+	}
+	return writeEnvelope(stream, env)
+}
+
+// handleStream reads the envelope sent on a stream by a peer. Responses to
+// pending requests are handed to the requester, and other messages are
+// delivered to the subscribers of their type.
+func (s *Server) handleStream(stream inet.Stream) {
+	defer stream.Close()
+	p := Peer{ID: stream.Conn().RemotePeer().Pretty()}
+	if s.scores.banned(p) {
+		log.Debugf("Dropping stream from banned peer %s", p.ID)
+		return
+	}
+	if err := stream.SetReadDeadline(time.Now().Add(s.requests.timeout)); err != nil {
+		log.Errorf("Could not set stream deadline: %v", err)
+		return
+	}
+	env, err := readEnvelope(stream)
+	if err != nil {
+		log.Debugf("Could not read message from peer %s: %v", p.ID, err)
+		if err == errEnvelopeTooLarge {
+			s.ReportPeer(p, invalidMessagePenalty)
+		}
+		return
+	}
+	msg, err := openEnvelope(env)
+	if err != nil {
+		log.Debugf("Rejecting malformed message from peer %s: %v", p.ID, err)
+		s.ReportPeer(p, invalidMessagePenalty)
+		return
+	}
+	s.record(false, string(requestProtocol), p, proto.Size(env), msg)
+
+	m := Message{Peer: p, Addrs: s.peerAddrs(p), Data: msg}
+	if !env.Response {
+		m.RequestID = env.RequestId
+	}
+	if result := s.validators.validate(m); result != ValidationAccept {
+		if result == ValidationReject {
+			s.ReportPeer(p, invalidMessagePenalty)
+		}
+		log.Debugf("Dropping invalid %T message from peer %s", msg, p.ID)
+		return
+	}
+	if env.Response {
+		if s.requests.deliver(p, env.RequestId, msg) {
+			return
+		}
+		// Late responses are still delivered to subscribers.
+		log.Debugf("Received %T from peer %s for no pending request", msg, p.ID)
+	}
+	s.Feed(topicTypeMapping[env.Topic]).Send(m)
+}
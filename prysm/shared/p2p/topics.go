@@ -1,6 +1,7 @@
 package p2p
 
 import (
+	"fmt"
 	"reflect"
 
 	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
@@ -8,12 +9,16 @@ import (
 
 // Mapping of message topic enums to protobuf types.
 var topicTypeMapping = map[pb.Topic]reflect.Type{
-	pb.Topic_BEACON_BLOCK_HASH_ANNOUNCE: reflect.TypeOf(pb.BeaconBlockHashAnnounce{}),
-	pb.Topic_BEACON_BLOCK_REQUEST:       reflect.TypeOf(pb.BeaconBlockRequest{}),
-	pb.Topic_BEACON_BLOCK_RESPONSE:      reflect.TypeOf(pb.BeaconBlockResponse{}),
-	pb.Topic_COLLATION_BODY_REQUEST:     reflect.TypeOf(pb.CollationBodyRequest{}),
-	pb.Topic_COLLATION_BODY_RESPONSE:    reflect.TypeOf(pb.CollationBodyResponse{}),
-	pb.Topic_TRANSACTIONS:               reflect.TypeOf(pb.Transaction{}),
+	pb.Topic_BEACON_BLOCK_HASH_ANNOUNCE:  reflect.TypeOf(pb.BeaconBlockHashAnnounce{}),
+	pb.Topic_BEACON_BLOCK_REQUEST:        reflect.TypeOf(pb.BeaconBlockRequest{}),
+	pb.Topic_BEACON_BLOCK_RESPONSE:       reflect.TypeOf(pb.BeaconBlockResponse{}),
+	pb.Topic_CHAIN_HEAD_REQUEST:          reflect.TypeOf(pb.ChainHeadRequest{}),
+	pb.Topic_CHAIN_HEAD_RESPONSE:         reflect.TypeOf(pb.ChainHeadResponse{}),
+	pb.Topic_BEACON_BLOCK_RANGE_REQUEST:  reflect.TypeOf(pb.BeaconBlockRangeRequest{}),
+	pb.Topic_BEACON_BLOCK_RANGE_RESPONSE: reflect.TypeOf(pb.BeaconBlockRangeResponse{}),
+	pb.Topic_COLLATION_BODY_REQUEST:      reflect.TypeOf(pb.CollationBodyRequest{}),
+	pb.Topic_COLLATION_BODY_RESPONSE:     reflect.TypeOf(pb.CollationBodyResponse{}),
+	pb.Topic_TRANSACTIONS:                reflect.TypeOf(pb.Transaction{}),
 }
 
 // Mapping of message types to topic enums.
@@ -38,3 +43,9 @@ func topic(msg interface{}) pb.Topic {
 	}
 	return typeTopicMapping[msgType]
 }
+
+// shardTopic returns the name of the gossipsub topic carrying the messages of
+// a topic for a single shard, such as TRANSACTIONS/shard/3.
+func shardTopic(topic pb.Topic, shardID uint64) string {
+	return fmt.Sprintf("%s/shard/%d", topic, shardID)
+}
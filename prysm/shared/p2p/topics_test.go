@@ -60,3 +60,9 @@ func TestTopic(t *testing.T) {
 		}
 	}
 }
+
+func TestShardTopic(t *testing.T) {
+	if got, want := shardTopic(pb.Topic_TRANSACTIONS, 3), "TRANSACTIONS/shard/3"; got != want {
+		t.Errorf("shardTopic(%v, 3) = %s. wanted %s", pb.Topic_TRANSACTIONS, got, want)
+	}
+}
@@ -0,0 +1,90 @@
+package p2p
+
+import (
+	"reflect"
+	"sync"
+)
+
+// ValidationResult is the outcome of the validation of a gossip message.
+type ValidationResult int
+
+const (
+	// ValidationAccept delivers the message to subscribers and relays it to
+	// other peers.
+	ValidationAccept ValidationResult = iota
+	// ValidationIgnore drops the message without holding it against the
+	// sender, e.g. for stale or duplicate data.
+	ValidationIgnore
+	// ValidationReject drops the message and penalizes the sender.
+	ValidationReject
+)
+
+// Penalty reported for a peer that sent a rejected or malformed message.
+const invalidMessagePenalty = -20
+
+func (r ValidationResult) String() string {
+	switch r {
+	case ValidationAccept:
+		return "accept"
+	case ValidationIgnore:
+		return "ignore"
+	case ValidationReject:
+		return "reject"
+	default:
+		return "unknown"
+	}
+}
+
+// Validator checks a message received from a peer before it is delivered to
+// subscribers or relayed. It must not block, as messages are held until they
+// are validated.
+type Validator func(msg Message) ValidationResult
+
+// messageValidators holds the validator registered for each message type.
+type messageValidators struct {
+	lock   sync.RWMutex
+	byType map[reflect.Type]Validator
+}
+
+func newMessageValidators() *messageValidators {
+	return &messageValidators{byType: make(map[reflect.Type]Validator)}
+}
+
+// register sets the validator of the messages of msg's type. The msg can be
+// a value, a pointer or a reflect.Type. A nil validator removes the
+// registered one.
+func (mv *messageValidators) register(msg interface{}, validator Validator) {
+	t := messageType(msg)
+	mv.lock.Lock()
+	defer mv.lock.Unlock()
+	if validator == nil {
+		delete(mv.byType, t)
+		return
+	}
+	mv.byType[t] = validator
+}
+
+// validate runs the validator registered for the type of the message data.
+// Messages without a validator are accepted.
+func (mv *messageValidators) validate(msg Message) ValidationResult {
+	mv.lock.RLock()
+	validator, ok := mv.byType[messageType(msg.Data)]
+	mv.lock.RUnlock()
+	if !ok {
+		return ValidationAccept
+	}
+	return validator(msg)
+}
+
+// messageType returns the type of a message, whether it is given as a value,
+// a pointer or a reflect.Type.
+func messageType(msg interface{}) reflect.Type {
+	t, ok := msg.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(msg)
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,41 @@
+package p2p
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/prysmaticlabs/prysm/proto/sharding/v1"
+)
+
+func TestMessageValidators(t *testing.T) {
+	mv := newMessageValidators()
+	mv.register(&pb.CollationBodyRequest{}, func(msg Message) ValidationResult {
+		if msg.Data.(*pb.CollationBodyRequest).ShardId == 0 {
+			return ValidationReject
+		}
+		return ValidationAccept
+	})
+	mv.register(reflect.TypeOf(pb.Transaction{}), func(msg Message) ValidationResult {
+		return ValidationIgnore
+	})
+
+	tests := []struct {
+		data interface{}
+		want ValidationResult
+	}{
+		{&pb.CollationBodyRequest{ShardId: 1}, ValidationAccept},
+		{&pb.CollationBodyRequest{}, ValidationReject},
+		{&pb.Transaction{}, ValidationIgnore},
+		{&pb.CollationBodyResponse{}, ValidationAccept}, // no validator.
+	}
+	for _, tt := range tests {
+		if got := mv.validate(Message{Data: tt.data}); got != tt.want {
+			t.Errorf("validate(%T) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+
+	mv.register(pb.Transaction{}, nil)
+	if got := mv.validate(Message{Data: &pb.Transaction{}}); got != ValidationAccept {
+		t.Errorf("Expected message to be accepted once its validator is removed, got %v", got)
+	}
+}